@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// errCommandsBusy is returned for slash commands that arrive while the queue of the command pool is full.
+var errCommandsBusy = errors.New("command queue is full")
+
+// CommandPoolConfig configures how many slash commands of a workspace run at the same time and how many wait.
+type CommandPoolConfig struct {
+	Workers int // commands that run at the same time
+	Queue   int // commands that wait for a worker, further commands are turned away
+}
+
+// DefaultCommandPoolConfig runs a few commands at once, which is plenty for the players of a workspace, and queues
+// the commands of a burst instead of turning them away.
+var DefaultCommandPoolConfig = CommandPoolConfig{
+	Workers: 4,
+	Queue:   64,
+}
+
+// responseTimeout is how long posting to the response_url of a slash command may take.
+const responseTimeout = 10 * time.Second
+
+// commandPool runs the slash commands of a game manager on a fixed number of workers, so that the commands can be
+// acknowledged within the three seconds Slack waits for, however long their calls of the Slack API take.
+type commandPool struct {
+	queue   chan func()
+	quit    chan struct{}
+	pending int           // submitted commands that didn't finish yet
+	idle    chan struct{} // closed once no command is pending, see flush
+	stopped bool
+	mu      sync.Mutex
+}
+
+func newCommandPool(config CommandPoolConfig) *commandPool {
+	idle := make(chan struct{})
+	close(idle)
+	pool := &commandPool{
+		queue: make(chan func(), config.Queue),
+		quit:  make(chan struct{}),
+		idle:  idle,
+	}
+	for range config.Workers {
+		go pool.work()
+	}
+	return pool
+}
+
+// submit queues the command and reports whether it was queued. Commands are turned away once the queue is full or
+// the pool was stopped.
+func (pool *commandPool) submit(command func()) bool {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.stopped {
+		return false
+	}
+	select {
+	case pool.queue <- command:
+	default:
+		return false
+	}
+	if pool.pending == 0 {
+		pool.idle = make(chan struct{})
+	}
+	pool.pending++
+	return true
+}
+
+func (pool *commandPool) work() {
+	for {
+		select {
+		case command := <-pool.queue:
+			command()
+			pool.mu.Lock()
+			if pool.pending--; pool.pending == 0 {
+				close(pool.idle)
+			}
+			pool.mu.Unlock()
+		case <-pool.quit:
+			return
+		}
+	}
+}
+
+// flush waits until the submitted commands finished or the context is done.
+func (pool *commandPool) flush(ctx context.Context) {
+	pool.mu.Lock()
+	idle := pool.idle
+	pool.mu.Unlock()
+
+	select {
+	case <-idle:
+	case <-ctx.Done():
+	}
+}
+
+// stop turns away further commands, waits for the submitted ones as long as the context allows and stops the
+// workers.
+func (pool *commandPool) stop(ctx context.Context) {
+	pool.mu.Lock()
+	if pool.stopped {
+		pool.mu.Unlock()
+		return
+	}
+	pool.stopped = true
+	pool.mu.Unlock()
+
+	pool.flush(ctx)
+	close(pool.quit)
+}
+
+// queueCommand runs a slash command on the command pool of the game manager, no matter whether it arrived over
+// HTTP or Socket Mode, so that it can be acknowledged right away. Unknown commands fail with errInvalidRequest
+// without being queued, errCommandsBusy is returned if the queue is full.
+func queueCommand(gm *GameManager, cmd slack.SlashCommand) error {
+	if !gm.Settings().Commands.known(cmd.Command) {
+		return fmt.Errorf("%w: command %q", errInvalidRequest, cmd.Command)
+	}
+	if !gm.commands.submit(func() { runCommand(gm, cmd) }) {
+		return errCommandsBusy
+	}
+	return nil
+}
+
+// runCommand runs a queued slash command. Since the command was already acknowledged, its failures are answered
+// through its response_url.
+func runCommand(gm *GameManager, cmd slack.SlashCommand) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Slash command panicked", "command", cmd.Command, "panic", r)
+			gm.respond(SlackChannel(cmd.ChannelID), cmd.UserID, cmd.ResponseURL, msgError)
+		}
+	}()
+	if err := dispatchCommand(gm, cmd); err != nil {
+		// the command names may have been reloaded since the command was queued
+		slog.Warn("Recieved an invalid command", "command", cmd.Command, "error", err)
+		gm.respond(SlackChannel(cmd.ChannelID), cmd.UserID, cmd.ResponseURL, msgError)
+	}
+}
+
+// commandBusyMsg is the acknowledgement of a slash command that was turned away because the queue is full. It is
+// only shown to the user who sent the command.
+func commandBusyMsg(gm *GameManager, cmd slack.SlashCommand) slack.Msg {
+	return slack.Msg{
+		ResponseType: slack.ResponseTypeEphemeral,
+		Text:         gm.channelLanguage(SlackChannel(cmd.ChannelID)).Text(msgCommandsBusy),
+	}
+}
+
+// commandID identifies a slash command across the retries of its delivery. Slack sends a new trigger ID for every
+// invocation, so that commands with the same text sent twice by a user still run twice.
+func commandID(cmd slack.SlashCommand) string {
+	return strings.Join([]string{cmd.EnterpriseID, cmd.TeamID, cmd.ChannelID, cmd.UserID, cmd.Command, cmd.Text, cmd.TriggerID}, "\x00")
+}
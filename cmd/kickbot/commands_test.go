@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/mock/gomock"
+)
+
+// commandRequest returns the request of a start command in the channel. Requests with a retry number are retried
+// deliveries of the command.
+func commandRequest(channelID, text, responseURL, retry string) *http.Request {
+	formData := url.Values{
+		"team_id":      {"T1"},
+		"channel_id":   {channelID},
+		"user_id":      {"U1"},
+		"command":      {CMD_START_ROUND},
+		"text":         {text},
+		"response_url": {responseURL},
+		"trigger_id":   {"trigger-" + channelID},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/commands", strings.NewReader(formData.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if retry != "" {
+		req.Header.Set("X-Slack-Retry-Num", retry)
+	}
+	return req
+}
+
+// TestCommandIsAcknowledgedBeforeItRuns verifies that a slash command is acknowledged while the Slack API still
+// works on it, and that a retried delivery of the command doesn't start a second game.
+func TestCommandIsAcknowledgedBeforeItRuns(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+	handler := handleSlackCommand(SingleWorkspace(gameMgr))
+
+	release := make(chan struct{})
+	mockSlackClient.EXPECT().
+		PostMessage("C1", gomock.Any()).
+		DoAndReturn(func(channelID string, options ...slack.MsgOption) (string, string, error) {
+			<-release
+			return channelID, "ts", nil
+		}).Times(1)
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), "C1", "ts").
+		Return("C1", "ts", nil).AnyTimes()
+
+	rr := httptest.NewRecorder()
+	handler(rr, commandRequest("C1", "", "", ""))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected the command to be acknowledged with %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler(rr, commandRequest("C1", "", "", "1"))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected the retried command to be acknowledged with %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	close(release)
+	gameMgr.commands.flush(context.TODO())
+	if lobbies := len(gameMgr.gameRequests); lobbies != 1 {
+		t.Errorf("Expected the command to open a single game request, found %d", lobbies)
+	}
+}
+
+// TestFullCommandQueue verifies that slash commands are turned away with an ephemeral answer once the workers are
+// busy and the queue is full.
+func TestFullCommandQueue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient, WithCommandPoolConfig(CommandPoolConfig{Workers: 1, Queue: 1}))
+	defer gameMgr.Shutdown(context.TODO())
+	handler := handleSlackCommand(SingleWorkspace(gameMgr))
+
+	inFlight := make(chan struct{}, 2)
+	release := make(chan struct{})
+	mockSlackClient.EXPECT().
+		PostMessage(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(channelID string, options ...slack.MsgOption) (string, string, error) {
+			inFlight <- struct{}{}
+			<-release
+			return channelID, "ts", nil
+		}).Times(2)
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), gomock.Any(), "ts").
+		Return("", "ts", nil).AnyTimes()
+
+	// the first command keeps the worker busy, the second one waits in the queue
+	handler(httptest.NewRecorder(), commandRequest("C1", "", "", ""))
+	<-inFlight
+	handler(httptest.NewRecorder(), commandRequest("C2", "", "", ""))
+
+	rr := httptest.NewRecorder()
+	handler(rr, commandRequest("C3", "", "", ""))
+	var msg slack.Msg
+	if err := json.NewDecoder(rr.Body).Decode(&msg); err != nil {
+		t.Fatalf("Expected a message in the acknowledgement of the turned away command: %v", err)
+	}
+	if msg.ResponseType != slack.ResponseTypeEphemeral || msg.Text != DefaultLanguage.Text(msgCommandsBusy) {
+		t.Errorf("Expected an ephemeral busy message, got %+v", msg)
+	}
+
+	close(release)
+	gameMgr.commands.flush(context.TODO())
+}
+
+// TestCommandErrorsAreSentToResponseURL verifies that errors of a queued slash command are answered through the
+// response_url of the command instead of the Web API.
+func TestCommandErrorsAreSentToResponseURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// no ephemeral message is posted through the Web API
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+
+	responses := make(chan slack.WebhookMessage, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg slack.WebhookMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("Failed to decode response: %v", err)
+		}
+		responses <- msg
+	}))
+	defer server.Close()
+
+	rr := httptest.NewRecorder()
+	handleSlackCommand(SingleWorkspace(gameMgr))(rr, commandRequest("C1", "--players many", server.URL, ""))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected the command to be acknowledged with %d, got %d", http.StatusOK, rr.Code)
+	}
+	gameMgr.commands.flush(context.TODO())
+
+	select {
+	case msg := <-responses:
+		if msg.ResponseType != slack.ResponseTypeEphemeral || !strings.Contains(msg.Text, CMD_START_ROUND) {
+			t.Errorf("Expected an ephemeral answer with the usage of the command, got %+v", msg)
+		}
+	default:
+		t.Fatal("Expected the invalid parameters to be answered through the response_url")
+	}
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the bot, read from a YAML file (see kickbot.example.yaml) and the environment.
+// The Settings can be reloaded while the bot runs, all other fields only take effect on a restart.
+type Config struct {
+	Port           string         `yaml:"port"`
+	StorePath      string         `yaml:"store_path"`
+	TimeoutWarning time.Duration  `yaml:"timeout_warning"`
+	Rating         RatingSettings `yaml:"rating"`
+	SocketMode     bool           `yaml:"socket_mode"` // receive Slack requests over a websocket instead of HTTP
+	// redirect URL of the OAuth installation, only needed if the Slack app has several
+	OAuthRedirectURL string `yaml:"oauth_redirect_url"`
+	Settings         `yaml:",inline"`
+}
+
+// RatingSettings configure the decay of the player ratings, see RatingConfig.
+type RatingSettings struct {
+	DecayAfter time.Duration `yaml:"decay_after"`
+	DecayRate  float64       `yaml:"decay_rate"`
+}
+
+// Settings are the parts of the configuration that are reloaded on SIGHUP.
+type Settings struct {
+	UserLocale   bool                             `yaml:"user_locale"`
+	Commands     Commands                         `yaml:"commands"`
+	Tables       []Table                          `yaml:"tables"`
+	TableBusyFor time.Duration                    `yaml:"table_busy_for"` // how long a table is busy if no result is entered
+	Timezone     Timezone                         `yaml:"timezone"`       // in which start times are given and shown
+	Defaults     ChannelSettings                  `yaml:"defaults"`
+	Channels     map[SlackChannel]ChannelSettings `yaml:"channels"`
+	// settings of single workspaces by team ID, only used by a bot installed through OAuth
+	Workspaces map[string]WorkspaceSettings `yaml:"workspaces"`
+}
+
+// WorkspaceSettings are the settings of the games in a workspace of a bot installed through OAuth. They replace the
+// defaults and channels of the bot in the workspace, the defaults left out in the configuration file are taken from
+// the defaults of the bot.
+type WorkspaceSettings struct {
+	Defaults ChannelSettings                  `yaml:"defaults"`
+	Channels map[SlackChannel]ChannelSettings `yaml:"channels"`
+}
+
+// Timezone is a time zone by its IANA name, e.g. Europe/Berlin. The zero Timezone is the local time zone of the
+// server, which is UTC in most containers.
+type Timezone struct {
+	location *time.Location
+}
+
+// UnmarshalYAML loads the time zone with the given name.
+func (timezone *Timezone) UnmarshalYAML(value *yaml.Node) error {
+	location, err := time.LoadLocation(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: unknown time zone %q", value.Line, value.Value)
+	}
+	timezone.location = location
+	return nil
+}
+
+// Location returns the location of the time zone.
+func (timezone Timezone) Location() *time.Location {
+	if timezone.location == nil {
+		return time.Local
+	}
+	return timezone.location
+}
+
+// Commands are the names of the slash commands, as they are registered in the Slack app.
+type Commands struct {
+	Start    string `yaml:"start"`
+	Cancel   string `yaml:"cancel"`
+	Stats    string `yaml:"stats"`
+	Position string `yaml:"position"`
+}
+
+// known reports whether the name is one of the slash commands.
+func (commands Commands) known(name string) bool {
+	return name == commands.Start || name == commands.Cancel || name == commands.Stats || name == commands.Position
+}
+
+// ChannelSettings are the settings of the games in a channel. Settings of a channel that are left out in the
+// configuration file are taken from the defaults.
+type ChannelSettings struct {
+	Timeout          time.Duration `yaml:"timeout"`
+	GameType         GameType      `yaml:"game_type"`
+	AllowedGameTypes []GameType    `yaml:"allowed_game_types"`
+	MentionStyle     MentionStyle  `yaml:"mention_style"`
+	Language         Language      `yaml:"language"`
+	MaxLobbies       int           `yaml:"max_lobbies"` // game requests that can form at the same time, e.g. two 1v1 duels and a 2v2 game
+}
+
+// MentionStyle is who is notified when a game request is announced.
+type MentionStyle string
+
+const (
+	MentionHere    MentionStyle = "here"    // the active members of the channel
+	MentionChannel MentionStyle = "channel" // all members of the channel
+	MentionNone    MentionStyle = "none"    // nobody, the message is posted silently
+)
+
+// prefix returns the mention that precedes the announcement of a game request.
+func (style MentionStyle) prefix() string {
+	switch style {
+	case MentionChannel:
+		return "<!channel> "
+	case MentionNone:
+		return ""
+	default:
+		return "<!here> "
+	}
+}
+
+// DefaultConfig returns the configuration used for everything the configuration file leaves out.
+func DefaultConfig() Config {
+	return Config{
+		Port:           "4000",
+		TimeoutWarning: DefaultTimeoutWarning,
+		Rating: RatingSettings{
+			DecayAfter: DefaultRatingConfig.DecayAfter,
+			DecayRate:  DefaultRatingConfig.DecayRate,
+		},
+		Settings: DefaultSettings(),
+	}
+}
+
+// DefaultSettings returns the settings of a bot without configuration file.
+func DefaultSettings() Settings {
+	return Settings{
+		Commands: Commands{
+			Start:    CMD_START_ROUND,
+			Cancel:   CMD_CANCEL_ROUND,
+			Stats:    CMD_STATS,
+			Position: CMD_POSITION,
+		},
+		TableBusyFor: 20 * time.Minute,
+		Defaults: ChannelSettings{
+			Timeout:          30 * time.Minute,
+			GameType:         GameTypeTwoVsTwo,
+			AllowedGameTypes: slices.Clone(gameTypes),
+			MentionStyle:     MentionHere,
+			Language:         DefaultLanguage,
+			MaxLobbies:       3,
+		},
+	}
+}
+
+// Channel returns the settings of the channel.
+func (settings *Settings) Channel(channel SlackChannel) ChannelSettings {
+	if channelSettings, exists := settings.Channels[channel]; exists {
+		return channelSettings
+	}
+	return settings.Defaults
+}
+
+// Workspace returns the settings of the workspace with the team ID, which are the settings of the bot unless the
+// workspace has settings of its own.
+func (settings *Settings) Workspace(teamID string) Settings {
+	workspaceSettings := *settings
+	if workspace, exists := settings.Workspaces[teamID]; exists {
+		workspaceSettings.Defaults = workspace.Defaults
+		workspaceSettings.Channels = workspace.Channels
+	}
+	return workspaceSettings
+}
+
+// LoadConfig reads the configuration file at the given path, applies the environment variables on top of it and
+// validates the result. Without a path only the environment variables are applied to the DefaultConfig.
+func LoadConfig(path string, getenv func(string) string) (Config, error) {
+	config := DefaultConfig()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := decodeConfig(data, &config); err != nil {
+			return Config{}, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+	if err := config.applyEnv(getenv); err != nil {
+		return Config{}, err
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return config, nil
+}
+
+// decodeConfig decodes the configuration file onto the config. Unknown fields are rejected to catch typos.
+// The settings of every channel start out as a copy of the defaults, so that a channel only needs to list what
+// it changes.
+func decodeConfig(data []byte, config *Config) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	var raw struct {
+		Channels   map[SlackChannel]yaml.Node `yaml:"channels"`
+		Workspaces map[string]struct {
+			Defaults yaml.Node                  `yaml:"defaults"`
+			Channels map[SlackChannel]yaml.Node `yaml:"channels"`
+		} `yaml:"workspaces"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	channels, err := decodeChannels(config.Defaults, raw.Channels, "channels")
+	if err != nil {
+		return err
+	}
+	config.Channels = channels
+
+	// the workspaces start out with the defaults of the bot, and their channels with the defaults of the workspace
+	config.Workspaces = make(map[string]WorkspaceSettings, len(raw.Workspaces))
+	for team, workspace := range raw.Workspaces {
+		defaults := config.Defaults
+		defaults.AllowedGameTypes = slices.Clone(defaults.AllowedGameTypes)
+		if !workspace.Defaults.IsZero() {
+			if err := workspace.Defaults.Decode(&defaults); err != nil {
+				return fmt.Errorf("workspaces.%s.defaults: %w", team, err)
+			}
+		}
+		channels, err := decodeChannels(defaults, workspace.Channels, "workspaces."+team+".channels")
+		if err != nil {
+			return err
+		}
+		config.Workspaces[team] = WorkspaceSettings{Defaults: defaults, Channels: channels}
+	}
+	return nil
+}
+
+// decodeChannels decodes the settings of the channels, each on top of a copy of the defaults.
+func decodeChannels(defaults ChannelSettings, nodes map[SlackChannel]yaml.Node, path string) (map[SlackChannel]ChannelSettings, error) {
+	channels := make(map[SlackChannel]ChannelSettings, len(nodes))
+	for channel, node := range nodes {
+		settings := defaults
+		settings.AllowedGameTypes = slices.Clone(settings.AllowedGameTypes)
+		if err := node.Decode(&settings); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", path, channel, err)
+		}
+		channels[channel] = settings
+	}
+	return channels, nil
+}
+
+// applyEnv overrides the configuration with the environment variables the bot was configured with before it had
+// a configuration file. Unset variables keep the configured value.
+func (config *Config) applyEnv(getenv func(string) string) error {
+	var errs []error
+	if port := getenv("KICKBOT_PORT"); port != "" {
+		config.Port = port
+	}
+	if storePath := getenv("KICKBOT_STORE_PATH"); storePath != "" {
+		config.StorePath = storePath
+	}
+	if value := getenv("KICKBOT_RATING_DECAY_AFTER"); value != "" {
+		decayAfter, err := time.ParseDuration(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("KICKBOT_RATING_DECAY_AFTER: %w", err))
+		}
+		config.Rating.DecayAfter = decayAfter
+	}
+	if value := getenv("KICKBOT_RATING_DECAY_RATE"); value != "" {
+		decayRate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("KICKBOT_RATING_DECAY_RATE: must be a number between 0 and 1, got %q", value))
+		}
+		config.Rating.DecayRate = decayRate
+	}
+	if value := getenv("KICKBOT_TIMEOUT_WARNING"); value != "" {
+		warning, err := time.ParseDuration(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("KICKBOT_TIMEOUT_WARNING: %w", err))
+		}
+		config.TimeoutWarning = warning
+	}
+	if value := getenv("KICKBOT_LANGUAGE"); value != "" {
+		lang, ok := ParseLanguage(value)
+		if !ok {
+			errs = append(errs, fmt.Errorf("KICKBOT_LANGUAGE: unknown language %q", value))
+		}
+		config.Defaults.Language = lang
+	}
+	if value := getenv("KICKBOT_CHANNEL_LANGUAGES"); value != "" {
+		languages, err := ParseChannelLanguages(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("KICKBOT_CHANNEL_LANGUAGES: %w", err))
+		}
+		channels := maps.Clone(config.Channels)
+		if channels == nil {
+			channels = make(map[SlackChannel]ChannelSettings)
+		}
+		for channel, lang := range languages {
+			settings := config.Channel(channel)
+			settings.Language = lang
+			channels[channel] = settings
+		}
+		config.Channels = channels
+	}
+	if value := getenv("KICKBOT_USER_LOCALE"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("KICKBOT_USER_LOCALE: must be true or false, got %q", value))
+		}
+		config.UserLocale = enabled
+	}
+	if value := getenv("KICKBOT_SOCKET_MODE"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("KICKBOT_SOCKET_MODE: must be true or false, got %q", value))
+		}
+		config.SocketMode = enabled
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the configuration and reports all problems at once, each prefixed with the path of the offending
+// setting in the configuration file.
+func (config *Config) Validate() error {
+	var errs []error
+	if port, err := strconv.Atoi(config.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("port: must be a number between 1 and 65535, got %q", config.Port))
+	}
+	if config.TimeoutWarning < 0 {
+		errs = append(errs, fmt.Errorf("timeout_warning: must not be negative, got %s", config.TimeoutWarning))
+	}
+	if config.Rating.DecayAfter < 0 {
+		errs = append(errs, fmt.Errorf("rating.decay_after: must not be negative, got %s", config.Rating.DecayAfter))
+	}
+	if config.OAuthRedirectURL != "" {
+		if redirectURL, err := url.Parse(config.OAuthRedirectURL); err != nil || redirectURL.Scheme != "https" || redirectURL.Host == "" {
+			errs = append(errs, fmt.Errorf("oauth_redirect_url: must be an https URL, got %q", config.OAuthRedirectURL))
+		}
+	}
+	if config.Rating.DecayRate < 0 || config.Rating.DecayRate > 1 {
+		errs = append(errs, fmt.Errorf("rating.decay_rate: must be between 0 and 1, got %v", config.Rating.DecayRate))
+	}
+	errs = append(errs, config.Commands.validate())
+	errs = append(errs, validateTables(config.Tables))
+	if config.TableBusyFor <= 0 {
+		errs = append(errs, fmt.Errorf("table_busy_for: must be positive, got %s", config.TableBusyFor))
+	}
+	errs = append(errs, config.Defaults.validate("defaults"))
+	errs = append(errs, validateChannels(config.Channels, "channels"))
+	teams := make([]string, 0, len(config.Workspaces))
+	for team := range config.Workspaces {
+		teams = append(teams, team)
+	}
+	slices.Sort(teams)
+	for _, team := range teams {
+		workspace := config.Workspaces[team]
+		errs = append(errs, workspace.Defaults.validate("workspaces."+team+".defaults"))
+		errs = append(errs, validateChannels(workspace.Channels, "workspaces."+team+".channels"))
+	}
+	return errors.Join(errs...)
+}
+
+func validateChannels(settings map[SlackChannel]ChannelSettings, path string) error {
+	var errs []error
+	channels := make([]SlackChannel, 0, len(settings))
+	for channel := range settings {
+		channels = append(channels, channel)
+	}
+	slices.Sort(channels)
+	for _, channel := range channels {
+		errs = append(errs, settings[channel].validate(path+"."+string(channel)))
+	}
+	return errors.Join(errs...)
+}
+
+func (commands Commands) validate() error {
+	var errs []error
+	seen := make(map[string]string)
+	for _, command := range []struct{ key, name string }{
+		{"start", commands.Start},
+		{"cancel", commands.Cancel},
+		{"stats", commands.Stats},
+		{"position", commands.Position},
+	} {
+		switch {
+		case !strings.HasPrefix(command.name, "/") || len(command.name) < 2 || strings.ContainsAny(command.name, " \t\n"):
+			errs = append(errs, fmt.Errorf("commands.%s: must be a slash command like /kicker, got %q", command.key, command.name))
+		case seen[command.name] != "":
+			errs = append(errs, fmt.Errorf("commands.%s: %s is already used by commands.%s", command.key, command.name, seen[command.name]))
+		default:
+			seen[command.name] = command.key
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateTables(tables []Table) error {
+	var errs []error
+	seen := make(map[string]bool)
+	for i, table := range tables {
+		key := strings.ToLower(table.Name)
+		switch {
+		case table.Name == "" || strings.ContainsAny(table.Name, " \t\n"):
+			errs = append(errs, fmt.Errorf("tables[%d].name: must be a single word like dach, got %q", i, table.Name))
+		case seen[key]:
+			errs = append(errs, fmt.Errorf("tables[%d].name: %s is already used by another table", i, table.Name))
+		}
+		seen[key] = true
+	}
+	return errors.Join(errs...)
+}
+
+func (settings ChannelSettings) validate(path string) error {
+	var errs []error
+	if settings.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("%s.timeout: must be positive, got %s", path, settings.Timeout))
+	}
+	if len(settings.AllowedGameTypes) == 0 {
+		errs = append(errs, fmt.Errorf("%s.allowed_game_types: must allow at least one game type", path))
+	} else if !slices.Contains(settings.AllowedGameTypes, settings.GameType) {
+		errs = append(errs, fmt.Errorf("%s.game_type: %s is not one of the allowed game types", path, settings.GameType))
+	}
+	switch settings.MentionStyle {
+	case MentionHere, MentionChannel, MentionNone:
+	default:
+		errs = append(errs, fmt.Errorf("%s.mention_style: must be one of here, channel or none, got %q", path, settings.MentionStyle))
+	}
+	if _, exists := catalog[settings.Language]; !exists {
+		errs = append(errs, fmt.Errorf("%s.language: unknown language %q", path, settings.Language))
+	}
+	if settings.MaxLobbies <= 0 {
+		errs = append(errs, fmt.Errorf("%s.max_lobbies: must be positive, got %d", path, settings.MaxLobbies))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "kickbot.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func noEnv(string) string { return "" }
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `
+port: "8080"
+store_path: /var/lib/kickbot/store.json
+user_locale: true
+timezone: Europe/Berlin
+commands:
+  start: /foosball
+defaults:
+  timeout: 45m
+  language: en
+channels:
+  C-DUEL:
+    game_type: 1v1
+    allowed_game_types: [1v1]
+    mention_style: none
+  C-GERMAN:
+    language: de
+    max_lobbies: 1
+`)
+
+	config, err := LoadConfig(path, noEnv)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Port != "8080" || config.StorePath != "/var/lib/kickbot/store.json" || !config.UserLocale {
+		t.Errorf("Unexpected global settings %+v", config)
+	}
+	// commands that are left out keep their default name
+	if config.Commands.Start != "/foosball" || config.Commands.Cancel != CMD_CANCEL_ROUND {
+		t.Errorf("Unexpected commands %+v", config.Commands)
+	}
+	if location := config.Timezone.Location(); location.String() != "Europe/Berlin" {
+		t.Errorf("Expected the time zone Europe/Berlin, got %s", location)
+	}
+	if DefaultSettings().Timezone.Location() != time.Local {
+		t.Error("Expected the local time zone without a configured time zone")
+	}
+	if config.TimeoutWarning != DefaultTimeoutWarning {
+		t.Errorf("Expected the default timeout warning, got %s", config.TimeoutWarning)
+	}
+
+	// channels inherit everything they don't set from the defaults
+	duel := config.Channel("C-DUEL")
+	if duel.GameType != GameTypeOneVsOne || !slices.Equal(duel.AllowedGameTypes, []GameType{GameTypeOneVsOne}) ||
+		duel.MentionStyle != MentionNone || duel.Timeout != 45*time.Minute || duel.Language != LanguageEnglish {
+		t.Errorf("Unexpected settings of the duel channel %+v", duel)
+	}
+	german := config.Channel("C-GERMAN")
+	if german.Language != LanguageGerman || german.GameType != GameTypeTwoVsTwo || german.MentionStyle != MentionHere || german.MaxLobbies != 1 {
+		t.Errorf("Unexpected settings of the german channel %+v", german)
+	}
+	if other := config.Channel("C-OTHER"); other.Language != LanguageEnglish || other.Timeout != 45*time.Minute || other.MaxLobbies != 3 {
+		t.Errorf("Expected the defaults for a channel without settings, got %+v", other)
+	}
+	if len(config.Defaults.AllowedGameTypes) != len(gameTypes) {
+		t.Errorf("Channel settings must not change the defaults, got %+v", config.Defaults)
+	}
+}
+
+func TestLoadConfigWorkspaces(t *testing.T) {
+	path := writeConfig(t, `
+defaults:
+  timeout: 45m
+channels:
+  C-DUEL:
+    game_type: 1v1
+workspaces:
+  T-ENGLISH:
+    defaults:
+      language: en
+    channels:
+      C-RUNDLAUF:
+        game_type: rundlauf
+`)
+
+	config, err := LoadConfig(path, noEnv)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// workspaces inherit the defaults of the bot they don't set, their channels the defaults of the workspace
+	english := config.Workspace("T-ENGLISH")
+	if english.Defaults.Language != LanguageEnglish || english.Defaults.Timeout != 45*time.Minute {
+		t.Errorf("Unexpected defaults of the workspace %+v", english.Defaults)
+	}
+	if rundlauf := english.Channel("C-RUNDLAUF"); rundlauf.GameType != GameTypeRundlauf || rundlauf.Language != LanguageEnglish {
+		t.Errorf("Unexpected settings of the rundlauf channel %+v", rundlauf)
+	}
+	if duel := english.Channel("C-DUEL"); duel.GameType != GameTypeTwoVsTwo {
+		t.Errorf("Expected the channels of the bot not to apply to the workspace, got %+v", duel)
+	}
+	if other := config.Workspace("T-OTHER"); other.Defaults.Language != DefaultLanguage || other.Channel("C-DUEL").GameType != GameTypeOneVsOne {
+		t.Errorf("Expected the settings of the bot for a workspace without settings, got %+v", other)
+	}
+}
+
+func TestLoadConfigEnvironment(t *testing.T) {
+	path := writeConfig(t, `
+port: "8080"
+rating:
+  decay_rate: 0.1
+`)
+	env := map[string]string{
+		"KICKBOT_PORT":              "9000",
+		"KICKBOT_RATING_DECAY_RATE": "0.5",
+		"KICKBOT_CHANNEL_LANGUAGES": "C1=en",
+		"KICKBOT_SOCKET_MODE":       "true",
+	}
+
+	config, err := LoadConfig(path, func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Port != "9000" || config.Rating.DecayRate != 0.5 || !config.SocketMode {
+		t.Errorf("Expected the environment to override the file, got %+v", config)
+	}
+	if lang := config.Channel("C1").Language; lang != LanguageEnglish {
+		t.Errorf("Expected the channel language from the environment, got %s", lang)
+	}
+
+	// without a file the environment is applied to the defaults
+	config, err = LoadConfig("", func(key string) string { return env[key] })
+	if err != nil || config.Port != "9000" || config.Defaults.Timeout != 30*time.Minute {
+		t.Errorf("Unexpected configuration without file %+v, %v", config, err)
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "empty file",
+			content:  "",
+			expected: nil,
+		},
+		{
+			name:     "unknown game type",
+			content:  "defaults:\n  game_type: 3v3\n",
+			expected: []string{`unknown game type "3v3"`},
+		},
+		{
+			name:     "unknown field",
+			content:  "defaults:\n  timout: 10m\n",
+			expected: []string{"field timout not found"},
+		},
+		{
+			name:     "unknown time zone",
+			content:  "timezone: Europe/Bielefeld\n",
+			expected: []string{`unknown time zone "Europe/Bielefeld"`},
+		},
+		{
+			name:     "duplicate table",
+			content:  "tables:\n  - name: dach\n  - name: Dach\n    location: 4. OG\ntable_busy_for: 0s\n",
+			expected: []string{"tables[1].name: Dach is already used by another table", "table_busy_for: must be positive"},
+		},
+		{
+			name:     "invalid workspace settings",
+			content:  "workspaces:\n  T1:\n    defaults:\n      language: fr\n    channels:\n      C1:\n        timeout: 0s\n",
+			expected: []string{`workspaces.T1.defaults.language: unknown language "fr"`, "workspaces.T1.channels.C1.timeout: must be positive"},
+		},
+		{
+			name: "every problem is reported",
+			content: `
+port: "http"
+rating:
+  decay_rate: 2
+commands:
+  start: kicker
+  stats: /kicker-abbrechen
+defaults:
+  timeout: -5m
+channels:
+  C1:
+    game_type: 1v1
+    allowed_game_types: [2v2]
+    mention_style: everyone
+    language: fr
+    max_lobbies: 0
+`,
+			expected: []string{
+				"port: must be a number",
+				"rating.decay_rate: must be between 0 and 1",
+				"commands.start: must be a slash command",
+				"commands.stats: /kicker-abbrechen is already used by commands.cancel",
+				"defaults.timeout: must be positive",
+				"channels.C1.game_type: 1v1 is not one of the allowed game types",
+				"channels.C1.mention_style: must be one of here, channel or none",
+				`channels.C1.language: unknown language "fr"`,
+				"channels.C1.max_lobbies: must be positive",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, tc.content), noEnv)
+			if len(tc.expected) == 0 {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, expected := range tc.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("Expected the error to contain %q, got:\n%v", expected, err)
+				}
+			}
+		})
+	}
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"), noEnv); err == nil {
+		t.Error("Expected an error for a missing config file")
+	}
+}
+
+func TestParsingGameFlagsWithChannelSettings(t *testing.T) {
+	settings := DefaultSettings().Defaults
+	settings.Timeout = 10 * time.Minute
+	settings.GameType = GameTypeOneVsOne
+	settings.AllowedGameTypes = []GameType{GameTypeOneVsOne}
+
+	gameOptions := parseFlags("", settings)
+	if gameOptions.err != nil || gameOptions.gameType != GameTypeOneVsOne || gameOptions.timeout != 10*time.Minute {
+		t.Errorf("Expected the defaults of the channel, got %+v", gameOptions)
+	}
+
+	settings.GameType = GameTypeTwoVsTwo
+	settings.AllowedGameTypes = []GameType{GameTypeTwoVsTwo}
+	if gameOptions := parseFlags("--duel", settings); gameOptions.err == nil {
+		t.Error("Expected an error for a game type that isn't allowed in the channel")
+	}
+}
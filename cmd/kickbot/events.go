@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+// eventDedupWindow is how long the IDs of handled events are remembered. Slack retries an event that wasn't
+// acknowledged in time up to three times within about five minutes.
+const eventDedupWindow = 10 * time.Minute
+
+// eventDeduplicator remembers the IDs of handled events, so that retried deliveries are only handled once.
+type eventDeduplicator struct {
+	mu     sync.Mutex
+	seen   map[string]time.Time
+	window time.Duration
+}
+
+func newEventDeduplicator(window time.Duration) *eventDeduplicator {
+	return &eventDeduplicator{seen: make(map[string]time.Time), window: window}
+}
+
+// firstDelivery reports whether the event wasn't seen within the window and remembers it. IDs older than the
+// window are forgotten on the way.
+func (dedup *eventDeduplicator) firstDelivery(eventID string, now time.Time) bool {
+	dedup.mu.Lock()
+	defer dedup.mu.Unlock()
+
+	for id, seenAt := range dedup.seen {
+		if now.Sub(seenAt) > dedup.window {
+			delete(dedup.seen, id)
+		}
+	}
+	if _, seen := dedup.seen[eventID]; seen {
+		return false
+	}
+	dedup.seen[eventID] = now
+	return true
+}
+
+// handleSlackEvents handles the Events API: it answers the url_verification challenge when the request URL is set
+// up and dispatches the events of event_callback requests to the game manager of their workspace, once per event
+// ID. Interactivity payloads have their own endpoint, but Slack apps set up before it existed still post them here.
+func handleSlackEvents(ws *Workspaces) http.HandlerFunc {
+	dedup := newEventDeduplicator(eventDedupWindow)
+	interactions := handleSlackInteraction(ws)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			interactions(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			slog.Warn("Failed to read event body", "error", err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// the request signature was already verified by the SlackVerifyMiddleware
+		event, err := slackevents.ParseEvent(body, slackevents.OptionNoVerifyToken())
+		if err != nil {
+			slog.Warn("Failed to parse event", "error", err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if event.Type == slackevents.URLVerification {
+			verification := event.Data.(*slackevents.EventsAPIURLVerificationEvent)
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(verification.Challenge))
+			return
+		}
+		if err := dispatchEvent(ws, dedup, event, r.Header.Get("X-Slack-Retry-Num")); errors.Is(err, errNotInstalled) {
+			slog.Warn("Received an event from another workspace", "error", err)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		} else if err != nil {
+			slog.Warn("Received an unsupported event", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// dispatchEvent queues an event of the Events API once per event ID, no matter whether it arrived over HTTP or
+// Socket Mode, so that it can be acknowledged right away. The retry is the number of the delivery, only used for logging.
+func dispatchEvent(ws *Workspaces, dedup *eventDeduplicator, event slackevents.EventsAPIEvent, retry string) error {
+	switch event.Type {
+	case slackevents.CallbackEvent:
+		callback := event.Data.(*slackevents.EventsAPICallbackEvent)
+		gm, err := ws.Manager(Workspace{EnterpriseID: event.EnterpriseID, TeamID: event.TeamID})
+		if err != nil {
+			return err
+		}
+		if !dedup.firstDelivery(callback.EventID, time.Now()) {
+			slog.Info("Ignoring a retried event", "eventId", callback.EventID, "retry", retry)
+			return nil
+		}
+		queueEvent(gm, event.InnerEvent)
+	case slackevents.AppRateLimited:
+		slog.Warn("Slack is rate limiting the events of the app", "team", event.TeamID)
+	default:
+		return fmt.Errorf("%w: event type %q", errInvalidRequest, event.Type)
+	}
+	return nil
+}
+
+// queueEvent runs an event on the command pool of the game manager, so that it is acknowledged before Slack is
+// called and isn't retried while it's being handled. Events that arrive while the queue is full are dropped, they
+// only ask for help messages.
+func queueEvent(gm *GameManager, event slackevents.EventsAPIInnerEvent) {
+	handle := func() {
+		defer func() {
+			if r := recover(); r != nil {
+				slog.Error("Event handler panicked", "type", event.Type, "panic", r)
+			}
+		}()
+		gm.HandleEvent(event)
+	}
+	if !gm.commands.submit(handle) {
+		slog.Warn("Dropping an event, the queue is full", "type", event.Type)
+	}
+}
+
+// HandleEvent dispatches an event of the Events API. Mentions of the bot are answered with the slash commands and
+// players joining a channel are told how to start a game. Reactions and the app home are not used yet.
+func (gameMgr *GameManager) HandleEvent(event slackevents.EventsAPIInnerEvent) {
+	commands := gameMgr.Settings().Commands
+	switch ev := event.Data.(type) {
+	case *slackevents.AppMentionEvent:
+		// other bots mentioning the bot get no answer
+		if ev.BotID != "" {
+			return
+		}
+		if _, err := gameMgr.notify(SlackChannel(ev.Channel), ev.User, msgMentionHelp, commands.Start, commands.Cancel, commands.Stats, commands.Position); err != nil {
+			slog.Error("Failed to answer mention", "error", err)
+		}
+	case *slackevents.MemberJoinedChannelEvent:
+		// the bot is told about joining a channel itself, it doesn't need a welcome
+		if botUser, err := gameMgr.botUser(context.Background()); err != nil {
+			slog.Error("Failed to look up the user of the bot", "error", err)
+			return
+		} else if ev.User == botUser {
+			return
+		}
+		if _, err := gameMgr.notify(SlackChannel(ev.Channel), ev.User, msgWelcome, commands.Start); err != nil {
+			slog.Error("Failed to welcome channel member", "error", err)
+		}
+	case *slackevents.ReactionAddedEvent:
+		slog.Debug("Reaction added", "user", ev.User, "reaction", ev.Reaction, "channel", ev.Item.Channel, "ts", ev.Item.Timestamp)
+	case *slackevents.AppHomeOpenedEvent:
+		slog.Debug("App home opened", "user", ev.User, "tab", ev.Tab)
+	default:
+		slog.Debug("Ignoring event", "type", event.Type)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/mock/gomock"
+)
+
+func postEvent(handler http.HandlerFunc, body string, retry string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if retry != "" {
+		req.Header.Set("X-Slack-Retry-Num", retry)
+	}
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+	return rr
+}
+
+func TestURLVerification(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	handler := handleSlackEvents(SingleWorkspace(NewGameManager(NewMockSlackClient(ctrl))))
+	rr := postEvent(handler, `{"token":"t","challenge":"3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P","type":"url_verification"}`, "")
+
+	if rr.Code != http.StatusOK || rr.Body.String() != "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P" {
+		t.Errorf("Expected the challenge to be echoed, got %d %q", rr.Code, rr.Body.String())
+	}
+}
+
+// TestEventCallbackIsHandledOnce verifies that events are dispatched to the game manager and that retried
+// deliveries of the same event are acknowledged without handling them again.
+func TestEventCallbackIsHandledOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	handler := handleSlackEvents(SingleWorkspace(gameMgr))
+
+	var help slack.MsgOption
+	mockSlackClient.EXPECT().
+		PostEphemeral("C1", "U1", gomock.Any()).
+		DoAndReturn(func(channelID, userID string, options ...slack.MsgOption) (string, error) {
+			help = options[0]
+			return "timestamp", nil
+		}).Times(1)
+	mockSlackClient.EXPECT().
+		PostEphemeral("C1", "U2", gomock.Any()).
+		Return("timestamp", nil).Times(1)
+
+	mention := `{"type":"event_callback","team_id":"T1","event_id":"Ev1","event_time":1,` +
+		`"event":{"type":"app_mention","user":"U1","text":"<@B1> hilfe","ts":"1.1","channel":"C1","event_ts":"1.1"}}`
+	for _, retry := range []string{"", "1", "2"} {
+		if rr := postEvent(handler, mention, retry); rr.Code != http.StatusOK {
+			t.Errorf("Expected delivery %q to be acknowledged, got %d", retry, rr.Code)
+		}
+	}
+	gameMgr.commands.flush(context.TODO())
+	if text := msgText(t, help); !strings.Contains(text, CMD_START_ROUND) {
+		t.Errorf("Expected the mention to be answered with the commands, got %q", text)
+	}
+
+	// the bot joining the channel itself isn't welcomed, its user ID is looked up once
+	mockSlackClient.EXPECT().
+		AuthTestContext(gomock.Any()).
+		Return(&slack.AuthTestResponse{TeamID: "T1", UserID: "B1"}, nil).Times(1)
+	for i, user := range []string{"B1", "U2"} {
+		joined := fmt.Sprintf(`{"type":"event_callback","team_id":"T1","event_id":"EvJoin%d","event_time":2,`+
+			`"event":{"type":"member_joined_channel","user":%q,"channel":"C1","channel_type":"C","team":"T1"}}`, i, user)
+		if rr := postEvent(handler, joined, ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected the event to be acknowledged, got %d", rr.Code)
+		}
+		gameMgr.commands.flush(context.TODO())
+	}
+
+	reaction := `{"type":"event_callback","team_id":"T1","event_id":"Ev3","event_time":3,` +
+		`"event":{"type":"reaction_added","user":"U1","reaction":"soccer","item":{"type":"message","channel":"C1","ts":"1.1"}}}`
+	if rr := postEvent(handler, reaction, ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected the event to be acknowledged, got %d", rr.Code)
+	}
+
+	if rr := postEvent(handler, `{"type":`, ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected a malformed event to be rejected, got %d", rr.Code)
+	}
+}
+
+// TestEventsAreAcknowledgedFirst verifies that events are acknowledged before Slack is called for them, so that a
+// slow answer doesn't make Slack retry the event.
+func TestEventsAreAcknowledgedFirst(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	handler := handleSlackEvents(SingleWorkspace(gameMgr))
+
+	acknowledged := make(chan struct{})
+	mockSlackClient.EXPECT().
+		PostEphemeral("C1", "U1", gomock.Any()).
+		DoAndReturn(func(channelID, userID string, options ...slack.MsgOption) (string, error) {
+			<-acknowledged
+			return "timestamp", nil
+		}).Times(1)
+
+	mention := `{"type":"event_callback","team_id":"T1","event_id":"Ev1","event_time":1,` +
+		`"event":{"type":"app_mention","user":"U1","text":"<@B1>","ts":"1.1","channel":"C1","event_ts":"1.1"}}`
+	if rr := postEvent(handler, mention, ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected the event to be acknowledged, got %d", rr.Code)
+	}
+	close(acknowledged)
+	gameMgr.commands.flush(context.TODO())
+}
+
+// TestEventsEndpointAcceptsInteractions verifies that interactivity payloads posted to the events endpoint by
+// Slack apps set up before the interactivity endpoint existed are still handled.
+func TestEventsEndpointAcceptsInteractions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	mockSlackClient.EXPECT().
+		PostEphemeral("C1", "U1", gomock.Any()).
+		Return("timestamp", nil).AnyTimes()
+
+	handler := handleSlackEvents(SingleWorkspace(NewGameManager(mockSlackClient)))
+	callback := slack.InteractionCallback{}
+	callback.Channel.ID = "C1"
+	callback.User.ID = "U1"
+	callback.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: ACTION_JOIN_ROUND, Value: "gone"}}
+	payload, _ := json.Marshal(callback)
+	form := url.Values{"payload": {string(payload)}}
+	req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected the interaction to be handled, got %d", rr.Code)
+	}
+}
+
+func TestEventDeduplicator(t *testing.T) {
+	dedup := newEventDeduplicator(time.Minute)
+	start := time.Now()
+
+	if !dedup.firstDelivery("Ev1", start) {
+		t.Error("Expected the first delivery to be handled")
+	}
+	if dedup.firstDelivery("Ev1", start.Add(30*time.Second)) {
+		t.Error("Expected a retry within the window to be ignored")
+	}
+	if !dedup.firstDelivery("Ev2", start.Add(30*time.Second)) {
+		t.Error("Expected another event to be handled")
+	}
+	if !dedup.firstDelivery("Ev1", start.Add(2*time.Minute)) {
+		t.Error("Expected the event ID to be forgotten after the window")
+	}
+	if len(dedup.seen) != 1 {
+		t.Errorf("Expected forgotten event IDs to be dropped, got %v", dedup.seen)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GameFormat describes how many players a game type needs and how they are split into teams.
+type GameFormat struct {
+	Name       string // name in slash commands, the configuration and messages, e.g. "2v2"
+	TeamSizes  [2]int // players per team, both zero for formats without teams
+	MinPlayers int    // fewest players the game starts with, by default a game request waits for that many
+	MaxPlayers int    // most players a game request can wait for, see --players
+	// announce is the text of a new game request, rendered with the creator, the number of missing players and
+	// the number of players the game request waits for
+	announce MessageKey
+}
+
+// gameFormats is the registry of all game formats by game type.
+var gameFormats = map[GameType]GameFormat{
+	GameTypeTwoVsTwo: {Name: "2v2", TeamSizes: [2]int{2, 2}, MinPlayers: 4, MaxPlayers: 4, announce: msgNewGame},
+	GameTypeOneVsOne: {Name: "1v1", TeamSizes: [2]int{1, 1}, MinPlayers: 2, MaxPlayers: 2, announce: msgNewDuel},
+	GameTypeTwoVsOne: {Name: "2v1", TeamSizes: [2]int{2, 1}, MinPlayers: 3, MaxPlayers: 3, announce: msgNewHandicap},
+	GameTypeRundlauf: {Name: "rundlauf", MinPlayers: 5, MaxPlayers: 8, announce: msgNewRundlauf},
+}
+
+// gameTypes are all game types in the order they are listed to users.
+var gameTypes = []GameType{GameTypeTwoVsTwo, GameTypeOneVsOne, GameTypeTwoVsOne, GameTypeRundlauf}
+
+// Format returns the format of the game type. Unknown game types have the format of a 2 vs 2 game.
+func (gameType GameType) Format() GameFormat {
+	if format, exists := gameFormats[gameType]; exists {
+		return format
+	}
+	return gameFormats[GameTypeTwoVsTwo]
+}
+
+func (gameType GameType) String() string {
+	if format, exists := gameFormats[gameType]; exists {
+		return format.Name
+	}
+	return fmt.Sprintf("GameType(%d)", int(gameType))
+}
+
+// UnmarshalYAML decodes a game type from the name of its format, e.g. "2v2".
+func (gameType *GameType) UnmarshalYAML(value *yaml.Node) error {
+	parsed, ok := ParseGameType(value.Value)
+	if !ok {
+		return fmt.Errorf("line %d: unknown game type %q, expected one of %s", value.Line, value.Value, gameTypeNames())
+	}
+	*gameType = parsed
+	return nil
+}
+
+// ParseGameType returns the game type whose format has the given name.
+func ParseGameType(name string) (GameType, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, gameType := range gameTypes {
+		if gameFormats[gameType].Name == name {
+			return gameType, true
+		}
+	}
+	return 0, false
+}
+
+// gameTypeForPlayers returns the game type whose format can be played by the given number of players.
+func gameTypeForPlayers(players int) (GameType, bool) {
+	for _, gameType := range gameTypes {
+		if format := gameFormats[gameType]; players >= format.MinPlayers && players <= format.MaxPlayers {
+			return gameType, true
+		}
+	}
+	return 0, false
+}
+
+// gameTypeNames lists the names of all formats for error messages.
+func gameTypeNames() string {
+	names := make([]string, len(gameTypes))
+	for i, gameType := range gameTypes {
+		names[i] = gameFormats[gameType].Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// HasTeams reports whether the players of the format play in two teams. Only games with teams have a result.
+func (format GameFormat) HasTeams() bool {
+	return format.TeamSizes[0] > 0 && format.TeamSizes[1] > 0
+}
+
+// checkPlayers reports an error if a game request of the format can't wait for the number of players.
+func (format GameFormat) checkPlayers(players int) error {
+	switch {
+	case players >= format.MinPlayers && players <= format.MaxPlayers:
+		return nil
+	case format.MinPlayers == format.MaxPlayers:
+		return fmt.Errorf("%s games need %d players", format.Name, format.MinPlayers)
+	default:
+		return fmt.Errorf("%s games need %d to %d players", format.Name, format.MinPlayers, format.MaxPlayers)
+	}
+}
+
+// splits returns all distinct ways to split the players of the format into its teams, as indexes into the player
+// list. Teams of the same size are interchangeable, so the first player is always put into the first team.
+func (format GameFormat) splits() [][2][]int {
+	if !format.HasTeams() {
+		return nil
+	}
+	players := format.TeamSizes[0] + format.TeamSizes[1]
+
+	var splits [][2][]int
+	var choose func(next int, teamOne []int)
+	choose = func(next int, teamOne []int) {
+		if len(teamOne) == format.TeamSizes[0] {
+			teamTwo := make([]int, 0, format.TeamSizes[1])
+			for i := range players {
+				if !slices.Contains(teamOne, i) {
+					teamTwo = append(teamTwo, i)
+				}
+			}
+			splits = append(splits, [2][]int{slices.Clone(teamOne), teamTwo})
+			return
+		}
+		for i := next; i < players; i++ {
+			choose(i+1, append(teamOne, i))
+		}
+	}
+	if format.TeamSizes[0] == format.TeamSizes[1] {
+		choose(1, []int{0})
+	} else {
+		choose(0, nil)
+	}
+	return splits
+}
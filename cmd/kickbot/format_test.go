@@ -0,0 +1,66 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGameFormatSplits(t *testing.T) {
+	tests := map[GameType]int{
+		GameTypeTwoVsTwo: 3,
+		GameTypeOneVsOne: 1,
+		GameTypeTwoVsOne: 3,
+		GameTypeRundlauf: 0,
+	}
+	for gameType, expected := range tests {
+		format := gameType.Format()
+		splits := format.splits()
+		if len(splits) != expected {
+			t.Errorf("Expected %d splits of %s, got %v", expected, gameType, splits)
+		}
+		for _, split := range splits {
+			if len(split[0]) != format.TeamSizes[0] || len(split[1]) != format.TeamSizes[1] {
+				t.Errorf("Expected teams of %v in %s, got %v", format.TeamSizes, gameType, split)
+			}
+			players := append(slices.Clone(split[0]), split[1]...)
+			slices.Sort(players)
+			if len(slices.Compact(players)) != format.MinPlayers {
+				t.Errorf("Expected every player in exactly one team, got %v", split)
+			}
+		}
+	}
+}
+
+func TestGameTypeForPlayers(t *testing.T) {
+	tests := map[int]GameType{
+		2: GameTypeOneVsOne,
+		3: GameTypeTwoVsOne,
+		4: GameTypeTwoVsTwo,
+		5: GameTypeRundlauf,
+		8: GameTypeRundlauf,
+	}
+	for players, expected := range tests {
+		if gameType, ok := gameTypeForPlayers(players); !ok || gameType != expected {
+			t.Errorf("gameTypeForPlayers(%d) = %s, %v, expected %s", players, gameType, ok, expected)
+		}
+	}
+	for _, players := range []int{-1, 0, 1, 9} {
+		if _, ok := gameTypeForPlayers(players); ok {
+			t.Errorf("Expected no format for %d players", players)
+		}
+	}
+}
+
+func TestParseGameType(t *testing.T) {
+	for _, gameType := range gameTypes {
+		if parsed, ok := ParseGameType(gameType.String()); !ok || parsed != gameType {
+			t.Errorf("ParseGameType(%q) = %s, %v", gameType.String(), parsed, ok)
+		}
+	}
+	if parsed, ok := ParseGameType(" Rundlauf "); !ok || parsed != GameTypeRundlauf {
+		t.Errorf("Expected the names to be case insensitive, got %s, %v", parsed, ok)
+	}
+	if _, ok := ParseGameType("3v3"); ok {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
@@ -2,306 +2,1560 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/slack-go/slack"
 )
 
 const (
-	CMD_START_ROUND    string = "/kicker"           // Start a game
-	CMD_CANCEL_ROUND          = "/kicker-abbrechen" // cancel a game
-	ACTION_JOIN_ROUND         = "GAME_JOIN"         // Join a game
-	ACTION_LEAVE_ROUND        = "GAME_LEAVE"        // Leave a game in "formation" state after joining
+	CMD_START_ROUND      string = "/kicker"            // Start a game
+	CMD_CANCEL_ROUND            = "/kicker-abbrechen"  // cancel a game
+	CMD_STATS                   = "/kicker-stats"      // Post the leaderboard
+	CMD_POSITION                = "/kicker-position"   // Set the preferred position in 2 vs 2 games
+	ACTION_JOIN_ROUND           = "GAME_JOIN"          // Join a game
+	ACTION_LEAVE_ROUND          = "GAME_LEAVE"         // Leave a game in "formation" state after joining
+	ACTION_RECORD_RESULT        = "GAME_RECORD_RESULT" // Open the modal to enter the result of a started game
+	VIEW_RECORD_RESULT          = "GAME_RESULT_VIEW"   // Submission of the result modal
+	ACTION_SHUFFLE_TEAMS        = "GAME_SHUFFLE_TEAMS" // Reroll the proposed teams of a started 2 vs 2 game
+	ACTION_EXTEND_ROUND         = "GAME_EXTEND"        // Push back the timeout of a game in "formation" state
+	ACTION_QUEUE_TABLE          = "GAME_QUEUE_TABLE"   // Queue a game request until its busy table is free
 )
 
 type SlackChannel string
 
+// reminderLead is how long before the start of a scheduled game its players are reminded.
+const reminderLead = 5 * time.Minute
+
+// DefaultTimeoutWarning is how long before a game request times out its message warns about the timeout.
+const DefaultTimeoutWarning = 5 * time.Minute
+
+// extendBy is how much the creator of a game request can push back its timeout at once.
+const extendBy = 15 * time.Minute
+
+// finishedLobbyHistory is the number of finished game requests that are remembered to answer actions on their messages.
+const finishedLobbyHistory = 200
+
+var (
+	errLobbyLimit    = errors.New("channel has reached the maximum number of lobbies")
+	errPlayerInLobby = errors.New("player is already in a lobby of the channel")
+	errShutDown      = errors.New("game manager is shut down")
+)
+
+// GameManager runs the game requests of a workspace. Every game request is owned by its own goroutine, see
+// GameRequest.do. The bookkeeping across game requests, i.e. the game requests by lobby ID, the seats of the players,
+// the finished game requests, the matches and the positions of the players, is owned by the goroutine of the game
+// manager, see do.
 type GameManager struct {
 	apiClient    SlackClient
-	gameRequests map[SlackChannel]*GameRequest
-	timeoutChan  chan SlackChannel
-	mu           sync.Mutex
+	critical     SlackClient // for the calls that must not wait behind regular ones, e.g. the pings of a full game
+	updates      *updateScheduler
+	store        GameStore // optional, game requests are only kept in memory if no store is set
+	gameRequests map[LobbyID]*GameRequest
+	finished     map[LobbyID]finishedLobby // the most recently finished game requests
+	finishedIDs  []LobbyID                 // lobby IDs of the finished game requests, oldest first
+	matches      map[string]MatchRecord    // all matches by ID, including the ones still waiting for their result
+	ratings      *Ratings
+	positions    map[string]Position      // preferred positions of the players in 2 vs 2 games
+	warning      time.Duration            // how long before the timeout a game request warns about it, zero disables the warning
+	settings     atomic.Pointer[Settings] // reloadable settings, replaced as a whole on reload
+	locales      map[string]userLocale    // cached languages of the users' Slack locales
+	localesMu    sync.Mutex
+	seats        map[seat]*GameRequest // the game request of every player of a channel, see takeSeat
+	metrics      *Metrics
+	retry        RetryConfig
+	rateLimits   RateLimitConfig
+	commandPool  CommandPoolConfig
+	commands     *commandPool // runs the slash commands after they were acknowledged, see queueCommand
+	clock        Clock
+	scheduler    *scheduler             // timeouts, announcements, reminders and table releases, see the job IDs
+	authTestedAt atomic.Int64           // unix nanoseconds of the last accepted auth.test, see Ready
+	botUserID    atomic.Pointer[string] // user ID of the bot reported by auth.test, see botUser
+	inbox        chan func()            // commands of the goroutine of the game manager, see do
+	quit         chan struct{}          // closed on Shutdown
+	stopped      chan struct{}          // closed once the goroutine of the game manager ended
+	stopOnce     sync.Once
+}
+
+// GameManagerOption configures optional dependencies of a GameManager.
+type GameManagerOption func(*GameManager)
+
+// WithGameStore makes the GameManager persist its open game requests in the given store.
+// Persisted game requests are kept on Shutdown and can be resumed with RestoreGames.
+func WithGameStore(store GameStore) GameManagerOption {
+	return func(gameMgr *GameManager) {
+		gameMgr.store = store
+	}
+}
+
+// WithRatingConfig sets the configuration of the player ratings, e.g. to enable the decay for inactive players.
+func WithRatingConfig(config RatingConfig) GameManagerOption {
+	return func(gameMgr *GameManager) {
+		gameMgr.ratings = NewRatings(config)
+	}
+}
+
+// WithTimeoutWarning sets how long before the timeout the message of a game request warns that it is about to expire.
+// A zero duration disables the warning.
+func WithTimeoutWarning(warning time.Duration) GameManagerOption {
+	return func(gameMgr *GameManager) {
+		gameMgr.warning = warning
+	}
+}
+
+// WithMetrics makes the GameManager record its games and the calls of its SlackClient in the given metrics,
+// which may be shared with other GameManagers. Without it the metrics are recorded in an unregistered registry.
+func WithMetrics(metrics *Metrics) GameManagerOption {
+	return func(gameMgr *GameManager) {
+		gameMgr.metrics = metrics
+	}
+}
+
+// WithRetryConfig sets how the calls of the Slack API are retried, see retryingClient.
+func WithRetryConfig(config RetryConfig) GameManagerOption {
+	return func(gameMgr *GameManager) {
+		gameMgr.retry = config
+	}
+}
+
+// WithRateLimitConfig sets how often the Slack API is called and how updates of messages are coalesced, see
+// rateLimiter and updateScheduler.
+func WithRateLimitConfig(config RateLimitConfig) GameManagerOption {
+	return func(gameMgr *GameManager) {
+		gameMgr.rateLimits = config
+	}
 }
 
-func NewGameManager(client SlackClient) *GameManager {
+// WithCommandPoolConfig sets how many slash commands run at the same time and how many wait, see
+// DefaultCommandPoolConfig.
+func WithCommandPoolConfig(config CommandPoolConfig) GameManagerOption {
+	return func(gameMgr *GameManager) {
+		gameMgr.commandPool = config
+	}
+}
+
+// WithClock sets the clock that the timeouts, announcements and reminders of the games follow, e.g. a fake clock
+// in tests.
+func WithClock(clock Clock) GameManagerOption {
+	return func(gameMgr *GameManager) {
+		gameMgr.clock = clock
+	}
+}
+
+// WithSettings sets the global and per channel settings of the games, see ApplySettings.
+func WithSettings(settings Settings) GameManagerOption {
+	return func(gameMgr *GameManager) {
+		gameMgr.settings.Store(&settings)
+	}
+}
+
+func NewGameManager(client SlackClient, opts ...GameManagerOption) *GameManager {
 	gameMgr := &GameManager{
 		apiClient:    client,
-		gameRequests: make(map[SlackChannel]*GameRequest),
-		mu:           sync.Mutex{},
-		timeoutChan:  make(chan SlackChannel, 10),
+		gameRequests: make(map[LobbyID]*GameRequest),
+		finished:     make(map[LobbyID]finishedLobby),
+		matches:      make(map[string]MatchRecord),
+		ratings:      NewRatings(DefaultRatingConfig),
+		positions:    make(map[string]Position),
+		warning:      DefaultTimeoutWarning,
+		locales:      make(map[string]userLocale),
+		seats:        make(map[seat]*GameRequest),
+		retry:        DefaultRetryConfig,
+		rateLimits:   DefaultRateLimitConfig,
+		commandPool:  DefaultCommandPoolConfig,
+		clock:        wallClock{},
+		inbox:        make(chan func()),
+		quit:         make(chan struct{}),
+		stopped:      make(chan struct{}),
 	}
-	go gameMgr.handleTimeouts()
+	defaults := DefaultSettings()
+	gameMgr.settings.Store(&defaults)
+	for _, opt := range opts {
+		opt(gameMgr)
+	}
+	if gameMgr.metrics == nil {
+		gameMgr.metrics = NewMetrics(prometheus.NewRegistry())
+	}
+	// every attempt of a call is recorded in the metrics, but only the first one waits for the rate limiter
+	retrying := newRetryingClient(&instrumentedClient{client: gameMgr.apiClient, metrics: gameMgr.metrics}, gameMgr.retry)
+	limiter := newRateLimiter(gameMgr.rateLimits)
+	gameMgr.apiClient = &throttledClient{client: retrying, limiter: limiter}
+	gameMgr.critical = &throttledClient{client: retrying, limiter: limiter, critical: true}
+	gameMgr.updates = newUpdateScheduler(gameMgr.apiClient, gameMgr.critical, gameMgr.rateLimits.Debounce)
+	gameMgr.scheduler = newScheduler(gameMgr.clock)
+	gameMgr.commands = newCommandPool(gameMgr.commandPool)
+	gameMgr.scheduler.every("locales", userLocaleTTL, gameMgr.pruneLocales)
+	go gameMgr.run()
 	return gameMgr
 }
 
+// run runs the commands of the game manager one after another until it is shut down.
+func (gameMgr *GameManager) run() {
+	defer close(gameMgr.stopped)
+	for {
+		select {
+		case cmd := <-gameMgr.inbox:
+			cmd()
+		case <-gameMgr.quit:
+			return
+		}
+	}
+}
+
+// do runs the command on the goroutine of the game manager and waits for it. The commands of the game manager read
+// and change its bookkeeping and copy what the caller needs, they must not wait for game requests or Slack, so that
+// game requests can wait for them. Once the game manager was shut down, do reports false without running the command.
+func (gameMgr *GameManager) do(cmd func()) bool {
+	done := make(chan struct{})
+	select {
+	case gameMgr.inbox <- func() {
+		defer close(done)
+		cmd()
+	}:
+		<-done
+		return true
+	case <-gameMgr.stopped:
+		return false
+	}
+}
+
+// Settings returns the current settings. The returned settings must not be modified.
+func (gameMgr *GameManager) Settings() *Settings {
+	return gameMgr.settings.Load()
+}
+
+// ApplySettings replaces the settings, e.g. after the configuration file was reloaded. Game requests that are
+// already open keep their timeout and game type, the new settings apply to everything that happens from now on.
+func (gameMgr *GameManager) ApplySettings(settings Settings) {
+	gameMgr.settings.Store(&settings)
+}
+
+// lobbyStyle returns how the messages of the game requests in the channel are rendered.
+func (gameMgr *GameManager) lobbyStyle(channel SlackChannel) lobbyStyle {
+	settings := gameMgr.Settings()
+	channelSettings := settings.Channel(channel)
+	return lobbyStyle{lang: channelSettings.Language, mention: channelSettings.MentionStyle, cancel: settings.Commands.Cancel, now: gameMgr.localNow()}
+}
+
+// localNow returns the current time in the time zone of the settings, in which the start times of scheduled games
+// are given and shown.
+func (gameMgr *GameManager) localNow() time.Time {
+	return gameMgr.clock.Now().In(gameMgr.Settings().Timezone.Location())
+}
+
+// requestStyle returns how the messages of the game request are rendered, including the table it targets.
+func (gameMgr *GameManager) requestStyle(gameReq *GameRequest) lobbyStyle {
+	style := gameMgr.lobbyStyle(gameReq.channel)
+	if gameReq.table != "" {
+		style.table = gameMgr.tableLabel(gameReq.table)
+	}
+	return style
+}
+
 // CreateGame initializes a new game request in the specified Slack channel. It posts a game request message to
 // the channel, allowing users to join. it handles the game creation process triggered by a Slack slash command (/kicker).
-// If a game is already being prepared in the channel, no new game is created and
-// it notifies the user who attempted to start a new game.
-// The game type (e.g., TwoVsTwo, OneVsOne) is specified in the call. (/kicker & /kicker1v1)
+// Up to max_lobbies game requests can form in a channel at the same time, each with its own lobby ID.
+// No new game is created if the channel reached that limit or the player is already part of another game request
+// in the channel, instead the user who attempted to start a new game is notified, through the response_url of the
+// slash command if the game options carry one.
+// The game type (e.g., TwoVsTwo, OneVsOne, Rundlauf) is specified in the call, along with the number of players
+// the game request waits for if its format allows a range of players (/kicker --format rundlauf --players 6).
+//
+// Games with a start time (/kicker --at 12:30 or --in 45m) are scheduled. Players can join ahead of time and the
+// game request expires at the start time if it isn't full by then. With a lead time (--lead 15m) the game request
+// is only announced that long before the start.
+//
+// Games can target a table of the configuration (--table dach). If the table is busy the player is told until when
+// and offered to queue, queued game requests are announced once the table is free (see releaseTable).
 func (gameMgr *GameManager) CreateGame(channel SlackChannel, player string, gameOptions GameOpts) {
 
-	gameReq := NewGameRequest(gameOptions.gameType, player)
+	gameReq := NewGameRequest(gameOptions.gameType, player, gameMgr.clock.Now())
+	gameReq.channel = channel
+	gameReq.timeout = gameOptions.timeout
+	if gameOptions.players > 0 {
+		gameReq.quorum = gameOptions.players
+	}
+	gameReq.startAt = gameOptions.startAt
+	if announceAt := gameOptions.startAt.Add(-gameOptions.lead); gameOptions.lead > 0 && announceAt.After(gameMgr.clock.Now()) {
+		gameReq.announceAt = announceAt
+	}
+	if gameOptions.table != "" {
+		settings := gameMgr.Settings()
+		table, exists := settings.Table(gameOptions.table)
+		switch {
+		case len(settings.Tables) == 0:
+			gameMgr.respond(channel, player, gameOptions.responseURL, msgNoTables)
+			return
+		case !exists:
+			gameMgr.respond(channel, player, gameOptions.responseURL, msgUnknownTable, gameOptions.table, settings.tableNames())
+			return
+		}
+		gameReq.table = table.Name
+		// scheduled games only need the table at their start
+		if until := gameMgr.TableBusyUntil(table.Name); until.After(gameMgr.clock.Now()) && until.After(gameOptions.startAt) {
+			if !gameOptions.queue || !gameOptions.startAt.IsZero() {
+				lang := gameMgr.userLanguage(channel, player)
+				gameMgr.apiClient.PostEphemeral(string(channel), player, TableBusyMsg(lang, table.Label(), until, gameOptions.queueParams(), gameMgr.localNow()))
+				return
+			}
+			gameReq.queuedAt = gameMgr.clock.Now()
+		}
+	}
+
+	// the game request is set up by its first command, so that other commands only reach it once it is announced,
+	// queued or scheduled
+	var err error
+	var queued bool
+	gameReq.do(func() func() {
+		if err = gameMgr.addGameRequest(gameReq); err != nil {
+			gameReq.closed = true
+			return nil
+		}
+		switch queued = !gameReq.queuedAt.IsZero(); {
+		case queued:
+			gameMgr.saveGameRequest(gameReq)
+		case !gameReq.announceAt.IsZero():
+			gameMgr.startAnnounceTimer(gameReq)
+			gameMgr.saveGameRequest(gameReq)
+		default:
+			if err = gameMgr.announceGame(gameReq); err != nil {
+				gameMgr.deleteGameRequest(gameReq, lobbyDiscarded)
+			}
+		}
+		return nil
+	})
 
-	if !gameMgr.setGameRequestIfNotExists(channel, gameReq) {
-		gameMgr.apiClient.PostEphemeral(string(channel), player, slack.MsgOptionText("Eine runde wird bereits vorbereitet!", false))
-		return
+	switch {
+	case errors.Is(err, errPlayerInLobby):
+		gameMgr.respond(channel, player, gameOptions.responseURL, msgInOtherLobby)
+	case errors.Is(err, errLobbyLimit):
+		gameMgr.respond(channel, player, gameOptions.responseURL, msgLobbyLimit, gameMgr.Settings().Channel(channel).MaxLobbies)
+	case err != nil:
+		slog.Error("Failed to send message", "error", err)
+		gameMgr.respond(channel, player, gameOptions.responseURL, msgError)
+	case queued:
+		gameMgr.notify(channel, player, msgQueued, gameMgr.tableLabel(gameReq.table), gameMgr.queueLength(gameReq.table))
+		// the table may have become free since it was checked
+		gameMgr.releaseTable(gameReq.table)
+	case !gameReq.announceAt.IsZero():
+		gameMgr.apiClient.PostEphemeral(string(channel), player, ScheduledConfirmationMsg(gameMgr.userLanguage(channel, player), gameReq.startAt, gameReq.announceAt, gameMgr.localNow()))
 	}
+}
 
-	msg := NewGameRequestMsg(player, gameOptions.gameType)
-	_, ts, err := gameMgr.apiClient.PostMessage(string(channel), msg)
+// announceGame posts the message of a game request to its channel and arms its timeout. Scheduled games time out
+// at their start time, all others once their timeout passed after the announcement. It runs on the goroutine of
+// the game request.
+func (gameMgr *GameManager) announceGame(gameReq *GameRequest) error {
+	msg := NewGameRequestMsg(gameMgr.requestStyle(gameReq), gameReq.id, gameReq.players[0], gameReq.gameType, gameReq.quorum, gameReq.startAt)
+	_, ts, err := gameMgr.apiClient.PostMessage(string(gameReq.channel), msg)
 	if err != nil {
-		slog.Error("Failed to send message", "error", err)
-		gameMgr.deleteGameRequest(channel)
-		gameMgr.apiClient.PostEphemeral(string(channel), player, slack.MsgOptionText("Ein Feheler ist aufgetreten!", false))
-		return
+		return err
 	}
 
-	gameReq.mu.Lock()
 	gameReq.messageTs = ts
-	ctx, cancel := context.WithCancel(context.Background())
-	gameReq.timerCancelFunc = cancel
-	gameReq.timer = time.AfterFunc(gameOptions.timeout, func() {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			gameMgr.timeoutChan <- channel
+	if gameReq.startAt.IsZero() {
+		gameReq.deadline = gameMgr.clock.Now().Add(gameReq.timeout)
+	} else {
+		gameReq.deadline = gameReq.startAt
+	}
+	gameMgr.startTimer(gameReq)
+	gameMgr.saveGameRequest(gameReq)
+	return nil
+}
+
+// startAnnounceTimer schedules the delayed announcement of a scheduled game. If the announcement fails the game
+// request is dropped and its creator is notified. The caller must run on the goroutine of the game request.
+func (gameMgr *GameManager) startAnnounceTimer(gameReq *GameRequest) {
+	gameMgr.scheduler.schedule(announceJob(gameReq.id), gameReq.announceAt, func() {
+		gameReq.do(func() func() {
+			if gameReq.closed || gameReq.announced() {
+				return nil
+			}
+			if err := gameMgr.announceGame(gameReq); err != nil {
+				slog.Error("Failed to announce scheduled game", "lobby", gameReq.id, "error", err)
+				gameMgr.deleteGameRequest(gameReq, lobbyDiscarded)
+				return gameMgr.reply(gameReq.channel, gameReq.players[0], msgAnnounceFailed)
+			}
+			return nil
+		})
+	})
+}
+
+// RestoreGames resumes the game requests persisted in the store, e.g. after a restart of the bot.
+// Restored game requests keep using their existing Slack messages and their timeouts are re-armed
+// with the remaining time. Game requests whose deadline passed while the bot was down expire right away. The
+// reminders of scheduled matches that didn't start yet are scheduled again.
+func (gameMgr *GameManager) RestoreGames() error {
+	if gameMgr.store == nil {
+		return nil
+	}
+
+	matches, err := gameMgr.store.LoadMatches()
+	if err != nil {
+		return fmt.Errorf("failed to load matches: %w", err)
+	}
+	positions, err := gameMgr.store.LoadPlayerPositions()
+	if err != nil {
+		return fmt.Errorf("failed to load player positions: %w", err)
+	}
+
+	gameMgr.do(func() {
+		for _, match := range matches {
+			gameMgr.matches[match.ID] = match
+		}
+		maps.Copy(gameMgr.positions, positions)
+	})
+	gameMgr.ratings.Recompute(matches)
+
+	records, err := gameMgr.store.LoadGameRequests()
+	if err != nil {
+		return fmt.Errorf("failed to load game requests: %w", err)
+	}
+
+	for _, record := range records {
+		gameReq := gameRequestFromRecord(record)
+		gameReq.do(func() func() {
+			gameMgr.setGameRequest(gameReq)
+			switch {
+			case !gameReq.queuedAt.IsZero():
+				// queued game requests wait for the release of their table below
+			case !gameReq.announced():
+				gameMgr.startAnnounceTimer(gameReq)
+			default:
+				gameMgr.startTimer(gameReq)
+			}
+			return nil
+		})
+	}
+	slog.Info("Restored game requests", "count", len(records))
+
+	for _, match := range matches {
+		if !match.IsRecorded() {
+			gameMgr.scheduleReminder(match)
 		}
+	}
+
+	tables := make(map[string]bool)
+	for _, match := range matches {
+		tables[match.Table] = true
+	}
+	for _, record := range records {
+		tables[record.Table] = true
+	}
+	delete(tables, "")
+	for table := range tables {
+		gameMgr.occupyTable(table)
+		gameMgr.releaseTable(table)
+	}
+	return nil
+}
+
+// startTimer schedules the timeout of the game request at the game request's deadline, as well as the warning
+// shortly before. The caller must run on the goroutine of the game request.
+func (gameMgr *GameManager) startTimer(gameReq *GameRequest) {
+	if warnAt := gameReq.deadline.Add(-gameMgr.warning); gameMgr.warning > 0 && warnAt.After(gameMgr.clock.Now()) {
+		gameMgr.scheduler.schedule(warningJob(gameReq.id), warnAt, func() {
+			gameMgr.warnTimeout(gameReq)
+		})
+	}
+	gameMgr.scheduler.schedule(timeoutJob(gameReq.id), gameReq.deadline, func() {
+		gameMgr.expireGame(gameReq)
 	})
-	gameReq.mu.Unlock()
+}
 
+// stopTimers cancels the timeout, the warning and the delayed announcement of the game request.
+func (gameMgr *GameManager) stopTimers(gameReq *GameRequest) {
+	gameMgr.scheduler.cancel(timeoutJob(gameReq.id))
+	gameMgr.scheduler.cancel(warningJob(gameReq.id))
+	gameMgr.scheduler.cancel(announceJob(gameReq.id))
 }
 
-// CancelGame cancels an ongoing game round in the specified Slack channel. It updates the game request status
-// in the Slack channel and notifies the users about the cancellation.
-func (gameMgr *GameManager) CancelGame(channel SlackChannel, requester string) {
-	gameReq, exists := gameMgr.getGameRequest(channel)
+// warnTimeout updates the message of a game request that is about to time out with the remaining time
+// and the number of missing players. Game requests that already have enough players to start aren't warned.
+func (gameMgr *GameManager) warnTimeout(gameReq *GameRequest) {
+	gameReq.do(func() func() {
+		if gameReq.closed || gameReq.closing || len(gameReq.players) >= gameReq.gameType.Format().MinPlayers {
+			return nil
+		}
+		msg := TimeoutWarningMsg(gameMgr.requestStyle(gameReq), gameReq.id, gameReq.gameType, gameReq.players, gameReq.quorum, gameReq.startAt, gameReq.deadline.Sub(gameMgr.clock.Now()))
+		gameMgr.updates.update(gameReq.channel, gameReq.messageTs, msg, false)
+		return nil
+	})
+}
+
+// ExtendGame pushes back the timeout of a game request by extendBy. Only the creator of the game request can extend
+// it and scheduled games keep their start time. It handles user interactions with the '+15 Min' button on the Slack
+// message interface which triggers the `ACTION_EXTEND_ROUND` action.
+func (gameMgr *GameManager) ExtendGame(channel SlackChannel, id LobbyID, player string) {
+	gameReq, exists := gameMgr.getGameRequest(channel, id)
 	if !exists {
-		gameMgr.apiClient.PostEphemeral(string(channel), requester, slack.MsgOptionText("Kein Spiel ist derzeit aktiv.", false))
+		gameMgr.rejectStaleLobby(channel, id, player)
 		return
 	}
 
-	gameReq.mu.Lock()
-	isCreator := len(gameReq.players) > 0 && gameReq.players[0] == requester
-	gameReq.mu.Unlock()
+	gameReq.do(func() func() {
+		switch {
+		case gameReq.closed:
+			return func() { gameMgr.rejectStaleLobby(channel, id, player) }
+		case gameReq.starting:
+			return gameMgr.reply(channel, player, msgLobbyStarted)
+		case gameReq.closing:
+			return gameMgr.reply(channel, player, msgLobbyClosing)
+		case gameReq.players[0] != player:
+			return gameMgr.reply(channel, player, msgExtendCreatorOnly)
+		case !gameReq.startAt.IsZero():
+			return gameMgr.reply(channel, player, msgExtendScheduled)
+		}
+		gameMgr.stopTimers(gameReq)
+		gameReq.deadline = gameReq.deadline.Add(extendBy)
+		gameMgr.startTimer(gameReq)
+		gameMgr.saveGameRequest(gameReq)
+		gameMgr.updateLobbyMessage(gameReq)
+		deadline := gameReq.deadline
+		return func() {
+			lang := gameMgr.userLanguage(channel, player)
+			gameMgr.apiClient.PostEphemeral(string(channel), player, slack.MsgOptionText(lang.Text(msgExtended, clockTime(lang, deadline, gameMgr.localNow())), false))
+		}
+	})
+}
+
+// saveGameRequest persists the current state of the game request if the game manager has a store.
+// It runs on the goroutine of the game request.
+func (gameMgr *GameManager) saveGameRequest(gameReq *GameRequest) {
+	if gameMgr.store == nil || gameReq.closed {
+		return
+	}
+	if err := gameMgr.store.SaveGameRequest(gameReq.record()); err != nil {
+		slog.Error("Failed to persist game request", "lobby", gameReq.id, "channel", gameReq.channel, "error", err)
+	}
+}
 
-	if !isCreator {
-		gameMgr.apiClient.PostEphemeral(string(channel), requester, slack.MsgOptionText("Nur der Ersteller des Spiels kann es abbrechen.", false))
+// CancelGame cancels an ongoing game round in the specified Slack channel. It updates the game request status
+// in the Slack channel and notifies the users about the cancellation. Without a lobby ID the game request
+// created by the requester is cancelled. The game request is only removed once its message shows the cancellation,
+// if the message can't be updated the game request stays open and the requester is told so, see undoClose. The
+// update is awaited after the command of the game request, which rejects other actions on it in the meantime.
+func (gameMgr *GameManager) CancelGame(channel SlackChannel, requester string, id LobbyID) {
+	var gameReq *GameRequest
+	var exists bool
+	if id == "" {
+		gameReq, exists = gameMgr.createdGameRequest(channel, requester)
+	} else {
+		gameReq, exists = gameMgr.getGameRequest(channel, id)
+	}
+	if !exists {
+		gameMgr.notify(channel, requester, msgNoActiveGame)
 		return
 	}
 
-	gameMgr.deleteGameRequest(channel)
+	gameReq.do(func() func() {
+		switch {
+		case gameReq.closed:
+			return gameMgr.reply(channel, requester, msgNoActiveGame)
+		case gameReq.players[0] != requester:
+			return gameMgr.reply(channel, requester, msgCancelCreatorOnly)
+		case gameReq.starting:
+			return gameMgr.reply(channel, requester, msgLobbyStarted)
+		case gameReq.closing:
+			return gameMgr.reply(channel, requester, msgLobbyClosing)
+		}
 
-	cancelMessage := slack.MsgOptionText("Die Runde wurde abgebrochen.", false)
-	_, _, _, err := gameMgr.apiClient.UpdateMessage(string(channel), gameReq.messageTs, cancelMessage)
-	if err != nil {
-		slog.Error("Failed to update game message", "error", err)
+		// scheduled and queued games that weren't announced yet have no message to update
+		if !gameReq.announced() {
+			gameMgr.deleteGameRequest(gameReq, lobbyCancelled)
+			return func() {
+				gameMgr.releaseTable(gameReq.table)
+				gameMgr.notify(channel, requester, msgCancelled)
+			}
+		}
+
+		gameReq.closing = true
+		result := gameMgr.updates.update(channel, gameReq.messageTs, cancelMSG(gameMgr.channelLanguage(channel)), true)
+		return func() {
+			if err := <-result; err != nil {
+				slog.Error("Failed to update game message, game stays open", "lobby", gameReq.id, "error", err)
+				gameReq.do(func() func() {
+					gameMgr.undoClose(gameReq)
+					return gameMgr.reply(channel, requester, msgCancelFailed)
+				})
+				return
+			}
+			gameMgr.closeGameRequest(gameReq, lobbyCancelled)
+		}
+	})
+}
+
+// closeGameRequest removes a cancelled or abandoned game request once its message was replaced or deleted and
+// announces the next game request queued for its table.
+func (gameMgr *GameManager) closeGameRequest(gameReq *GameRequest, outcome lobbyOutcome) {
+	gameReq.do(func() func() {
+		if !gameReq.closed {
+			gameMgr.deleteGameRequest(gameReq, outcome)
+		}
+		return nil
+	})
+	gameMgr.releaseTable(gameReq.table)
+}
+
+// undoClose reopens a game request whose message couldn't be replaced or deleted when it was cancelled or abandoned.
+// A timeout that passed in the meantime was skipped, so it is armed again.
+func (gameMgr *GameManager) undoClose(gameReq *GameRequest) {
+	if gameReq.closed {
+		return
+	}
+	gameReq.closing = false
+	if !gameReq.deadline.IsZero() && !gameMgr.clock.Now().Before(gameReq.deadline) {
+		gameMgr.startTimer(gameReq)
 	}
 }
 
 // JoinGame is called when a user wants to join an existing game request. It updates the game request status
 // in the Slack channel. If the game request reaches quorum, it marks the game as ready to start and notifies the users. This function
 // handles user interactions with the 'join' or 'Bin dabei!' button on the Slack message interface
-// which triggers the `ACTION_JOIN_ROUND` action.
-func (gameMgr *GameManager) JoinGame(channel SlackChannel, player string) {
-	var updateMsg slack.MsgOption
-	var gameMsgTS string
-	var isGameComplete bool
-	var players []string
-
-	gameReq, exists := gameMgr.getGameRequest(channel)
+// which triggers the `ACTION_JOIN_ROUND` action. Players joining a game that is already full are put on its waitlist
+// and seed the next game request once the game was announced. A player can only be part of a single game request
+// of a channel at a time. Joining a game request that already finished is rejected, see rejectStaleLobby.
+//
+// The player takes the seat right away, so that concurrent joins elsewhere in the channel can't take it as well.
+// The update of the message may be coalesced with the ones of concurrent joins, see updateScheduler. If it can't be
+// sent, the join is taken back, see undoJoin. The game only starts once its message was replaced.
+func (gameMgr *GameManager) JoinGame(channel SlackChannel, id LobbyID, player string) {
+	gameReq, exists := gameMgr.getGameRequest(channel, id)
 	if !exists {
-		gameMgr.apiClient.PostEphemeral(string(channel), player, slack.MsgOptionText("Ein Fehler ist aufgetreten", false))
+		gameMgr.rejectStaleLobby(channel, id, player)
 		return
 	}
+	gameReq.do(func() func() {
+		return gameMgr.join(gameReq, player)
+	})
+}
 
-	// lock game to prevent data races on concurrent joins & leaves
-	gameReq.mu.Lock()
-	{
-		// check if game is already full
-		isGameComplete = len(gameReq.players) == gameReq.quorum
-		if isGameComplete {
-			gameReq.mu.Unlock()
-			gameMgr.apiClient.PostEphemeral(string(channel), player, slack.MsgOptionText("Das Spiel ist bereits voll.", false))
-			return
+// join is the command of JoinGame.
+func (gameMgr *GameManager) join(gameReq *GameRequest, player string) func() {
+	channel := gameReq.channel
+	switch {
+	case gameReq.closed:
+		return func() { gameMgr.rejectStaleLobby(channel, gameReq.id, player) }
+	case gameReq.closing:
+		return gameMgr.reply(channel, player, msgLobbyClosing)
+	case !gameMgr.takeSeat(gameReq, player):
+		return gameMgr.reply(channel, player, msgInOtherLobby)
+	case slices.Contains(gameReq.players, player):
+		return gameMgr.reply(channel, player, msgAlreadyJoined)
+	case slices.Contains(gameReq.waitlist, player):
+		return gameMgr.reply(channel, player, msgAlreadyWaitlisted)
+	case len(gameReq.players) == gameReq.quorum:
+		// put the player on the waitlist since the game is already full
+		gameReq.waitlist = append(gameReq.waitlist, player)
+		position := len(gameReq.waitlist)
+		return func() {
+			gameMgr.apiClient.PostEphemeral(string(channel), player, WaitlistMsg(gameMgr.userLanguage(channel, player), position))
 		}
+	}
+
+	gameReq.players = append(gameReq.players, player)
+	gameMgr.saveGameRequest(gameReq)
+
+	if len(gameReq.players) < gameReq.quorum {
+		result := gameMgr.updateLobbyMessage(gameReq)
+		return func() {
+			if err := <-result; err != nil {
+				gameReq.do(func() func() {
+					return gameMgr.undoJoin(gameReq, player)
+				})
+				return
+			}
+			gameMgr.metrics.joins.WithLabelValues(gameReq.gameType.String()).Inc()
+		}
+	}
 
-		if idx := slices.Index(gameReq.players, player); idx != -1 {
-			gameReq.mu.Unlock()
-			gameMgr.apiClient.PostEphemeral(string(channel), player, slack.MsgOptionText("Du bist bereits im Spiel.", false))
+	// the game has become full after the player joined
+	return gameMgr.startJoined(gameReq, player)
+}
+
+// startJoined starts the game of a game request that the player filled. The game only starts once its message was
+// replaced, which is awaited after the command of the game request, see beginStart. If the message can't be
+// replaced, the join of the player is taken back, see undoStart.
+func (gameMgr *GameManager) startJoined(gameReq *GameRequest, player string) func() {
+	match, result := gameMgr.beginStart(gameReq, gameMgr.newMatch(gameReq))
+	return func() {
+		if err := <-result; err != nil {
+			slog.Error("Failed to start game, rolling back join", "lobby", gameReq.id, "player", player, "error", err)
+			gameReq.do(func() func() {
+				return gameMgr.undoStart(gameReq, player)
+			})
 			return
 		}
+		gameMgr.completeStart(match)
+		gameMgr.metrics.joins.WithLabelValues(gameReq.gameType.String()).Inc()
+		gameMgr.announceStart(gameReq, match)
+	}
+}
+
+// undoStart takes back the join that filled a game request whose game failed to start, the game request is open
+// again. The first player who ended up on the waitlist while the game was starting takes the seat, which fills the
+// game request again.
+func (gameMgr *GameManager) undoStart(gameReq *GameRequest, player string) func() {
+	if gameReq.closed {
+		return nil
+	}
+	gameReq.starting = false
+	gameReq.players = slices.DeleteFunc(gameReq.players, func(p string) bool { return p == player })
+	gameMgr.releaseSeat(gameReq, player)
+	gameMgr.saveGameRequest(gameReq)
+	reply := gameMgr.reply(gameReq.channel, player, msgJoinFailed)
+	if len(gameReq.waitlist) == 0 {
+		return reply
+	}
 
-		gameReq.players = append(gameReq.players, player)
+	next := gameReq.waitlist[0]
+	gameReq.waitlist = slices.Delete(slices.Clone(gameReq.waitlist), 0, 1)
+	gameReq.players = append(gameReq.players, next)
+	gameMgr.saveGameRequest(gameReq)
+	start := gameMgr.startJoined(gameReq, next)
+	return func() {
+		reply()
+		start()
+	}
+}
 
-		// check if game has become full after the player joined
-		isGameComplete = len(gameReq.players) == gameReq.quorum
-		gameMsgTS = gameReq.messageTs
-		updateMsg = GameRequestUpdateMsg(gameReq.players, gameReq.quorum)
-		players = slices.Clone(gameReq.players)
+// undoJoin takes back a join that the message of the game request failed to show, unless the game request moved on
+// in the meantime, e.g. the game started with the player or the player is the only one left. The player is only
+// told if the join was taken back.
+func (gameMgr *GameManager) undoJoin(gameReq *GameRequest, player string) func() {
+	if gameReq.closed || gameReq.starting || gameReq.closing || len(gameReq.players) < 2 || !slices.Contains(gameReq.players, player) {
+		return nil
 	}
-	gameReq.mu.Unlock()
+	slog.Warn("Rolling back join", "lobby", gameReq.id, "player", player)
+	gameReq.players = slices.DeleteFunc(gameReq.players, func(p string) bool { return p == player })
+	gameMgr.releaseSeat(gameReq, player)
+	gameMgr.saveGameRequest(gameReq)
+	gameMgr.updateLobbyMessage(gameReq)
+	return gameMgr.reply(gameReq.channel, player, msgJoinFailed)
+}
 
-	if isGameComplete {
-		var playerString = "<@" + strings.Join(players, ">, <@") + ">"
-		var gameStartMessage = fmt.Sprintf("Die Runde ist voll, %s zum Kickertisch! :kicker:", playerString)
-		var wg sync.WaitGroup
-		wg.Add(len(players))
-		for _, playerId := range players {
-			go func(playerId string) {
-				_, err := gameMgr.apiClient.PostEphemeral(string(channel), playerId, slack.MsgOptionText(gameStartMessage, false))
-				if err != nil {
-					slog.Error("Failed to ping user", "userid", playerId, "error", err.Error())
-				}
-				wg.Done()
-			}(playerId)
+// updateLobbyMessage submits the update of the message of a game request to show its current players. It runs on
+// the goroutine of the game request, so that the updates of the message are submitted in order.
+func (gameMgr *GameManager) updateLobbyMessage(gameReq *GameRequest) <-chan error {
+	msg := GameRequestUpdateMsg(gameMgr.requestStyle(gameReq), gameReq.id, gameReq.gameType, gameReq.players, gameReq.quorum, gameReq.startAt)
+	return gameMgr.updates.update(gameReq.channel, gameReq.messageTs, msg, false)
+}
+
+// newMatch returns the match of a game request that is about to start, with its current players. Scheduled games
+// start at their start time even if they are full earlier.
+func (gameMgr *GameManager) newMatch(gameReq *GameRequest) MatchRecord {
+	match := newMatchRecord(gameReq.channel, gameReq.gameType, gameReq.players, gameReq.messageTs, gameMgr.clock.Now())
+	if gameReq.startAt.After(match.StartedAt) {
+		match.StartedAt = gameReq.startAt
+	}
+	return match
+}
+
+// startGame starts a game request that reached its quorum, see beginStart. It waits for the game start message on
+// the goroutine of the game request, the players are pinged afterwards by announceStart.
+//
+// The game only starts once its message was replaced. If that fails, the game request stays open and the error is
+// returned, so that the caller can undo what filled it.
+func (gameMgr *GameManager) startGame(gameReq *GameRequest, match MatchRecord) (MatchRecord, error) {
+	match, result := gameMgr.beginStart(gameReq, match)
+	if err := <-result; err != nil {
+		gameReq.starting = false
+		return match, err
+	}
+	gameMgr.completeStart(match)
+	return match, nil
+}
+
+// beginStart proposes the teams of a game request that reached its quorum or, for formats without teams, the order
+// around the table and submits the game start message that replaces the game request message. It runs on the
+// goroutine of the game request and marks it as starting, so that players who join end up on the waitlist, which
+// seeds the next game request, and the game request can't be left, extended or cancelled anymore. Once the message
+// was replaced, completeStart starts the game.
+func (gameMgr *GameManager) beginStart(gameReq *GameRequest, match MatchRecord) (MatchRecord, <-chan error) {
+	channel := match.Channel
+	match.Table = gameReq.table
+	switch format := match.GameType.Format(); {
+	case !format.HasTeams():
+		match.Players = tableOrder(match.Players)
+	case len(format.splits()) > 1:
+		gameMgr.do(func() {
+			match.Teams = proposeTeams(match.GameType, match.Players, gameMgr.ratings, gameMgr.positions, gameMgr.clock.Now())
+		})
+	}
+
+	gameReq.starting = true
+	lang := gameMgr.channelLanguage(channel)
+	return match, gameMgr.updates.update(channel, match.MessageTs, GameStartMsg(lang, match, gameMgr.localNow()), true)
+}
+
+// completeStart records the match of a game whose start message was posted. The table of the game is busy from now
+// on.
+func (gameMgr *GameManager) completeStart(match MatchRecord) {
+	gameMgr.saveMatch(match)
+	if match.Table != "" {
+		gameMgr.occupyTable(match.Table)
+	}
+}
+
+// announceStart pings the players of a game started by startGame and closes its game request afterwards. Players
+// who ended up on the waitlist in the meantime seed the next game request in the channel. Scheduled games that are
+// full ahead of time schedule a reminder for their players shortly before the start.
+func (gameMgr *GameManager) announceStart(gameReq *GameRequest, match MatchRecord) {
+	channel := match.Channel
+	scheduled := match.StartedAt.After(gameMgr.clock.Now())
+	var playerString = "<@" + strings.Join(match.Players, ">, <@") + ">"
+	var wg sync.WaitGroup
+	wg.Add(len(match.Players))
+	for _, playerId := range match.Players {
+		go func(playerId string) {
+			lang := gameMgr.userLanguage(channel, playerId)
+			gameStartMessage := lang.Text(msgGameFull, playerString)
+			if scheduled {
+				gameStartMessage = lang.Text(msgGameFullScheduled, playerString, clockTime(lang, match.StartedAt, gameMgr.localNow()))
+			}
+			_, err := gameMgr.critical.PostEphemeral(string(channel), playerId, slack.MsgOptionText(gameStartMessage, false))
+			if err != nil {
+				slog.Error("Failed to ping user", "userid", playerId, "error", err.Error())
+			}
+			wg.Done()
+		}(playerId)
+	}
+	wg.Wait()
+
+	var waitlist []string
+	var quorum int
+	gameReq.do(func() func() {
+		if gameReq.closed {
+			return nil
 		}
-		wg.Wait()
-		gameMgr.deleteGameRequest(channel)
+		// the game request is closed now, so the waitlist can't grow anymore
+		waitlist, quorum = gameReq.waitlist, gameReq.quorum
+		gameMgr.deleteGameRequest(gameReq, lobbyStarted)
+		return nil
+	})
+
+	gameMgr.scheduleReminder(match)
+
+	if len(waitlist) > 0 {
+		gameMgr.seedGame(channel, match.GameType, quorum, match.Table, gameReq.timeout, waitlist)
 	}
+}
 
-	// TODO: Implement retry mechanism to be reslient against transient network errors
-	_, _, _, err := gameMgr.apiClient.UpdateMessage(string(channel), gameMsgTS, updateMsg)
-	if err != nil {
-		// TODO: Implement thread safe rollback of the game state
-		slog.Error("Failed to update game message", "error", err)
-		gameMgr.apiClient.PostEphemeral(string(channel), player, slack.MsgOptionText("Es gab ein technisches Problem beim Beitritt zum Spiel.", false))
+// scheduleReminder schedules the reminder of the players of a scheduled game shortly before its start. Games that
+// start sooner aren't reminded.
+func (gameMgr *GameManager) scheduleReminder(match MatchRecord) {
+	remindAt := match.StartedAt.Add(-reminderLead)
+	if !remindAt.After(gameMgr.clock.Now()) {
 		return
 	}
+	gameMgr.scheduler.schedule(reminderJob(match.ID), remindAt, func() {
+		if _, _, err := gameMgr.apiClient.PostMessage(string(match.Channel), ReminderMsg(gameMgr.channelLanguage(match.Channel), match, gameMgr.localNow())); err != nil {
+			slog.Error("Failed to post reminder", "match", match.ID, "error", err)
+		}
+	})
+}
+
+// seedGame opens the next game request in the channel for the players of a waitlist, with the format and quorum of
+// the previous game on the same table. The first player of the waitlist becomes the creator. Players beyond the quorum stay on the
+// waitlist of the new game request, which starts right away if the waitlist alone fills it. The players were
+// promised the next round, so the seeded game request doesn't count against the lobby limit of the channel.
+func (gameMgr *GameManager) seedGame(channel SlackChannel, gameType GameType, quorum int, table string, timeout time.Duration, waitlist []string) {
+	gameReq := NewGameRequest(gameType, waitlist[0], gameMgr.clock.Now())
+	gameReq.channel = channel
+	gameReq.quorum = quorum
+	gameReq.table = table
+	gameReq.timeout = timeout
+	n := min(len(waitlist), gameReq.quorum)
+	gameReq.players = slices.Clone(waitlist[:n])
+	gameReq.waitlist = slices.Clone(waitlist[n:])
+
+	// the seeded game request is set up by its first command, like a created one
+	gameReq.do(func() func() {
+		gameMgr.setGameRequest(gameReq)
+		gameMgr.metrics.lobbiesCreated.WithLabelValues(gameType.String()).Inc()
+
+		_, ts, err := gameMgr.apiClient.PostMessage(string(channel), SeededGameRequestMsg(gameMgr.requestStyle(gameReq), gameReq.id, gameType, gameReq.players, gameReq.quorum))
+		if err != nil {
+			slog.Error("Failed to send message", "error", err)
+			gameMgr.deleteGameRequest(gameReq, lobbyDiscarded)
+			return nil
+		}
+
+		gameReq.messageTs = ts
+		gameReq.deadline = gameMgr.clock.Now().Add(timeout)
+		isGameComplete := len(gameReq.players) == gameReq.quorum
+		if !isGameComplete {
+			gameMgr.startTimer(gameReq)
+		}
+
+		var match MatchRecord
+		if isGameComplete {
+			if match, err = gameMgr.startGame(gameReq, gameMgr.newMatch(gameReq)); err != nil {
+				// there is no join to undo, so the seeded game request is given up
+				slog.Error("Failed to start seeded game", "lobby", gameReq.id, "error", err)
+				gameMgr.deleteGameRequest(gameReq, lobbyDiscarded)
+				<-gameMgr.updates.remove(channel, ts)
+				return nil
+			}
+		} else {
+			gameMgr.saveGameRequest(gameReq)
+		}
+
+		players := slices.Clone(gameReq.players)
+		return func() {
+			for _, player := range players {
+				gameMgr.notify(channel, player, msgPromoted)
+			}
+			if isGameComplete {
+				gameMgr.announceStart(gameReq, match)
+			}
+		}
+	})
 }
 
 // LeaveGame is called when a user wants to leave a game request they had previously joined. This function updates the
 // game request status in the Slack channel. If all players leave, the game request is cancelled. It handles
 // user interactions with the 'leave' or 'bin raus' button on the Slack message interface which triggers
-// the 'ACTION_LEAVE_ROUND' action
-func (gameMgr *GameManager) LeaveGame(channel SlackChannel, player string) {
+// the 'ACTION_LEAVE_ROUND' action. Leaving a game request that already finished is rejected, see rejectStaleLobby.
+// The leave is taken back if the message of the game request can't be updated, see undoLeave. The last player only
+// leaves once the message was deleted.
+func (gameMgr *GameManager) LeaveGame(channel SlackChannel, id LobbyID, player string) {
 
-	gameReq, exists := gameMgr.getGameRequest(channel)
+	gameReq, exists := gameMgr.getGameRequest(channel, id)
 	if !exists {
-		gameMgr.apiClient.PostEphemeral(string(channel), player, slack.MsgOptionText("Ein Fehler ist aufgetreten", false))
+		gameMgr.rejectStaleLobby(channel, id, player)
 		return
 	}
+	gameReq.do(func() func() {
+		return gameMgr.leave(gameReq, player)
+	})
+}
 
-	var updateMsg slack.MsgOption
-	var isLastPlayer bool
-	var gameMsgTS string
+// leave is the command of LeaveGame.
+func (gameMgr *GameManager) leave(gameReq *GameRequest, player string) func() {
+	channel := gameReq.channel
+	if gameReq.closed {
+		return func() { gameMgr.rejectStaleLobby(channel, gameReq.id, player) }
+	}
+	if gameReq.closing {
+		return gameMgr.reply(channel, player, msgLobbyClosing)
+	}
+	if idx := slices.Index(gameReq.waitlist, player); idx >= 0 {
+		gameReq.waitlist = slices.Delete(gameReq.waitlist, idx, idx+1)
+		gameMgr.releaseSeat(gameReq, player)
+		gameMgr.saveGameRequest(gameReq)
+		return gameMgr.reply(channel, player, msgLeftWaitlist)
+	}
+	idx := slices.Index(gameReq.players, player)
+	if idx < 0 {
+		return gameMgr.reply(channel, player, msgNotInGame)
+	}
+	// a full game is being announced and can't be left anymore
+	if len(gameReq.players) == gameReq.quorum {
+		return gameMgr.reply(channel, player, msgLeaveFull)
+	}
+
+	// the last player leaves once the message was deleted, see undoClose
+	if len(gameReq.players) == 1 {
+		gameReq.closing = true
+		result := gameMgr.updates.remove(channel, gameReq.messageTs)
+		return func() {
+			if err := <-result; err != nil {
+				slog.Error("Failed to delete game message, player stays in the game", "lobby", gameReq.id, "player", player, "error", err)
+				gameReq.do(func() func() {
+					gameMgr.undoClose(gameReq)
+					return gameMgr.reply(channel, player, msgLeaveFailed)
+				})
+				return
+			}
+			gameMgr.metrics.leaves.WithLabelValues(gameReq.gameType.String()).Inc()
+			gameMgr.closeGameRequest(gameReq, lobbyAbandoned)
+		}
+	}
 
-	gameReq.mu.Lock()
-	{
-		idx := slices.Index(gameReq.players, player)
-		if idx < 0 {
-			gameReq.mu.Unlock()
-			gameMgr.apiClient.PostEphemeral(string(channel), player, slack.MsgOptionText("Du bist nicht in der aktuellen Runde.", false))
+	gameReq.players = slices.Delete(slices.Clone(gameReq.players), idx, idx+1)
+	gameMgr.releaseSeat(gameReq, player)
+	gameMgr.saveGameRequest(gameReq)
+	result := gameMgr.updateLobbyMessage(gameReq)
+	return func() {
+		if err := <-result; err != nil {
+			gameReq.do(func() func() {
+				return gameMgr.undoLeave(gameReq, player, idx)
+			})
 			return
 		}
-		// remove player from game
-		gameReq.players = append(gameReq.players[:idx], gameReq.players[idx+1:]...)
-		isLastPlayer = len(gameReq.players) == 0
-		updateMsg = GameRequestUpdateMsg(gameReq.players, gameReq.quorum)
-		gameMsgTS = gameReq.messageTs
+		gameMgr.metrics.leaves.WithLabelValues(gameReq.gameType.String()).Inc()
 	}
-	gameReq.mu.Unlock()
+}
 
-	if isLastPlayer {
-		gameMgr.deleteGameRequest(channel)
-		_, _, err := gameMgr.apiClient.DeleteMessage(string(channel), gameMsgTS)
-		if err != nil {
-			slog.Error("Failed to delete game message", "error", err)
+// undoLeave takes back a leave that the message of the game request failed to show, unless the game request moved on
+// in the meantime, e.g. it filled up or the player joined another one. The player is only told if the leave was
+// taken back.
+func (gameMgr *GameManager) undoLeave(gameReq *GameRequest, player string, idx int) func() {
+	if gameReq.closed || gameReq.starting || gameReq.closing || len(gameReq.players) >= gameReq.quorum ||
+		slices.Contains(gameReq.players, player) || slices.Contains(gameReq.waitlist, player) || !gameMgr.takeSeat(gameReq, player) {
+		return nil
+	}
+	slog.Warn("Rolling back leave", "lobby", gameReq.id, "player", player)
+	gameReq.players = slices.Insert(gameReq.players, min(idx, len(gameReq.players)), player)
+	gameMgr.saveGameRequest(gameReq)
+	gameMgr.updateLobbyMessage(gameReq)
+	return gameMgr.reply(gameReq.channel, player, msgLeaveFailed)
+}
+
+// rejectStaleLobby answers an action on the message of a game request that is no longer open. The player is told
+// what happened to the game request and its message is refreshed to show its final state, in case an earlier
+// update of the message failed or the player looks at an outdated message.
+func (gameMgr *GameManager) rejectStaleLobby(channel SlackChannel, id LobbyID, player string) {
+	var lobby finishedLobby
+	var exists bool
+	var match MatchRecord
+	var matchExists bool
+	gameMgr.do(func() {
+		lobby, exists = gameMgr.finished[id]
+		if exists && lobby.outcome == lobbyStarted {
+			for _, m := range gameMgr.matches {
+				if m.Channel == lobby.channel && m.MessageTs == lobby.messageTs {
+					match, matchExists = m, true
+					break
+				}
+			}
+		}
+	})
+
+	if !exists || lobby.channel != channel {
+		gameMgr.notify(channel, player, msgLobbyUnknown)
+		return
+	}
+	gameMgr.notify(channel, player, staleLobbyText[lobby.outcome])
+
+	lang := gameMgr.channelLanguage(channel)
+	var finalMsg slack.MsgOption
+	switch lobby.outcome {
+	case lobbyStarted:
+		if !matchExists {
+			return
+		}
+		finalMsg = GameStartMsg(lang, match, gameMgr.localNow())
+		if match.IsRecorded() {
+			finalMsg = MatchResultMsg(lang, match)
 		}
+	case lobbyCancelled:
+		finalMsg = cancelMSG(lang)
+	case lobbyExpired:
+		finalMsg = timeoutMSG(lang)
+	default:
 		return
 	}
-	_, _, _, err := gameMgr.apiClient.UpdateMessage(string(channel), gameMsgTS, updateMsg)
+	<-gameMgr.updates.update(channel, lobby.messageTs, finalMsg, false)
+}
+
+// OpenResultForm opens the modal to enter the result of a match. Only players of the match can enter its result
+// and only once. Matches of formats without teams have no result. It handles user interactions with the 'Ergebnis eintragen' button of the game start message
+// which triggers the `ACTION_RECORD_RESULT` action.
+func (gameMgr *GameManager) OpenResultForm(channel SlackChannel, matchID, player, triggerID string) {
+	var match MatchRecord
+	var exists bool
+	gameMgr.do(func() {
+		match, exists = gameMgr.matches[matchID]
+	})
+
+	switch {
+	case !exists:
+		gameMgr.notify(channel, player, msgUnknownMatch)
+		return
+	case !match.HasPlayer(player):
+		gameMgr.notify(channel, player, msgResultPlayersOnly)
+		return
+	case match.IsRecorded():
+		gameMgr.notify(channel, player, msgResultRecorded)
+		return
+	case !match.GameType.Format().HasTeams():
+		gameMgr.notify(channel, player, msgNoResult, match.GameType)
+		return
+	}
+
+	if _, err := gameMgr.apiClient.OpenView(triggerID, ResultModal(gameMgr.userLanguage(channel, player), match)); err != nil {
+		slog.Error("Failed to open result modal", "match", matchID, "error", err)
+		gameMgr.notify(channel, player, msgError)
+	}
+}
+
+// RecordResult stores the result of a match submitted through the result modal and replaces the game start
+// message with the result. It returns validation errors keyed by the block id of the offending modal input,
+// in which case nothing is stored. The errors are rendered in the language of the player.
+func (gameMgr *GameManager) RecordResult(matchID, player string, result MatchResult) map[string]string {
+	var match MatchRecord
+	errs := map[string]Message{resultBlockTeam: newMessage(msgUnknownMatch)}
+	gameMgr.do(func() {
+		var exists bool
+		match, exists = gameMgr.matches[matchID]
+		switch {
+		case !exists:
+			return
+		case !match.HasPlayer(player):
+			errs = map[string]Message{resultBlockTeam: newMessage(msgResultPlayersOnly)}
+		case match.IsRecorded():
+			errs = map[string]Message{resultBlockTeam: newMessage(msgResultRecorded)}
+		default:
+			errs = match.applyResult(result, player, gameMgr.clock.Now())
+		}
+		if errs == nil {
+			gameMgr.matches[matchID] = match
+			gameMgr.rateMatch(match)
+		}
+	})
+	if errs != nil {
+		lang := gameMgr.userLanguage(match.Channel, player)
+		rendered := make(map[string]string, len(errs))
+		for block, msg := range errs {
+			rendered[block] = lang.Render(msg)
+		}
+		return rendered
+	}
+
+	gameMgr.saveMatch(match)
+	gameMgr.releaseTable(match.Table)
+
+	if err := <-gameMgr.updates.update(match.Channel, match.MessageTs, MatchResultMsg(gameMgr.channelLanguage(match.Channel), match), false); err != nil {
+		slog.Error("Failed to update game message with result", "match", matchID, "error", err)
+	}
+	return nil
+}
+
+// ShuffleTeams replaces the proposed teams of a started game with a different random split. Only formats whose
+// players can be split in more than one way, e.g. 2 vs 2 and 2 vs 1, have proposed teams.
+// It handles user interactions with the 'Neu mischen' button of the game start message which triggers
+// the `ACTION_SHUFFLE_TEAMS` action.
+func (gameMgr *GameManager) ShuffleTeams(channel SlackChannel, matchID, player string) {
+	var match MatchRecord
+	reject := msgUnknownMatch
+	gameMgr.do(func() {
+		var exists bool
+		match, exists = gameMgr.matches[matchID]
+		switch {
+		case !exists || len(match.Teams[0]) == 0 || len(match.GameType.Format().splits()) < 2:
+			return
+		case !match.HasPlayer(player):
+			reject = msgShufflePlayersOnly
+			return
+		case match.IsRecorded():
+			reject = msgResultRecorded
+			return
+		}
+		match.Teams = reshuffleTeams(match.GameType, match.Players, match.Teams, gameMgr.positions)
+		gameMgr.matches[matchID] = match
+		reject = ""
+	})
+	if reject != "" {
+		gameMgr.notify(channel, player, reject)
+		return
+	}
+
+	gameMgr.saveMatch(match)
+
+	if err := <-gameMgr.updates.update(match.Channel, match.MessageTs, GameStartMsg(gameMgr.channelLanguage(match.Channel), match, gameMgr.localNow()), false); err != nil {
+		slog.Error("Failed to update game message with new teams", "match", matchID, "error", err)
+	}
+}
+
+// SetPosition stores the preferred position of a player which is respected when teams are proposed.
+// It handles the /kicker-position command.
+func (gameMgr *GameManager) SetPosition(channel SlackChannel, player string, position Position) {
+	gameMgr.do(func() {
+		if position == PositionAny {
+			delete(gameMgr.positions, player)
+		} else {
+			gameMgr.positions[player] = position
+		}
+	})
+
+	if gameMgr.store != nil {
+		if err := gameMgr.store.SavePlayerPosition(player, position); err != nil {
+			slog.Error("Failed to persist player position", "player", player, "error", err)
+		}
+	}
+
+	gameMgr.notify(channel, player, positionConfirmationText[position])
+}
+
+// PostStats posts the leaderboard built from the recorded matches selected by the options. The leaderboard is
+// only visible to the requester unless the options ask for a public post. It handles the /kicker-stats command.
+func (gameMgr *GameManager) PostStats(channel SlackChannel, requester string, statsOptions StatsOpts) {
+	now := gameMgr.clock.Now()
+	standings := leaderboard(gameMgr.recordedMatches(), gameMgr.ratings.config, channel, statsOptions, now)
+	var err error
+	if statsOptions.public {
+		msg := LeaderboardMsg(gameMgr.channelLanguage(channel), standings, statsOptions)
+		_, _, err = gameMgr.apiClient.PostMessage(string(channel), msg)
+	} else {
+		msg := LeaderboardMsg(gameMgr.userLanguage(channel, requester), standings, statsOptions)
+		_, err = gameMgr.apiClient.PostEphemeral(string(channel), requester, msg)
+	}
 	if err != nil {
-		slog.Error("Failed to update game message", "error", err)
+		slog.Error("Failed to post leaderboard", "channel", channel, "error", err)
+	}
+}
+
+// leaderboard rates the recorded matches of the game type within the time window and scope of the options.
+func leaderboard(matches []MatchRecord, config RatingConfig, channel SlackChannel, statsOptions StatsOpts, now time.Time) []PlayerRating {
+	selected := make([]MatchRecord, 0, len(matches))
+	for _, match := range matches {
+		if match.GameType != statsOptions.gameType {
+			continue
+		}
+		if !statsOptions.workspace && match.Channel != channel {
+			continue
+		}
+		if statsOptions.since > 0 && match.StartedAt.Before(now.Add(-statsOptions.since)) {
+			continue
+		}
+		selected = append(selected, match)
 	}
+	return ComputeRatings(config, selected).Standings(statsOptions.gameType, now)
 }
 
-func (gameMgr *GameManager) getGameRequest(channel SlackChannel) (*GameRequest, bool) {
-	gameMgr.mu.Lock()
-	defer gameMgr.mu.Unlock()
+// recordedMatches returns all matches with a result.
+func (gameMgr *GameManager) recordedMatches() []MatchRecord {
+	var matches []MatchRecord
+	gameMgr.do(func() {
+		matches = make([]MatchRecord, 0, len(gameMgr.matches))
+		for _, match := range gameMgr.matches {
+			if match.IsRecorded() {
+				matches = append(matches, match)
+			}
+		}
+	})
+	return matches
+}
 
-	game, exists := gameMgr.gameRequests[channel]
-	return game, exists
+// rateMatch updates the ratings with the result of the match. A match that started before the latest rated one,
+// e.g. on another table, is rated by recomputing the ratings from the history, so that the ratings don't depend on
+// the order in which results are entered. It runs on the goroutine of the game manager, so that results entered at
+// the same time are rated one after another.
+func (gameMgr *GameManager) rateMatch(match MatchRecord) {
+	if gameMgr.ratings.Apply(match) {
+		return
+	}
+	matches := make([]MatchRecord, 0, len(gameMgr.matches))
+	for _, match := range gameMgr.matches {
+		if match.IsRecorded() {
+			matches = append(matches, match)
+		}
+	}
+	gameMgr.ratings.Recompute(matches)
 }
-func (gameMgr *GameManager) setGameRequest(channel SlackChannel, game *GameRequest) {
-	gameMgr.mu.Lock()
-	gameMgr.gameRequests[channel] = game
-	gameMgr.mu.Unlock()
+
+// saveMatch keeps the match in memory and persists it if the game manager has a store.
+func (gameMgr *GameManager) saveMatch(match MatchRecord) {
+	gameMgr.do(func() {
+		gameMgr.matches[match.ID] = match
+	})
+
+	if gameMgr.store == nil {
+		return
+	}
+	if err := gameMgr.store.SaveMatch(match); err != nil {
+		slog.Error("Failed to persist match", "match", match.ID, "error", err)
+	}
 }
 
-func (gameMgr *GameManager) deleteGameRequest(channel SlackChannel) {
-	gameMgr.mu.Lock()
-	defer gameMgr.mu.Unlock()
+// getGameRequest returns the game request with the given lobby ID if it belongs to the channel.
+func (gameMgr *GameManager) getGameRequest(channel SlackChannel, id LobbyID) (*GameRequest, bool) {
+	var game *GameRequest
+	gameMgr.do(func() {
+		if gameReq, exists := gameMgr.gameRequests[id]; exists && gameReq.channel == channel {
+			game = gameReq
+		}
+	})
+	return game, game != nil
+}
 
-	if gameReq, exists := gameMgr.gameRequests[channel]; exists {
-		gameReq.mu.Lock()
-		if gameReq.timer != nil {
-			gameReq.timer.Stop()
+// LobbyByMessage returns the lobby ID of the game request whose message in the channel has the given timestamp.
+// The returned ID is empty if no open game request posted that message.
+func (gameMgr *GameManager) LobbyByMessage(channel SlackChannel, messageTs string) LobbyID {
+	for _, gameReq := range gameMgr.channelLobbies(channel) {
+		if snapshot := gameReq.snapshot(); snapshot.messageTs == messageTs && snapshot.announced && !snapshot.closed {
+			return gameReq.id
 		}
-		gameReq.mu.Unlock()
-		delete(gameMgr.gameRequests, channel)
 	}
+	return ""
 }
 
-// setGameRequestIfNotExists sets a new game for the specified channel only if there isn't already a game present.
-// It returns true if the new game was set, or false if a game already exists for the channel.
-func (gm *GameManager) setGameRequestIfNotExists(channel SlackChannel, game *GameRequest) bool {
-	gm.mu.Lock()
-	defer gm.mu.Unlock()
+// createdGameRequest returns the game request of the channel that was created by the player.
+func (gameMgr *GameManager) createdGameRequest(channel SlackChannel, player string) (*GameRequest, bool) {
+	for _, gameReq := range gameMgr.channelLobbies(channel) {
+		if snapshot := gameReq.snapshot(); snapshot.creator == player && !snapshot.closed {
+			return gameReq, true
+		}
+	}
+	return nil, false
+}
 
-	if _, exists := gm.gameRequests[channel]; exists {
-		return false
+// channelLobbies returns the open game requests of the channel. Their state has to be read with their commands,
+// e.g. with GameRequest.snapshot.
+func (gameMgr *GameManager) channelLobbies(channel SlackChannel) []*GameRequest {
+	var lobbies []*GameRequest
+	gameMgr.do(func() {
+		for _, gameReq := range gameMgr.gameRequests {
+			if gameReq.channel == channel {
+				lobbies = append(lobbies, gameReq)
+			}
+		}
+	})
+	return lobbies
+}
+
+// seat is a player in a channel, who can only be part of a single game request of the channel at a time.
+type seat struct {
+	channel SlackChannel
+	player  string
+}
+
+// takeSeat reserves the seat of the player in the channel of the game request, as a player or on its waitlist.
+// It fails if the player is already part of another game request of the channel.
+func (gameMgr *GameManager) takeSeat(gameReq *GameRequest, player string) bool {
+	taken := false
+	gameMgr.do(func() {
+		key := seat{gameReq.channel, player}
+		if other, exists := gameMgr.seats[key]; exists && other != gameReq {
+			return
+		}
+		gameMgr.seats[key] = gameReq
+		taken = true
+	})
+	return taken
+}
+
+// releaseSeat frees the seat of the player if the player was part of the game request.
+func (gameMgr *GameManager) releaseSeat(gameReq *GameRequest, player string) {
+	gameMgr.do(func() {
+		gameMgr.vacateSeat(gameReq, player)
+	})
+}
+
+// vacateSeat is releaseSeat for commands of the game manager.
+func (gameMgr *GameManager) vacateSeat(gameReq *GameRequest, player string) {
+	key := seat{gameReq.channel, player}
+	if gameMgr.seats[key] == gameReq {
+		delete(gameMgr.seats, key)
 	}
+}
 
-	gm.gameRequests[channel] = game
-	return true
+// setGameRequest adds a seeded or restored game request with its players and waitlist. It runs on the goroutine of
+// the game request.
+func (gameMgr *GameManager) setGameRequest(game *GameRequest) {
+	gameMgr.do(func() {
+		if _, exists := gameMgr.gameRequests[game.id]; !exists {
+			gameMgr.metrics.openLobbies.Inc()
+		}
+		gameMgr.gameRequests[game.id] = game
+		for _, player := range slices.Concat(game.players, game.waitlist) {
+			gameMgr.seats[seat{game.channel, player}] = game
+		}
+	})
 }
 
-// handleTimeouts manages the timeouts of game requests. It listens for timeout
-// signals on a channel and handles the expiration of game requests accordingly. When a timeout occurs, the
-// function updates the game request and its associated Slack message from the specified channel.
-func (gameMgr *GameManager) handleTimeouts() {
-	for channel := range gameMgr.timeoutChan {
-		if gameReq, exists := gameMgr.getGameRequest(channel); exists {
-			ts := gameReq.messageTs
-			gameMgr.deleteGameRequest(channel)
-			gameMgr.apiClient.UpdateMessage(string(channel), ts, timeoutMSG)
+// deleteGameRequest closes the game request, removes it with the seats of its players and remembers how it
+// finished unless it was discarded. It runs on the goroutine of the game request.
+func (gameMgr *GameManager) deleteGameRequest(gameReq *GameRequest, outcome lobbyOutcome) {
+	gameMgr.stopTimers(gameReq)
+	gameReq.closed = true
+
+	removed := false
+	gameMgr.do(func() {
+		// the game request is gone once the game manager was shut down
+		if gameMgr.gameRequests[gameReq.id] != gameReq {
+			return
+		}
+		delete(gameMgr.gameRequests, gameReq.id)
+		for _, player := range slices.Concat(gameReq.players, gameReq.waitlist) {
+			gameMgr.vacateSeat(gameReq, player)
 		}
+		gameMgr.metrics.lobbyClosed(gameReq.gameType, outcome, gameReq.createdAt, gameMgr.clock.Now())
+		if outcome != lobbyDiscarded {
+			gameMgr.rememberFinished(gameReq.id, finishedLobby{channel: gameReq.channel, messageTs: gameReq.messageTs, outcome: outcome})
+		}
+		removed = true
+	})
+	if !removed {
+		return
 	}
+
+	if gameMgr.store != nil {
+		if err := gameMgr.store.DeleteGameRequest(gameReq.id); err != nil {
+			slog.Error("Failed to delete persisted game request", "lobby", gameReq.id, "channel", gameReq.channel, "error", err)
+		}
+	}
+}
+
+// rememberFinished adds a finished game request to the history, dropping the oldest one if the history is full.
+// It runs on the goroutine of the game manager.
+func (gameMgr *GameManager) rememberFinished(id LobbyID, lobby finishedLobby) {
+	gameMgr.finished[id] = lobby
+	gameMgr.finishedIDs = append(gameMgr.finishedIDs, id)
+	if len(gameMgr.finishedIDs) > finishedLobbyHistory {
+		delete(gameMgr.finished, gameMgr.finishedIDs[0])
+		gameMgr.finishedIDs = slices.Delete(gameMgr.finishedIDs, 0, 1)
+	}
+}
+
+// addGameRequest adds a new game request to its channel. It fails if the channel already reached the maximum number
+// of game requests or if the creator is already part of another game request of the channel. It runs on the
+// goroutine of the game request.
+func (gameMgr *GameManager) addGameRequest(game *GameRequest) error {
+	err := errShutDown
+	gameMgr.do(func() {
+		creator := seat{game.channel, game.players[0]}
+		if _, taken := gameMgr.seats[creator]; taken {
+			err = errPlayerInLobby
+			return
+		}
+		lobbies := 0
+		for _, gameReq := range gameMgr.gameRequests {
+			if gameReq.channel == game.channel {
+				lobbies++
+			}
+		}
+		if lobbies >= gameMgr.Settings().Channel(game.channel).MaxLobbies {
+			err = errLobbyLimit
+			return
+		}
+
+		// lobby IDs are short, so make sure they don't collide
+		for {
+			if _, exists := gameMgr.gameRequests[game.id]; !exists {
+				break
+			}
+			game.id = newLobbyID()
+		}
+		gameMgr.gameRequests[game.id] = game
+		gameMgr.seats[creator] = game
+		gameMgr.metrics.openLobbies.Inc()
+		gameMgr.metrics.lobbiesCreated.WithLabelValues(game.gameType.String()).Inc()
+		err = nil
+	})
+	return err
+}
+
+// botUser returns the user ID of the bot. It is asked for with auth.test once and remembered, Ready refreshes it.
+func (gameMgr *GameManager) botUser(ctx context.Context) (string, error) {
+	if userID := gameMgr.botUserID.Load(); userID != nil {
+		return *userID, nil
+	}
+	response, err := gameMgr.apiClient.AuthTestContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("slack auth.test failed: %w", err)
+	}
+	gameMgr.botUserID.Store(&response.UserID)
+	return response.UserID, nil
+}
+
+// authTestInterval is how long a successful auth.test of the readiness check is trusted, so that frequent probes
+// don't hit the rate limit of the Slack API.
+const authTestInterval = time.Minute
+
+// Ready checks that the game manager can serve requests: Slack accepts its bot token, its store can be written
+// and its scheduler runs the timeouts in time. It fails if the checks don't finish before the context is done.
+func (gameMgr *GameManager) Ready(ctx context.Context) error {
+	now := gameMgr.clock.Now()
+	if now.Sub(time.Unix(0, gameMgr.authTestedAt.Load())) > authTestInterval {
+		response, err := gameMgr.apiClient.AuthTestContext(ctx)
+		if err != nil {
+			return fmt.Errorf("slack auth.test failed: %w", err)
+		}
+		gameMgr.botUserID.Store(&response.UserID)
+		gameMgr.authTestedAt.Store(now.UnixNano())
+	}
+	if gameMgr.store != nil {
+		if err := gameMgr.store.Ping(); err != nil {
+			return fmt.Errorf("store is not reachable: %w", err)
+		}
+	}
+	if err := gameMgr.scheduler.check(); err != nil {
+		return fmt.Errorf("timeouts are not handled: %w", err)
+	}
+	return nil
+}
+
+// expireGame handles the timeout of a game request. It starts the game if the game request has enough players,
+// otherwise or if the start fails the game request expires. Timeouts of game requests whose deadline was pushed back
+// in the meantime are ignored.
+func (gameMgr *GameManager) expireGame(gameReq *GameRequest) {
+	gameReq.do(func() func() {
+		if gameReq.closed || gameReq.starting || gameReq.closing || gameMgr.clock.Now().Before(gameReq.deadline) {
+			return nil
+		}
+		if len(gameReq.players) >= gameReq.gameType.Format().MinPlayers {
+			// the game request counts as full from now on, so that late joins go to the waitlist
+			quorum := gameReq.quorum
+			gameReq.quorum = len(gameReq.players)
+			match, err := gameMgr.startGame(gameReq, gameMgr.newMatch(gameReq))
+			if err == nil {
+				return func() { gameMgr.announceStart(gameReq, match) }
+			}
+			slog.Error("Failed to start game at its timeout", "lobby", gameReq.id, "error", err)
+			gameReq.quorum = quorum
+		}
+		gameMgr.deleteGameRequest(gameReq, lobbyExpired)
+		gameMgr.updates.update(gameReq.channel, gameReq.messageTs, timeoutMSG(gameMgr.channelLanguage(gameReq.channel)), false)
+		return func() { gameMgr.releaseTable(gameReq.table) }
+	})
 }
 
-// Shutdown closes the timeout channel and releases all used resources
+// Shutdown waits for the queued slash commands, stops the scheduler and the goroutines of the game manager and its
+// game requests and releases all used resources.
+// Without a store the messages of open game requests are deleted, since they can't be resumed. With a store they are
+// left untouched so that RestoreGames can pick them up on the next start.
 func (gameMgr *GameManager) Shutdown(ctx context.Context) {
 	var wg sync.WaitGroup
 
+	gameMgr.commands.stop(ctx)
+
+	var lobbies []*GameRequest
+	gameMgr.do(func() {
+		for _, gameReq := range gameMgr.gameRequests {
+			lobbies = append(lobbies, gameReq)
+		}
+		gameMgr.metrics.openLobbies.Sub(float64(len(gameMgr.gameRequests)))
+		clear(gameMgr.gameRequests)
+		clear(gameMgr.seats)
+	})
+	gameMgr.scheduler.stop()
+
 	gameReqCancels := make([]struct {
 		channel   string
 		messageTs string
 	}, 0)
-
-	gameMgr.mu.Lock()
-	for channel, gameReq := range gameMgr.gameRequests {
-		if gameReq.timerCancelFunc != nil {
-			gameReq.timerCancelFunc()
-		}
-		gameReqCancels = append(gameReqCancels, struct {
-			channel   string
-			messageTs string
-		}{
-			channel:   string(channel),
-			messageTs: gameReq.messageTs,
-		})
+	for _, gameReq := range lobbies {
+		// scheduled and queued games that weren't announced yet have no message to delete
+		if snapshot := gameReq.snapshot(); snapshot.announced {
+			gameReqCancels = append(gameReqCancels, struct {
+				channel   string
+				messageTs string
+			}{
+				channel:   string(gameReq.channel),
+				messageTs: snapshot.messageTs,
+			})
+		}
+		gameReq.stop()
 	}
-	clear(gameMgr.gameRequests)
-	gameMgr.mu.Unlock()
 
-	close(gameMgr.timeoutChan)
+	// the updates of the messages are sent before the messages are left behind or deleted
+	gameMgr.updates.flush(ctx)
+	gameMgr.stopOnce.Do(func() { close(gameMgr.quit) })
+
+	if gameMgr.store != nil {
+		return
+	}
 
 	for _, gr := range gameReqCancels {
 		wg.Add(1)
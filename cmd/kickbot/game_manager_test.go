@@ -4,47 +4,95 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"path/filepath"
 	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/slack-go/slack"
 	"go.uber.org/mock/gomock"
 )
 
+// inspect runs the check on the goroutine of the game manager, which owns the game requests, seats and matches.
+func inspect(gameMgr *GameManager, check func()) {
+	gameMgr.do(check)
+}
+
+// inspectLobby runs the check on the goroutine of the game request, which owns its state.
+func inspectLobby(gameReq *GameRequest, check func()) {
+	gameReq.do(func() func() {
+		check()
+		return nil
+	})
+}
+
+// matchesOf returns a copy of the matches of the game manager.
+func matchesOf(gameMgr *GameManager) map[string]MatchRecord {
+	var matches map[string]MatchRecord
+	inspect(gameMgr, func() {
+		matches = maps.Clone(gameMgr.matches)
+	})
+	return matches
+}
+
+// lobbyIn returns the lobby ID of a game request in the channel, or an empty ID if the channel has none.
+func lobbyIn(gameMgr *GameManager, channel SlackChannel) LobbyID {
+	var lobby LobbyID
+	inspect(gameMgr, func() {
+		for id, gameReq := range gameMgr.gameRequests {
+			if gameReq.channel == channel {
+				lobby = id
+				return
+			}
+		}
+	})
+	return lobby
+}
+
 // TestConcurrentGameCreationForSingleChannel verifies the behavior of concurrently creating game requests in the same Slack channel.
 // The test uses a mock Slack client to simulate the GameManager's response to multiple concurrent
 // game request commands (Creating game request) and interactions (leaving and joining).
 // The GameManager is expected to handle these scenarios:
-//  1. Only the first valid game request should lead to a public announcement in the channel (handled by PostMessage).
-//  2. Any subsequent game requests in the same channel, while the first game request is still active, should result in
+//  1. Only the first max_lobbies game requests of the channel's settings should lead to a public announcement in the channel (handled by PostMessage).
+//  2. Any subsequent game requests in the same channel, while the channel is at its limit, should result in
 //     an ephemeral message to the user who attempted to start the new game (handled by PostEphemeral), indicating that
-//     a game request is already in progress.
+//     enough game requests are already in progress.
 //
 // This test creates 50 concurrent game request attempts in the same channel to ensure that the GameManager respects
-// the constraint of one active game request per channel.
+// the limit of active game requests per channel.
 func TestConcurrentGameCreationForSingleChannel(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockSlackClient := NewMockSlackClient(ctrl)
 
-	gameMgr := NewGameManager(mockSlackClient)
+	// the channel allows fewer game requests than the defaults
+	maxLobbies := 2
+	settings := DefaultSettings()
+	limited := settings.Defaults
+	limited.MaxLobbies = maxLobbies
+	settings.Channels = map[SlackChannel]ChannelSettings{"sameChannel": limited}
+	gameMgr := NewGameManager(mockSlackClient, WithSettings(settings))
 	defer gameMgr.Shutdown(context.TODO())
 
-	// A channel can only have a single active game request. when a game is created a message is sent to the channel when `PostMessage` is invoked
+	// A channel can only have maxLobbies active game requests. when a game is created a message is sent to the channel when `PostMessage` is invoked
 	// when other users try to create a new game request in the same channel they will receive an ephemeral error message when `PostEphemeral` is invoked
-	// Expected is: 1 `PostMessage` and the rest should be PostEphemeral
+	// Expected is: maxLobbies `PostMessage` and the rest should be PostEphemeral
 	mockSlackClient.EXPECT().
 		PostMessage(gomock.Any(), gomock.Any()).
-		Return("channelID", "timestamp", nil).Times(1)
+		Return("channelID", "timestamp", nil).Times(maxLobbies)
 
 	mockSlackClient.EXPECT().
 		PostEphemeral(gomock.Any(), gomock.Any(), gomock.Any()).
 		Return("timestamp", nil).AnyTimes()
 
-	// Shutdown should cleanup and delete any trailing game requests (which should be exactly 1 game)
-	mockSlackClient.EXPECT().DeleteMessageContext(gomock.Any(), gomock.Any(), gomock.Any()).Times(1)
+	// Shutdown should cleanup and delete any trailing game requests
+	mockSlackClient.EXPECT().DeleteMessageContext(gomock.Any(), gomock.Any(), gomock.Any()).Times(maxLobbies)
 
 	var gameOptions = GameOpts{
 		timeout:  time.Minute * 30,
@@ -64,8 +112,8 @@ func TestConcurrentGameCreationForSingleChannel(t *testing.T) {
 
 	wg.Wait()
 
-	if len(gameMgr.gameRequests) != 1 {
-		t.Errorf("Expected only one game to be created, but found %d", len(gameMgr.gameRequests))
+	if len(gameMgr.gameRequests) != maxLobbies {
+		t.Errorf("Expected %d games to be created, but found %d", maxLobbies, len(gameMgr.gameRequests))
 	}
 }
 
@@ -115,9 +163,10 @@ func TestConcurrentGameCreationForMultipleChannels(t *testing.T) {
 }
 
 // TestConcurrentJoins checks GameManager's handling of concurrent join requests for an existing game.
-// The test ensures that once a game reaches its required quorum, further join attempts are blocked with error messages,
-// and the game request is deleted. It verifies that only the necessary number of players can join, and any simultaneous extra join attempts
-// are correctly handled as game already started.
+// The test ensures that once a game reaches its required quorum it is announced with exactly quorum players and the
+// game request is deleted. Players who join while the game is full end up on the waitlist and seed follow-up games,
+// so every player either plays in exactly one game, waits in the open follow-up game or is told that no game is open.
+// Some updates of the message fail, the players whose join they should have shown are told so and aren't part of any game.
 func TestConcurrentJoins(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -130,23 +179,54 @@ func TestConcurrentJoins(t *testing.T) {
 	nJoins := 10
 
 	// Expectations for Slack client interaction:
-	// - One "PostMessage" for the game's initial announcement.
-	// - "UpdateMessage" called three times (`quorum - 1`) for player joins until quorum (4 players) is reached.
-	// - "PostEphemeral" called for notifying the 4 successful joins and sending 7 error messages to players who attempt joining after the game is full.
-	// In essence, 10 players try to join a game that already has 1 player. Only 3 additional players can join successfully,
-	// with each successful join triggering an "UpdateMessage". All players, successful or not, receive a "PostEphemeral" notification about their join status.
-
+	// - One "PostMessage" for the game's initial announcement and one for every follow-up game seeded by the waitlist.
+	// - "UpdateMessage" for every join and every game start.
+	// - "PostEphemeral" for the game start pings, the waitlist notifications and join errors.
+	var nPosts int
+	var postsMu sync.Mutex
 	mockSlackClient.EXPECT().
 		PostMessage(gomock.Any(), gomock.Any()).
-		Return("channelID", "timestamp", nil).Times(1)
-
+		DoAndReturn(func(channelID string, options ...slack.MsgOption) (string, string, error) {
+			postsMu.Lock()
+			defer postsMu.Unlock()
+			nPosts++
+			return channelID, fmt.Sprintf("ts-%d", nPosts), nil
+		}).MinTimes(1)
+
+	// The second and the fourth update fail. They may show coalesced joins, the start of a game or of a game seeded
+	// by the waitlist, whose message is deleted then.
+	var nUpdates int
 	mockSlackClient.EXPECT().
 		UpdateMessage(gomock.Any(), gomock.Any(), gomock.Any()).
-		Return("channelID", "ts", "text", nil).Times(quorum - 1)
-
+		DoAndReturn(func(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+			postsMu.Lock()
+			defer postsMu.Unlock()
+			nUpdates++
+			if nUpdates == 2 || nUpdates == 4 {
+				return "", "", "", slack.SlackErrorResponse{Err: "message_not_found"}
+			}
+			return channelID, timestamp, "text", nil
+		}).AnyTimes()
+
+	failed := make(map[string]bool)
 	mockSlackClient.EXPECT().
 		PostEphemeral(gomock.Any(), gomock.Any(), gomock.Any()).
-		Return("timestamp", nil).MaxTimes(nJoins + 1)
+		DoAndReturn(func(channelID, userID string, options ...slack.MsgOption) (string, error) {
+			if msgText(t, options[0]) == catalog[DefaultLanguage][msgJoinFailed] {
+				postsMu.Lock()
+				failed[userID] = true
+				postsMu.Unlock()
+			}
+			return "timestamp", nil
+		}).AnyTimes()
+
+	mockSlackClient.EXPECT().
+		DeleteMessage(gomock.Any(), gomock.Any()).
+		Return("channelID", "timestamp", nil).AnyTimes()
+
+	// A follow-up game might still be open on shutdown
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 
 	channelID := "12345678"
 
@@ -165,17 +245,55 @@ func TestConcurrentJoins(t *testing.T) {
 		wg.Add(1)
 		go func(userID string) {
 			defer wg.Done()
-			gameMgr.JoinGame(SlackChannel(channelID), userID)
+			gameMgr.JoinGame(SlackChannel(channelID), lobbyIn(gameMgr, SlackChannel(channelID)), userID)
 		}(userID)
 	}
 
 	wg.Wait()
 
-	gameMgr.mu.Lock()
-	if len(gameMgr.gameRequests) != 0 {
-		t.Errorf("Expected all games to be deleted, but found %d games", len(gameMgr.gameRequests))
-	}
-	gameMgr.mu.Unlock()
+	inspect(gameMgr, func() {
+		seen := make(map[string]bool)
+		countPlayer := func(player string) {
+			if seen[player] {
+				t.Errorf("Player %s is part of more than one game", player)
+			}
+			if failed[player] {
+				t.Errorf("Player %s is part of a game although the join failed", player)
+			}
+			seen[player] = true
+		}
+
+		var firstGameFound bool
+		for _, match := range gameMgr.matches {
+			if len(match.Players) != quorum {
+				t.Errorf("Expected every started game to have %d players, found %v", quorum, match.Players)
+			}
+			if match.MessageTs == "ts-1" {
+				firstGameFound = true
+				if !slices.Contains(match.Players, "user-0x") {
+					t.Errorf("Expected the creator to play in the first game, found %v", match.Players)
+				}
+			}
+			for _, player := range match.Players {
+				countPlayer(player)
+			}
+		}
+		if !firstGameFound {
+			t.Error("Expected the first game to be started")
+		}
+
+		if len(gameMgr.gameRequests) > 1 {
+			t.Errorf("Expected at most one open follow-up game, but found %d games", len(gameMgr.gameRequests))
+		}
+		for _, gameReq := range gameMgr.gameRequests {
+			if len(gameReq.players) >= quorum || len(gameReq.waitlist) > 0 {
+				t.Errorf("Expected the open follow-up game to still look for players, found %v", gameReq.players)
+			}
+			for _, player := range gameReq.players {
+				countPlayer(player)
+			}
+		}
+	})
 }
 
 // TestConcurrentLeaves verifies the GameManager's handling of multiple players leaving a game simultaneously.
@@ -189,9 +307,10 @@ func TestConcurrentLeaves(t *testing.T) {
 	gameMgr := NewGameManager(mockSlackClient)
 	defer gameMgr.Shutdown(context.TODO())
 
+	// The update of the second leave may be coalesced with the deletion of the message on the last leave
 	mockSlackClient.EXPECT().
 		UpdateMessage(gomock.Any(), gomock.Any(), gomock.Any()).
-		Return("channelID", "ts", "text", nil).Times(2)
+		Return("channelID", "ts", "text", nil).MinTimes(1).MaxTimes(2)
 
 	mockSlackClient.EXPECT().
 		DeleteMessage(gomock.Any(), gomock.Any()).
@@ -200,11 +319,12 @@ func TestConcurrentLeaves(t *testing.T) {
 	channel := "lpzg-24"
 	players := []string{"p1", "p2", "p3"}
 
-	gameMgr.gameRequests[SlackChannel(channel)] = &GameRequest{
+	gameMgr.gameRequests["lobby"] = &GameRequest{
+		id:        "lobby",
+		channel:   SlackChannel(channel),
 		players:   slices.Clone(players),
 		quorum:    4,
 		messageTs: "ts",
-		mu:        &sync.Mutex{},
 	}
 
 	wg := &sync.WaitGroup{}
@@ -212,16 +332,83 @@ func TestConcurrentLeaves(t *testing.T) {
 		wg.Add(1)
 		go func(player string) {
 			defer wg.Done()
-			gameMgr.LeaveGame(SlackChannel(channel), player)
+			gameMgr.LeaveGame(SlackChannel(channel), "lobby", player)
 		}(player)
 	}
 	wg.Wait()
 
-	gameMgr.mu.Lock()
-	if len(gameMgr.gameRequests) != 0 {
-		t.Errorf("Expected all games to be deleted, but found %d games", len(gameMgr.gameRequests))
+	inspect(gameMgr, func() {
+		if len(gameMgr.gameRequests) != 0 {
+			t.Errorf("Expected all games to be deleted, but found %d games", len(gameMgr.gameRequests))
+		}
+	})
+}
+
+// TestConcurrentLeavesWithFailingUpdates verifies that players whose leaves can't be shown in the message of the game
+// request stay in the game and are told so, even if their updates were coalesced.
+func TestConcurrentLeavesWithFailingUpdates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+
+	mockSlackClient.EXPECT().
+		UpdateMessage(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("", "", "", slack.SlackErrorResponse{Err: "cant_update_message"}).MinTimes(1)
+	var stayed []string
+	var stayedMu sync.Mutex
+	mockSlackClient.EXPECT().
+		PostEphemeral(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(channelID, userID string, options ...slack.MsgOption) (string, error) {
+			if msgText(t, options[0]) == catalog[DefaultLanguage][msgLeaveFailed] {
+				stayedMu.Lock()
+				stayed = append(stayed, userID)
+				stayedMu.Unlock()
+			}
+			return "timestamp", nil
+		}).Times(2)
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), gomock.Any(), "ts").Times(1)
+
+	channel := "lpzg-24"
+	players := []string{"p1", "p2", "p3"}
+	leaving := []string{"p1", "p2"}
+
+	gameMgr.gameRequests["lobby"] = &GameRequest{
+		id:        "lobby",
+		channel:   SlackChannel(channel),
+		players:   slices.Clone(players),
+		quorum:    4,
+		messageTs: "ts",
+	}
+
+	wg := &sync.WaitGroup{}
+	for _, player := range leaving {
+		wg.Add(1)
+		go func(player string) {
+			defer wg.Done()
+			gameMgr.LeaveGame(SlackChannel(channel), "lobby", player)
+		}(player)
+	}
+	wg.Wait()
+
+	slices.Sort(stayed)
+	if !slices.Equal(stayed, leaving) {
+		t.Errorf("Expected the players %v to be told that they stay in the game, got %v", leaving, stayed)
+	}
+	gameReq, exists := gameMgr.getGameRequest(SlackChannel(channel), "lobby")
+	if !exists {
+		t.Fatal("Expected the game to stay open")
 	}
-	gameMgr.mu.Unlock()
+	inspectLobby(gameReq, func() {
+		remaining := slices.Clone(gameReq.players)
+		slices.Sort(remaining)
+		if !slices.Equal(remaining, players) {
+			t.Errorf("Expected all players %v to stay in the game, found %v", players, gameReq.players)
+		}
+	})
 }
 
 // TestConcurrentLeavesAndJoins checks that GameManager correctly handles concurrent leaves and joins for a game request.
@@ -246,10 +433,11 @@ func TestConcurrentLeavesAndJoins(t *testing.T) {
 	// EXPECT 5 updates for each leave and join actions. 4 notifications (PostEphemeral) for each player when game reaches quorum
 	//
 	// Case Scenario II: 1 player joins before the other 2 leave. Game reaches quorum and is deleted.
-	// 2 Players send `join` and Players send leave after the game is deleted. They receive 4 notifications (PostEphemeral) with errors
+	// 2 Players send `join` while the game is full and end up on the waitlist, which seeds a follow-up game (PostMessage).
+	// Players can't leave a full game, so they receive notifications (PostEphemeral) with errors
 	// and the 4 players of the game receive exactly 4 notifications (PostEphemeral) for the game completion
 	//
-	// From I And II: Expected Max of 5 UpdateMessage, 8 PostEphemeral and 1 DeleteMessage
+	// From I And II: Expected Max of 5 UpdateMessage, 1 PostMessage and 1 DeleteMessage
 
 	mockSlackClient.EXPECT().
 		UpdateMessage(gomock.Any(), gomock.Any(), gomock.Any()).
@@ -261,12 +449,19 @@ func TestConcurrentLeavesAndJoins(t *testing.T) {
 	mockSlackClient.EXPECT().
 		PostEphemeral(gomock.Any(), gomock.Any(), gomock.Any()).
 		Return("timestamp", nil).AnyTimes()
+	mockSlackClient.EXPECT().
+		PostMessage(gomock.Any(), gomock.Any()).
+		Return("channelID", "seeded-ts", nil).MaxTimes(1)
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), gomock.Any(), "seeded-ts").MaxTimes(1)
 
-	gameMgr.gameRequests[SlackChannel(channel)] = &GameRequest{
+	gameMgr.gameRequests["lobby"] = &GameRequest{
+		id:        "lobby",
+		channel:   SlackChannel(channel),
 		players:   slices.Clone(players),
 		quorum:    4,
 		messageTs: "ts",
-		mu:        &sync.Mutex{},
+		timeout:   time.Minute * 30,
 	}
 
 	wg := sync.WaitGroup{}
@@ -274,21 +469,31 @@ func TestConcurrentLeavesAndJoins(t *testing.T) {
 		wg.Add(1)
 		go func(player string) {
 			defer wg.Done()
-			gameMgr.LeaveGame(SlackChannel(channel), player)
+			gameMgr.LeaveGame(SlackChannel(channel), "lobby", player)
 		}(player)
 	}
 	for _, player := range playersToJoin {
 		wg.Add(1)
 		go func(player string) {
 			defer wg.Done()
-			gameMgr.JoinGame(SlackChannel(channel), player)
+			gameMgr.JoinGame(SlackChannel(channel), "lobby", player)
 		}(player)
 	}
 	wg.Wait()
 
-	if len(gameMgr.gameRequests) != 0 {
-		t.Errorf("Expected all games to be deleted, but found %d games", len(gameMgr.gameRequests))
-	}
+	// Only a follow-up game of waitlisted players may remain
+	inspect(gameMgr, func() {
+		for _, gameReq := range gameMgr.gameRequests {
+			if gameReq.messageTs != "seeded-ts" {
+				t.Errorf("Expected the game to be deleted, but found %v", gameReq.players)
+			}
+			for _, player := range gameReq.players {
+				if !slices.Contains(playersToJoin, player) {
+					t.Errorf("Expected only waitlisted players in the follow-up game, found %v", gameReq.players)
+				}
+			}
+		}
+	})
 }
 
 // TestConcurrentLeaveAndJoin checks that GameManager handles a case where the last or only player in a game request attempts to leave
@@ -323,11 +528,12 @@ func TestConcurrentLeaveAndJoin(t *testing.T) {
 		PostEphemeral(gomock.Any(), gomock.Any(), gomock.Any()).
 		Return("timestamp", nil).MaxTimes(1)
 
-	gameMgr.gameRequests[SlackChannel(channel)] = &GameRequest{
+	gameMgr.gameRequests["lobby"] = &GameRequest{
+		id:        "lobby",
+		channel:   SlackChannel(channel),
 		players:   []string{initialPlayer},
 		quorum:    4,
 		messageTs: "ts",
-		mu:        &sync.Mutex{},
 	}
 
 	wg := &sync.WaitGroup{}
@@ -336,16 +542,16 @@ func TestConcurrentLeaveAndJoin(t *testing.T) {
 	// Concurrent leave and join
 	go func() {
 		defer wg.Done()
-		gameMgr.LeaveGame(SlackChannel(channel), initialPlayer)
+		gameMgr.LeaveGame(SlackChannel(channel), "lobby", initialPlayer)
 	}()
 	go func() {
 		defer wg.Done()
-		gameMgr.JoinGame(SlackChannel(channel), joiningPlayer)
+		gameMgr.JoinGame(SlackChannel(channel), "lobby", joiningPlayer)
 	}()
 
 	wg.Wait()
 
-	game, exists := gameMgr.gameRequests[SlackChannel(channel)]
+	game, exists := gameMgr.gameRequests["lobby"]
 	if exists {
 		if len(game.players) != 1 {
 			t.Errorf("Expected the game to have 1 player, found %d", len(game.players))
@@ -353,6 +559,67 @@ func TestConcurrentLeaveAndJoin(t *testing.T) {
 	}
 }
 
+// TestConcurrentJoinsUpdateMessageInOrder verifies that the updates of a lobby's message reach Slack one at a time
+// and in the order of the joins, so the message never shows a stale player list. Updates of concurrent joins may be
+// coalesced, but the last one shows all players.
+func TestConcurrentJoinsUpdateMessageInOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+
+	nJoins := 6
+	var inFlight atomic.Int32
+	var mentions []int
+	mockSlackClient.EXPECT().
+		UpdateMessage(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+			if inFlight.Add(1) > 1 {
+				t.Error("Expected the updates of a lobby not to overlap")
+			}
+			defer inFlight.Add(-1)
+			_, values, err := slack.UnsafeApplyMsgOptions("token", "channel", "https://slack.com/api/", options[0])
+			if err != nil {
+				t.Fatalf("Failed to apply message options: %v", err)
+			}
+			time.Sleep(time.Millisecond)
+			mentions = append(mentions, strings.Count(values.Get("blocks"), "@p"))
+			return channelID, timestamp, "text", nil
+		}).MinTimes(1).MaxTimes(nJoins)
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	gameMgr.gameRequests["lobby"] = &GameRequest{
+		id:        "lobby",
+		channel:   "C1",
+		players:   []string{"p0"},
+		quorum:    nJoins + 2,
+		messageTs: "ts",
+	}
+
+	var wg sync.WaitGroup
+	for i := range nJoins {
+		wg.Add(1)
+		go func(player string) {
+			defer wg.Done()
+			gameMgr.JoinGame("C1", "lobby", player)
+		}(fmt.Sprintf("p%d", i+1))
+	}
+	wg.Wait()
+
+	for i := 1; i < len(mentions); i++ {
+		if mentions[i] <= mentions[i-1] {
+			t.Errorf("Expected every update to show more players than the one before, got mentions %v", mentions)
+			break
+		}
+	}
+	if last := mentions[len(mentions)-1]; last != nJoins+1 {
+		t.Errorf("Expected the last update to show all %d players, got %d", nJoins+1, last)
+	}
+}
+
 // TestGameReqTimeout verifies that game requests will be deleted once they time out.
 func TestGameReqTimeout(t *testing.T) {
 	ctrl := gomock.NewController(t)
@@ -372,7 +639,8 @@ func TestGameReqTimeout(t *testing.T) {
 		UpdateMessage(gomock.Any(), gomock.Any(), gomock.Any()).
 		Return("ch", "ts", "", nil).MaxTimes(nGames)
 
-	gameMgr := NewGameManager(mockSlackClient)
+	clock := newFakeClock()
+	gameMgr := NewGameManager(mockSlackClient, WithClock(clock))
 	defer gameMgr.Shutdown(context.TODO())
 
 	var gameOptions = GameOpts{
@@ -383,12 +651,12 @@ func TestGameReqTimeout(t *testing.T) {
 	for i := range nGames {
 		gameMgr.CreateGame(SlackChannel(fmt.Sprintf("channel-%d", i)), "test", gameOptions)
 	}
-	time.Sleep(125 * time.Millisecond)
-	gameMgr.mu.Lock()
-	if len(gameMgr.gameRequests) != 0 {
-		t.Errorf("Expected all games to be deleted, but found %d games", len(gameMgr.gameRequests))
-	}
-	gameMgr.mu.Unlock()
+	clock.Advance(100 * time.Millisecond)
+	inspect(gameMgr, func() {
+		if len(gameMgr.gameRequests) != 0 {
+			t.Errorf("Expected all games to be deleted, but found %d games", len(gameMgr.gameRequests))
+		}
+	})
 }
 
 func TestGameCompletionBeforeTimeout(t *testing.T) {
@@ -417,7 +685,8 @@ func TestGameCompletionBeforeTimeout(t *testing.T) {
 		DeleteMessage(gomock.Any(), gomock.Any()).
 		Return("ch", "ts", nil).Times(0)
 
-	gameMgr := NewGameManager(mockSlackClient)
+	clock := newFakeClock()
+	gameMgr := NewGameManager(mockSlackClient, WithClock(clock))
 	defer gameMgr.Shutdown(context.TODO())
 	channel := SlackChannel("test-channel")
 
@@ -426,17 +695,16 @@ func TestGameCompletionBeforeTimeout(t *testing.T) {
 		gameType: GameTypeOneVsOne,
 	}
 
-	time.Sleep(50 * time.Millisecond)
-
 	gameMgr.CreateGame(channel, "test-player-01", gameOptions)
-	gameMgr.JoinGame(channel, "test-player-02")
+	clock.Advance(50 * time.Millisecond)
+	gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), "test-player-02")
 
 	// Check if the game has been deleted
-	gameMgr.mu.Lock()
-	if _, exists := gameMgr.gameRequests[channel]; exists {
-		t.Errorf("Game was not correctly deleted upon completion")
-	}
-	gameMgr.mu.Unlock()
+	inspect(gameMgr, func() {
+		if len(gameMgr.gameRequests) != 0 {
+			t.Errorf("Game was not correctly deleted upon completion")
+		}
+	})
 }
 
 func TestGameCancellationBeforeTimeout(t *testing.T) {
@@ -454,7 +722,8 @@ func TestGameCancellationBeforeTimeout(t *testing.T) {
 		DeleteMessage(gomock.Any(), gomock.Any()).
 		Return("ch", "ts", nil).Times(1)
 
-	gameMgr := NewGameManager(mockSlackClient)
+	clock := newFakeClock()
+	gameMgr := NewGameManager(mockSlackClient, WithClock(clock))
 	defer gameMgr.Shutdown(context.TODO())
 
 	var gameOptions = GameOpts{
@@ -466,15 +735,15 @@ func TestGameCancellationBeforeTimeout(t *testing.T) {
 	player := "test-player"
 	gameMgr.CreateGame(channel, player, gameOptions)
 
-	gameMgr.LeaveGame(channel, player)
+	gameMgr.LeaveGame(channel, lobbyIn(gameMgr, channel), player)
 
-	time.Sleep(100 * time.Millisecond)
+	clock.Advance(100 * time.Millisecond)
 
-	gameMgr.mu.Lock()
-	if _, exists := gameMgr.gameRequests[channel]; exists {
-		t.Errorf("Game was not correctly deleted upon cancellation")
-	}
-	gameMgr.mu.Unlock()
+	inspect(gameMgr, func() {
+		if len(gameMgr.gameRequests) != 0 {
+			t.Errorf("Game was not correctly deleted upon cancellation")
+		}
+	})
 }
 
 // TestPlayerCannotJoinGameTwice verified that a user cannot double joins a game request
@@ -519,13 +788,13 @@ func TestPlayerCannotJoinGameTwice(t *testing.T) {
 	// player 1 creates game
 	gameMgr.CreateGame(channel, player1, gameOptions)
 	// player 2 double joins
-	gameMgr.JoinGame(channel, player1)
+	gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), player1)
 	// player 2 joins
-	gameMgr.JoinGame(channel, player2)
+	gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), player2)
 	// player 2 joins
-	gameMgr.JoinGame(channel, player2)
+	gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), player2)
 
-	game, exists := gameMgr.getGameRequest(channel)
+	game, exists := gameMgr.getGameRequest(channel, lobbyIn(gameMgr, channel))
 	if !exists {
 		t.Error("Game incorrectly deleted")
 	}
@@ -560,9 +829,9 @@ func TestUserCannotLeaveGameRequestTheyNotPartOf(t *testing.T) {
 		PostEphemeral(channelId, leaver, gomock.Any()).
 		Return("timestamp", nil).Times(1)
 	gameMgr.CreateGame(channel, gameMaker, gameOptions)
-	gameMgr.LeaveGame(channel, leaver)
+	gameMgr.LeaveGame(channel, lobbyIn(gameMgr, channel), leaver)
 
-	gameRequest, _ := gameMgr.getGameRequest(channel)
+	gameRequest, _ := gameMgr.getGameRequest(channel, lobbyIn(gameMgr, channel))
 	if numPlayers := len(gameRequest.players); numPlayers != 1 {
 		t.Errorf("Expected to find 1 player in game request but found %d", numPlayers)
 	}
@@ -587,8 +856,8 @@ func TestUserCannotLeaveOrJoinGameRequestThatDoesntExist(t *testing.T) {
 		PostEphemeral(channelID, user, gomock.Any()).
 		Return("timestamp", nil).AnyTimes()
 
-	gameMgr.JoinGame(channel, user)
-	gameMgr.LeaveGame(channel, user)
+	gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), user)
+	gameMgr.LeaveGame(channel, lobbyIn(gameMgr, channel), user)
 
 	if len(gameMgr.gameRequests) != 0 {
 		t.Errorf("Expected to fine zero game requests, but found %d", len(gameMgr.gameRequests))
@@ -677,15 +946,15 @@ func TestGameCompletionNotification(t *testing.T) {
 	// should trigger 1 "PostMessage"
 	gameMgr.CreateGame(SlackChannel(channelID), p1, gameOptions)
 	// should trigger 3 updates
-	gameMgr.JoinGame(channel, p2)
-	gameMgr.JoinGame(channel, p3)
-	gameMgr.JoinGame(channel, p4)
+	gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), p2)
+	gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), p3)
+	gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), p4)
 
-	gameMgr.mu.Lock()
-	if len(gameMgr.gameRequests) != 0 {
-		t.Errorf("Expected all games to be deleted, but found %d games", len(gameMgr.gameRequests))
-	}
-	gameMgr.mu.Unlock()
+	inspect(gameMgr, func() {
+		if len(gameMgr.gameRequests) != 0 {
+			t.Errorf("Expected all games to be deleted, but found %d games", len(gameMgr.gameRequests))
+		}
+	})
 }
 
 func TestGameManagerShutdown(t *testing.T) {
@@ -737,40 +1006,41 @@ func TestCancelGame(t *testing.T) {
 	nonCreator := "non-creator"
 
 	gameReq := &GameRequest{
+		id:        "lobby",
+		channel:   channel,
 		players:   []string{creator, nonCreator},
 		quorum:    2,
 		messageTs: "ts",
-		mu:        &sync.Mutex{},
 	}
-	gameMgr.gameRequests[channel] = gameReq
+	gameMgr.gameRequests[gameReq.id] = gameReq
 
 	// Case 1: Non-creator attempts to cancel the game
 	mockSlackClient.EXPECT().
 		PostEphemeral(string(channel), nonCreator, gomock.Any()).
 		Return("timestamp", nil).Times(1)
 
-	gameMgr.CancelGame(channel, nonCreator)
+	gameMgr.CancelGame(channel, nonCreator, gameReq.id)
 
 	// Verify the game still exists after the non-creator's attempt
-	gameMgr.mu.Lock()
-	if _, exists := gameMgr.gameRequests[channel]; !exists {
-		t.Errorf("Game should still exist after non-creator's cancel attempt")
-	}
-	gameMgr.mu.Unlock()
+	inspect(gameMgr, func() {
+		if _, exists := gameMgr.gameRequests[gameReq.id]; !exists {
+			t.Errorf("Game should still exist after non-creator's cancel attempt")
+		}
+	})
 
 	// Case 2: Creator cancels the game
 	mockSlackClient.EXPECT().
 		UpdateMessage(string(channel), "ts", gomock.Any()).
 		Return("channelID", "timestamp", "text", nil).Times(1)
 
-	gameMgr.CancelGame(channel, creator)
+	gameMgr.CancelGame(channel, creator, "")
 
 	// Verify the game is deleted after the creator's cancel attempt
-	gameMgr.mu.Lock()
-	if _, exists := gameMgr.gameRequests[channel]; exists {
-		t.Errorf("Game should not exist after creator's cancel attempt")
-	}
-	gameMgr.mu.Unlock()
+	inspect(gameMgr, func() {
+		if _, exists := gameMgr.gameRequests[gameReq.id]; exists {
+			t.Errorf("Game should not exist after creator's cancel attempt")
+		}
+	})
 }
 
 func TestCancelNonExistingGame(t *testing.T) {
@@ -788,7 +1058,7 @@ func TestCancelNonExistingGame(t *testing.T) {
 		PostEphemeral(string(channel), player, gomock.Any()).
 		Return("timestamp", nil).Times(1)
 
-	gameMgr.CancelGame(channel, player)
+	gameMgr.CancelGame(channel, player, "")
 }
 
 func TestConcurrentCancelGame(t *testing.T) {
@@ -804,14 +1074,15 @@ func TestConcurrentCancelGame(t *testing.T) {
 	nonCreators := []string{"non-creator-1", "non-creator-2", "non-creator-3", "non-creator-4", "non-creator-5", "non-creator-6", "non-creator-7", "non-creator-8", "non-creator-9"}
 
 	gameReq := &GameRequest{
+		id:        "lobby",
+		channel:   channel,
 		players:   append([]string{creator}, nonCreators...),
 		quorum:    20,
 		messageTs: "ts",
-		mu:        &sync.Mutex{},
 	}
-	gameMgr.mu.Lock()
-	gameMgr.gameRequests[channel] = gameReq
-	gameMgr.mu.Unlock()
+	inspect(gameMgr, func() {
+		gameMgr.gameRequests[gameReq.id] = gameReq
+	})
 
 	// Expect an update message for the creator's cancel attempt
 	mockSlackClient.EXPECT().
@@ -829,12 +1100,12 @@ func TestConcurrentCancelGame(t *testing.T) {
 
 	go func() {
 		defer wg.Done()
-		gameMgr.CancelGame(channel, creator)
+		gameMgr.CancelGame(channel, creator, "")
 	}()
 	for _, nonCreator := range nonCreators {
 		go func(nonCreator string) {
 			defer wg.Done()
-			gameMgr.CancelGame(channel, nonCreator)
+			gameMgr.CancelGame(channel, nonCreator, gameReq.id)
 		}(nonCreator)
 	}
 
@@ -842,11 +1113,1146 @@ func TestConcurrentCancelGame(t *testing.T) {
 	wg.Wait()
 
 	// Verify the game is deleted after the creator's cancel attempt
-	gameMgr.mu.Lock()
-	_, exists := gameMgr.gameRequests[channel]
-	gameMgr.mu.Unlock()
+	var exists bool
+	inspect(gameMgr, func() {
+		_, exists = gameMgr.gameRequests[gameReq.id]
+	})
 
 	if exists {
 		t.Errorf("Game should not exist after creator's cancel attempt")
 	}
 }
+
+// TestFailedGameStartIsRolledBack verifies that a game whose start message can't be posted stays open without the
+// player who filled it, that no match is recorded and that the players aren't pinged.
+func TestFailedGameStartIsRolledBack(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+
+	channel := SlackChannel("test-channel")
+	mockSlackClient.EXPECT().
+		UpdateMessage(string(channel), "ts", gomock.Any()).
+		Return("", "", "", slack.SlackErrorResponse{Err: "message_not_found"}).Times(1)
+	mockSlackClient.EXPECT().
+		PostEphemeral(string(channel), "p4", gomock.Any()).
+		DoAndReturn(func(channelID, userID string, options ...slack.MsgOption) (string, error) {
+			if text := msgText(t, options[0]); text != catalog[DefaultLanguage][msgJoinFailed] {
+				t.Errorf("Expected p4 to be told that the join failed, got %q", text)
+			}
+			return "timestamp", nil
+		}).Times(1)
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), string(channel), "ts").Times(1)
+
+	gameMgr.gameRequests["lobby"] = &GameRequest{
+		id:        "lobby",
+		channel:   channel,
+		players:   []string{"p1", "p2", "p3"},
+		gameType:  GameTypeTwoVsTwo,
+		quorum:    4,
+		messageTs: "ts",
+	}
+
+	gameMgr.JoinGame(channel, "lobby", "p4")
+
+	gameReq, exists := gameMgr.getGameRequest(channel, "lobby")
+	if !exists {
+		t.Fatal("Expected the game to stay open after its start failed")
+	}
+	inspectLobby(gameReq, func() {
+		if !slices.Equal(gameReq.players, []string{"p1", "p2", "p3"}) || gameReq.closed {
+			t.Errorf("Expected the game to be open with its previous players, found %v", gameReq.players)
+		}
+	})
+	inspect(gameMgr, func() {
+		if len(gameMgr.matches) != 0 {
+			t.Errorf("Expected no match to be recorded, found %d", len(gameMgr.matches))
+		}
+	})
+}
+
+// TestGameStartDoesNotBlockLobby verifies that the lobby goroutine doesn't wait for the game start message of a
+// full game, so that players who click meanwhile are answered right away and end up on the waitlist.
+func TestGameStartDoesNotBlockLobby(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+
+	updating := make(chan struct{})
+	release := make(chan struct{})
+	mockSlackClient.EXPECT().
+		UpdateMessage("C1", "ts", gomock.Any()).
+		DoAndReturn(func(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+			close(updating)
+			<-release
+			return channelID, timestamp, "text", nil
+		}).Times(1)
+	mockSlackClient.EXPECT().
+		PostEphemeral("C1", gomock.Any(), gomock.Any()).
+		Return("timestamp", nil).AnyTimes()
+	// the waitlist seeds the next game request
+	mockSlackClient.EXPECT().
+		PostMessage("C1", gomock.Any()).
+		Return("C1", "ts-2", nil).AnyTimes()
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	gameMgr.gameRequests["lobby"] = &GameRequest{
+		id:        "lobby",
+		channel:   "C1",
+		players:   []string{"creator"},
+		gameType:  GameTypeOneVsOne,
+		quorum:    2,
+		timeout:   30 * time.Minute,
+		messageTs: "ts",
+	}
+
+	started := make(chan struct{})
+	go func() {
+		defer close(started)
+		gameMgr.JoinGame("C1", "lobby", "p2")
+	}()
+	<-updating
+
+	waitlisted := make(chan struct{})
+	go func() {
+		defer close(waitlisted)
+		gameMgr.JoinGame("C1", "lobby", "p3")
+	}()
+	select {
+	case <-waitlisted:
+	case <-time.After(time.Second):
+		t.Error("Expected the join to be answered while the game start message is sent")
+	}
+	close(release)
+	<-started
+
+	inspect(gameMgr, func() {
+		if len(gameMgr.matches) != 1 {
+			t.Errorf("Expected the game to start once its message was replaced, found %d matches", len(gameMgr.matches))
+		}
+	})
+}
+
+// TestFailedCancelKeepsGame verifies that a game whose message can't show the cancellation stays open and that its
+// creator is told so.
+func TestFailedCancelKeepsGame(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+
+	channel := SlackChannel("test-channel")
+	mockSlackClient.EXPECT().
+		UpdateMessage(string(channel), "ts", gomock.Any()).
+		Return("", "", "", errCircuitOpen).Times(1)
+	mockSlackClient.EXPECT().
+		PostEphemeral(string(channel), "creator", gomock.Any()).
+		DoAndReturn(func(channelID, userID string, options ...slack.MsgOption) (string, error) {
+			if text := msgText(t, options[0]); text != catalog[DefaultLanguage][msgCancelFailed] {
+				t.Errorf("Expected the creator to be told that the cancellation failed, got %q", text)
+			}
+			return "timestamp", nil
+		}).Times(1)
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), string(channel), "ts").Times(1)
+
+	gameMgr.gameRequests["lobby"] = &GameRequest{
+		id:        "lobby",
+		channel:   channel,
+		players:   []string{"creator", "p2"},
+		quorum:    4,
+		messageTs: "ts",
+	}
+
+	gameMgr.CancelGame(channel, "creator", "")
+
+	if _, exists := gameMgr.getGameRequest(channel, "lobby"); !exists {
+		t.Error("Expected the game to stay open after its cancellation failed")
+	}
+	inspect(gameMgr, func() {
+		if _, finished := gameMgr.finished["lobby"]; finished {
+			t.Error("Expected the game not to be remembered as cancelled")
+		}
+	})
+}
+
+// TestCancelDoesNotBlockLobby verifies that the lobby keeps answering while the message of a cancelled game request
+// is replaced, and that it rejects joins in the meantime.
+func TestCancelDoesNotBlockLobby(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+
+	updating := make(chan struct{})
+	release := make(chan struct{})
+	mockSlackClient.EXPECT().
+		UpdateMessage("C1", "ts", gomock.Any()).
+		DoAndReturn(func(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+			close(updating)
+			<-release
+			return channelID, timestamp, "text", nil
+		}).Times(1)
+	mockSlackClient.EXPECT().
+		PostEphemeral("C1", "p3", gomock.Any()).
+		DoAndReturn(func(channelID, userID string, options ...slack.MsgOption) (string, error) {
+			if text := msgText(t, options[0]); text != catalog[DefaultLanguage][msgLobbyClosing] {
+				t.Errorf("Expected the join to be rejected while the game is cancelled, got %q", text)
+			}
+			return "timestamp", nil
+		}).Times(1)
+
+	gameMgr.gameRequests["lobby"] = &GameRequest{
+		id:        "lobby",
+		channel:   "C1",
+		players:   []string{"creator", "p2"},
+		gameType:  GameTypeTwoVsTwo,
+		quorum:    4,
+		timeout:   30 * time.Minute,
+		messageTs: "ts",
+	}
+
+	cancelled := make(chan struct{})
+	go func() {
+		defer close(cancelled)
+		gameMgr.CancelGame("C1", "creator", "lobby")
+	}()
+	<-updating
+
+	rejected := make(chan struct{})
+	go func() {
+		defer close(rejected)
+		gameMgr.JoinGame("C1", "lobby", "p3")
+	}()
+	select {
+	case <-rejected:
+	case <-time.After(time.Second):
+		t.Error("Expected the join to be answered while the cancellation is sent")
+	}
+	close(release)
+	<-cancelled
+
+	if _, exists := gameMgr.getGameRequest("C1", "lobby"); exists {
+		t.Error("Expected the game to be removed once its message shows the cancellation")
+	}
+}
+
+// TestShutdownAndRestoreWithStore verifies that a game manager with a store keeps the lobby messages on shutdown
+// and that a new game manager resumes the persisted lobby, working with the existing Slack message.
+func TestShutdownAndRestoreWithStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "kickbot.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	channelID := "test-channel"
+	channel := SlackChannel(channelID)
+
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "lobby-ts", nil).Times(1)
+	mockSlackClient.EXPECT().
+		UpdateMessage(channelID, "lobby-ts", gomock.Any()).
+		Return(channelID, "lobby-ts", "text", nil).Times(2)
+
+	// Messages must not be deleted on shutdown, the lobby is resumed after the restart
+	mockSlackClient.EXPECT().DeleteMessageContext(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	var gameOptions = GameOpts{
+		timeout:  time.Minute * 30,
+		gameType: GameTypeTwoVsTwo,
+	}
+
+	gameMgr := NewGameManager(mockSlackClient, WithGameStore(store))
+	gameMgr.CreateGame(channel, "p1", gameOptions)
+	gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), "p2")
+	gameMgr.Shutdown(context.TODO())
+
+	restarted := NewGameManager(mockSlackClient, WithGameStore(store))
+	defer restarted.Shutdown(context.TODO())
+	if err := restarted.RestoreGames(); err != nil {
+		t.Fatalf("Failed to restore games: %v", err)
+	}
+
+	gameReq, exists := restarted.getGameRequest(channel, lobbyIn(restarted, channel))
+	if !exists {
+		t.Fatal("Expected the game request to be restored")
+	}
+	inspectLobby(gameReq, func() {
+		if !slices.Equal(gameReq.players, []string{"p1", "p2"}) || gameReq.quorum != 4 || gameReq.messageTs != "lobby-ts" {
+			t.Errorf("Restored game request doesn't match: players %v, quorum %d, ts %s", gameReq.players, gameReq.quorum, gameReq.messageTs)
+		}
+		if _, scheduled := restarted.scheduler.when(timeoutJob(gameReq.id)); !scheduled {
+			t.Error("Expected the timeout of the restored game request to be re-armed")
+		}
+	})
+
+	// Joining the restored lobby updates the existing message
+	restarted.JoinGame(channel, lobbyIn(restarted, channel), "p3")
+
+	records, _ := store.LoadGameRequests()
+	if len(records) != 1 || len(records[0].Players) != 3 {
+		t.Errorf("Expected the store to hold the lobby with 3 players, found %+v", records)
+	}
+}
+
+// TestRestoreExpiredGame verifies that a persisted game request whose deadline passed while the bot was down
+// expires right after the restore and is removed from the store.
+func TestRestoreExpiredGame(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	clock := newFakeClock()
+
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "kickbot.json"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	store.SaveGameRequest(GameRequestRecord{
+		Channel:   "test-channel",
+		GameType:  GameTypeOneVsOne,
+		Players:   []string{"p1"},
+		Quorum:    2,
+		MessageTs: "lobby-ts",
+		Deadline:  clock.Now().Add(-time.Minute),
+	})
+
+	// The timeout message replaces the lobby message
+	mockSlackClient.EXPECT().
+		UpdateMessage("test-channel", "lobby-ts", gomock.Any()).
+		Return("test-channel", "lobby-ts", "text", nil).Times(1)
+
+	gameMgr := NewGameManager(mockSlackClient, WithGameStore(store), WithClock(clock))
+	defer gameMgr.Shutdown(context.TODO())
+	if err := gameMgr.RestoreGames(); err != nil {
+		t.Fatalf("Failed to restore games: %v", err)
+	}
+
+	clock.Advance(0)
+
+	if _, exists := gameMgr.getGameRequest("test-channel", lobbyIn(gameMgr, "test-channel")); exists {
+		t.Error("Expected the expired game request to be deleted")
+	}
+	if records, _ := store.LoadGameRequests(); len(records) != 0 {
+		t.Errorf("Expected the expired game request to be removed from the store, found %d", len(records))
+	}
+}
+
+// TestRecordMatchResult verifies that a filled game request creates a match whose result can be entered
+// exactly once by one of its players.
+func TestRecordMatchResult(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	clock := newFakeClock()
+	gameMgr := NewGameManager(mockSlackClient, WithClock(clock))
+	defer gameMgr.Shutdown(context.TODO())
+
+	channelID := "test-channel"
+	channel := SlackChannel(channelID)
+
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "lobby-ts", nil).Times(1)
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, gomock.Any(), gomock.Any()).
+		Return("timestamp", nil).AnyTimes()
+	// One update for the game start and one for the result
+	mockSlackClient.EXPECT().
+		UpdateMessage(channelID, "lobby-ts", gomock.Any()).
+		Return(channelID, "lobby-ts", "text", nil).Times(2)
+	mockSlackClient.EXPECT().
+		OpenView("trigger", gomock.Any()).
+		Return(&slack.ViewResponse{}, nil).Times(1)
+
+	gameMgr.CreateGame(channel, "p1", GameOpts{timeout: time.Minute * 30, gameType: GameTypeOneVsOne})
+	gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), "p2")
+
+	matches := matchesOf(gameMgr)
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match after the game filled, found %d", len(matches))
+	}
+	var match MatchRecord
+	for _, m := range matches {
+		match = m
+	}
+
+	if match.IsRecorded() || !slices.Equal(match.Players, []string{"p1", "p2"}) || match.MessageTs != "lobby-ts" || !match.StartedAt.Equal(clock.Now()) {
+		t.Errorf("Unexpected match %+v", match)
+	}
+
+	// Outsiders can neither open the form nor submit a result
+	gameMgr.OpenResultForm(channel, match.ID, "outsider", "trigger")
+	if errs := gameMgr.RecordResult(match.ID, "outsider", MatchResult{TeamOne: []string{"p1"}, Score: [2]int{10, 3}, Winner: 0}); errs == nil {
+		t.Error("Expected outsider to be rejected")
+	}
+
+	gameMgr.OpenResultForm(channel, match.ID, "p2", "trigger")
+	clock.Advance(10 * time.Minute)
+
+	if errs := gameMgr.RecordResult(match.ID, "p2", MatchResult{TeamOne: []string{"p1"}, Score: [2]int{10, 3}, Winner: 0}); errs != nil {
+		t.Fatalf("Expected result to be recorded, got %v", errs)
+	}
+	if errs := gameMgr.RecordResult(match.ID, "p1", MatchResult{TeamOne: []string{"p1"}, Score: [2]int{3, 10}, Winner: 1}); errs == nil {
+		t.Error("Expected second result submission to be rejected")
+	}
+
+	recorded := matchesOf(gameMgr)[match.ID]
+	if !recorded.IsRecorded() || recorded.Score != [2]int{10, 3} || recorded.Winner != 0 || recorded.RecordedBy != "p2" ||
+		!recorded.RecordedAt.Equal(match.StartedAt.Add(10*time.Minute)) {
+		t.Errorf("Unexpected recorded match %+v", recorded)
+	}
+}
+
+func TestLeaderboardSelectsMatches(t *testing.T) {
+	now := time.Now()
+
+	inChannel := func(match MatchRecord, channel SlackChannel) MatchRecord {
+		match.Channel = channel
+		return match
+	}
+	matches := []MatchRecord{
+		inChannel(recordedMatch(GameTypeOneVsOne, []string{"old"}, []string{"p2"}, 0, now.Add(-60*24*time.Hour)), "c1"),
+		inChannel(recordedMatch(GameTypeOneVsOne, []string{"p1"}, []string{"p2"}, 0, now.Add(-time.Hour)), "c1"),
+		inChannel(recordedMatch(GameTypeOneVsOne, []string{"other"}, []string{"p2"}, 0, now.Add(-time.Hour)), "c2"),
+		inChannel(recordedMatch(GameTypeTwoVsTwo, []string{"t1", "t2"}, []string{"t3", "t4"}, 0, now.Add(-time.Hour)), "c1"),
+	}
+
+	players := func(standings []PlayerRating) []string {
+		result := make([]string, len(standings))
+		for i, standing := range standings {
+			result[i] = standing.Player
+		}
+		slices.Sort(result)
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		options  StatsOpts
+		expected []string
+	}{
+		{"channel all time", StatsOpts{gameType: GameTypeOneVsOne}, []string{"old", "p1", "p2"}},
+		{"channel last 30 days", StatsOpts{gameType: GameTypeOneVsOne, since: 30 * 24 * time.Hour}, []string{"p1", "p2"}},
+		{"workspace last 30 days", StatsOpts{gameType: GameTypeOneVsOne, since: 30 * 24 * time.Hour, workspace: true}, []string{"other", "p1", "p2"}},
+		{"2v2", StatsOpts{gameType: GameTypeTwoVsTwo}, []string{"t1", "t2", "t3", "t4"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			standings := leaderboard(matches, DefaultRatingConfig, "c1", tc.options, now)
+			if got := players(standings); !slices.Equal(got, tc.expected) {
+				t.Errorf("Expected players %v, got %v", tc.expected, got)
+			}
+		})
+	}
+
+	// p2 lost both matches of the last 30 days in the workspace
+	standings := leaderboard(matches, DefaultRatingConfig, "c1", StatsOpts{gameType: GameTypeOneVsOne, since: 30 * 24 * time.Hour, workspace: true}, now)
+	last := standings[len(standings)-1]
+	if last.Player != "p2" || last.Losses != 2 || last.Wins != 0 {
+		t.Errorf("Expected p2 to be last with 2 losses, got %+v", last)
+	}
+}
+
+// TestTwoVsTwoTeamProposal verifies that a filled 2 vs 2 game proposes teams and that players can reshuffle them.
+func TestTwoVsTwoTeamProposal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+
+	channelID := "test-channel"
+	channel := SlackChannel(channelID)
+
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "lobby-ts", nil).Times(1)
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, gomock.Any(), gomock.Any()).
+		Return("timestamp", nil).AnyTimes()
+	// 3 joins and 1 reshuffle
+	mockSlackClient.EXPECT().
+		UpdateMessage(channelID, "lobby-ts", gomock.Any()).
+		Return(channelID, "lobby-ts", "text", nil).Times(4)
+
+	gameMgr.SetPosition(channel, "p1", PositionAttack)
+
+	gameMgr.CreateGame(channel, "p1", GameOpts{timeout: time.Minute * 30, gameType: GameTypeTwoVsTwo})
+	for _, player := range []string{"p2", "p3", "p4"} {
+		gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), player)
+	}
+
+	var match MatchRecord
+	for _, m := range matchesOf(gameMgr) {
+		match = m
+	}
+
+	if len(match.Teams[0]) != 2 || len(match.Teams[1]) != 2 {
+		t.Fatalf("Expected two proposed teams of two, got %v", match.Teams)
+	}
+	for _, team := range match.Teams {
+		if team[0] == "p1" {
+			t.Errorf("Expected p1 to be placed in attack, got %v", match.Teams)
+		}
+	}
+
+	// Outsiders can't reshuffle
+	gameMgr.ShuffleTeams(channel, match.ID, "outsider")
+	gameMgr.ShuffleTeams(channel, match.ID, "p3")
+
+	reshuffled := matchesOf(gameMgr)[match.ID]
+	p1Team := reshuffled.Teams[0]
+	if !slices.Contains(p1Team, "p1") {
+		p1Team = reshuffled.Teams[1]
+	}
+	oldP1Team := match.Teams[0]
+	if !slices.Contains(oldP1Team, "p1") {
+		oldP1Team = match.Teams[1]
+	}
+	if slices.Equal(sortedPlayers([2][]string{p1Team, nil}), sortedPlayers([2][]string{oldP1Team, nil})) {
+		t.Errorf("Expected reshuffle to change the teams, got %v before and %v after", match.Teams, reshuffled.Teams)
+	}
+}
+
+// TestWaitlistSeedsNextGame verifies that a player joining a full game is put on the waitlist and that the waitlist
+// seeds the next game request in the channel with the first waitlisted player as creator once the game is announced.
+func TestWaitlistSeedsNextGame(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+
+	channelID := "test-channel"
+	channel := SlackChannel(channelID)
+
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "first-ts", nil).Times(1)
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "seeded-ts", nil).Times(1)
+	mockSlackClient.EXPECT().
+		UpdateMessage(channelID, "first-ts", gomock.Any()).
+		Return(channelID, "first-ts", "text", nil).Times(1)
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), channelID, "seeded-ts").Times(1)
+
+	// While the players of the full game are pinged, p3 and p4 click join and end up on the waitlist
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, "p1", gomock.Any()).
+		DoAndReturn(func(channelID, userID string, options ...slack.MsgOption) (string, error) {
+			gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), "p3")
+			gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), "p4")
+			gameMgr.LeaveGame(channel, lobbyIn(gameMgr, channel), "p4")
+			return "timestamp", nil
+		}).Times(1)
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, "p2", gomock.Any()).
+		Return("timestamp", nil).Times(1)
+	// waitlist position, the notification for the seeded game
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, "p3", gomock.Any()).
+		Return("timestamp", nil).Times(2)
+	// waitlist position and the confirmation for leaving the waitlist
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, "p4", gomock.Any()).
+		Return("timestamp", nil).Times(2)
+
+	gameMgr.CreateGame(channel, "p1", GameOpts{timeout: time.Minute * 30, gameType: GameTypeOneVsOne})
+	gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), "p2")
+
+	gameReq, exists := gameMgr.getGameRequest(channel, lobbyIn(gameMgr, channel))
+	if !exists {
+		t.Fatal("Expected the waitlist to seed a new game request")
+	}
+	inspectLobby(gameReq, func() {
+		if !slices.Equal(gameReq.players, []string{"p3"}) || gameReq.messageTs != "seeded-ts" || gameReq.timeout != time.Minute*30 {
+			t.Errorf("Unexpected seeded game request: players %v, ts %s, timeout %v", gameReq.players, gameReq.messageTs, gameReq.timeout)
+		}
+		if _, scheduled := gameMgr.scheduler.when(timeoutJob(gameReq.id)); !scheduled {
+			t.Error("Expected the timeout of the seeded game request to be armed")
+		}
+	})
+}
+
+// TestMultipleLobbiesPerChannel verifies that several game requests form in the same channel at the same time,
+// that joins and leaves are routed to the game request of the given lobby and that a player can only be part of
+// a single game request of the channel.
+func TestMultipleLobbiesPerChannel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+
+	channelID := "test-channel"
+	channel := SlackChannel(channelID)
+
+	for _, ts := range []string{"duel-1", "duel-2", "game"} {
+		mockSlackClient.EXPECT().
+			PostMessage(channelID, gomock.Any()).
+			Return(channelID, ts, nil).Times(1)
+	}
+	mockSlackClient.EXPECT().
+		UpdateMessage(channelID, gomock.Any(), gomock.Any()).
+		Return(channelID, "ts", "text", nil).AnyTimes()
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), channelID, gomock.Any()).Times(2)
+
+	// p1 is already part of a lobby and p3 is already part of another lobby of the channel
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, "p1", gomock.Any()).
+		Return("timestamp", nil).Times(2)
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, "p3", gomock.Any()).
+		Return("timestamp", nil).Times(1)
+	// game start ping of the first duel
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, "p4", gomock.Any()).
+		Return("timestamp", nil).Times(1)
+
+	duel := GameOpts{timeout: time.Minute * 30, gameType: GameTypeOneVsOne}
+	gameMgr.CreateGame(channel, "p1", duel)
+	gameMgr.CreateGame(channel, "p2", duel)
+	gameMgr.CreateGame(channel, "p3", GameOpts{timeout: time.Minute * 30, gameType: GameTypeTwoVsTwo})
+	gameMgr.CreateGame(channel, "p1", duel)
+
+	firstDuel := gameMgr.LobbyByMessage(channel, "duel-1")
+	secondDuel := gameMgr.LobbyByMessage(channel, "duel-2")
+	game := gameMgr.LobbyByMessage(channel, "game")
+	if firstDuel == "" || secondDuel == "" || game == "" || firstDuel == secondDuel {
+		t.Fatalf("Expected three distinct lobbies, got %q, %q and %q", firstDuel, secondDuel, game)
+	}
+	if id := gameMgr.LobbyByMessage("other-channel", "duel-1"); id != "" {
+		t.Errorf("Expected no lobby for a message of another channel, got %q", id)
+	}
+
+	gameMgr.JoinGame(channel, secondDuel, "p3")
+	gameMgr.JoinGame(channel, firstDuel, "p4")
+
+	if _, exists := gameMgr.getGameRequest(channel, firstDuel); exists {
+		t.Error("Expected the first duel to start once p4 joined")
+	}
+	gameReq, exists := gameMgr.getGameRequest(channel, secondDuel)
+	if !exists {
+		t.Fatal("Expected the second duel to still be open")
+	}
+	inspectLobby(gameReq, func() {
+		if !slices.Equal(gameReq.players, []string{"p2"}) {
+			t.Errorf("Expected p3 to be rejected from the second duel, found %v", gameReq.players)
+		}
+	})
+
+	// p1 is free again after the duel started
+	gameMgr.JoinGame(channel, game, "p1")
+	gameReq, _ = gameMgr.getGameRequest(channel, game)
+	inspectLobby(gameReq, func() {
+		if !slices.Equal(gameReq.players, []string{"p3", "p1"}) {
+			t.Errorf("Expected p1 to join the 2v2 game, found %v", gameReq.players)
+		}
+	})
+}
+
+// TestStaleLobbyActions verifies that actions on the message of a finished game request are rejected and that the
+// stale message is refreshed to show the final state of the game request.
+func TestStaleLobbyActions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+
+	channelID := "test-channel"
+	channel := SlackChannel(channelID)
+
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "cancelled-ts", nil).Times(1)
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "open-ts", nil).Times(1)
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), channelID, "open-ts").Times(1)
+
+	// The cancellation and the refresh of the stale message for the join and the leave
+	mockSlackClient.EXPECT().
+		UpdateMessage(channelID, "cancelled-ts", gomock.Any()).
+		Return(channelID, "cancelled-ts", "text", nil).Times(3)
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, "p2", gomock.Any()).
+		Return("timestamp", nil).Times(3)
+
+	gameMgr.CreateGame(channel, "p1", GameOpts{timeout: time.Minute * 30, gameType: GameTypeTwoVsTwo})
+	cancelled := gameMgr.LobbyByMessage(channel, "cancelled-ts")
+	gameMgr.CancelGame(channel, "p1", "")
+
+	// a new game request is open in the channel, but clicks on the old message must not join it
+	gameMgr.CreateGame(channel, "p1", GameOpts{timeout: time.Minute * 30, gameType: GameTypeTwoVsTwo})
+	gameMgr.JoinGame(channel, cancelled, "p2")
+	gameMgr.LeaveGame(channel, cancelled, "p2")
+
+	// lobbies that are not known at all can't be refreshed
+	gameMgr.JoinGame(channel, "unknown", "p2")
+
+	gameReq, exists := gameMgr.getGameRequest(channel, gameMgr.LobbyByMessage(channel, "open-ts"))
+	if !exists {
+		t.Fatal("Expected the new game request to be open")
+	}
+	inspectLobby(gameReq, func() {
+		if !slices.Equal(gameReq.players, []string{"p1"}) {
+			t.Errorf("Expected the new game request to be untouched, found %v", gameReq.players)
+		}
+	})
+}
+
+func TestFinishedLobbyHistoryIsBounded(t *testing.T) {
+	gameMgr := NewGameManager(nil)
+	defer gameMgr.Shutdown(context.TODO())
+
+	inspect(gameMgr, func() {
+		for i := range finishedLobbyHistory + 1 {
+			gameMgr.rememberFinished(LobbyID(fmt.Sprint(i)), finishedLobby{channel: "test-channel", outcome: lobbyExpired})
+		}
+		if len(gameMgr.finished) != finishedLobbyHistory || len(gameMgr.finishedIDs) != finishedLobbyHistory {
+			t.Errorf("Expected %d remembered lobbies, found %d", finishedLobbyHistory, len(gameMgr.finished))
+		}
+		if _, exists := gameMgr.finished["0"]; exists {
+			t.Error("Expected the oldest lobby to be forgotten")
+		}
+	})
+}
+
+// TestScheduledGame verifies that a scheduled game is announced right away without a lead time, that players can join
+// ahead of time and that the players of the full game are reminded shortly before the start.
+func TestScheduledGame(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	clock := newFakeClock()
+	gameMgr := NewGameManager(mockSlackClient, WithClock(clock))
+	defer gameMgr.Shutdown(context.TODO())
+
+	channelID := "test-channel"
+	channel := SlackChannel(channelID)
+	startAt := clock.Now().Add(time.Hour)
+
+	// a scheduled game starts at its time, its message has no extend button
+	var announcement slack.MsgOption
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		DoAndReturn(func(channelID string, options ...slack.MsgOption) (string, string, error) {
+			announcement = options[0]
+			return channelID, "ts", nil
+		}).Times(1)
+	mockSlackClient.EXPECT().
+		UpdateMessage(channelID, "ts", gomock.Any()).
+		Return(channelID, "ts", "text", nil).Times(1)
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, gomock.Any(), gomock.Any()).
+		Return("timestamp", nil).Times(2)
+
+	gameMgr.CreateGame(channel, "p1", GameOpts{timeout: time.Minute * 30, gameType: GameTypeOneVsOne, startAt: startAt})
+	if blocks := msgBlocks(t, announcement); !strings.Contains(blocks, ACTION_JOIN_ROUND) || strings.Contains(blocks, ACTION_EXTEND_ROUND) {
+		t.Errorf("Expected the scheduled game to have no extend button, got %s", blocks)
+	}
+
+	gameReq, exists := gameMgr.getGameRequest(channel, lobbyIn(gameMgr, channel))
+	if !exists {
+		t.Fatal("Expected the scheduled game to be open")
+	}
+	inspectLobby(gameReq, func() {
+		if !gameReq.deadline.Equal(startAt) {
+			t.Errorf("Expected the scheduled game to expire at its start %v, got %v", startAt, gameReq.deadline)
+		}
+	})
+
+	gameMgr.JoinGame(channel, gameReq.id, "p2")
+
+	matches := matchesOf(gameMgr)
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match, found %d", len(matches))
+	}
+	var match MatchRecord
+	for _, match = range matches {
+		if !match.StartedAt.Equal(startAt) {
+			t.Errorf("Expected the match to start at %v, got %v", startAt, match.StartedAt)
+		}
+	}
+
+	if remindAt, scheduled := gameMgr.scheduler.when(reminderJob(match.ID)); !scheduled || !remindAt.Equal(startAt.Add(-reminderLead)) {
+		t.Errorf("Expected the reminder to be scheduled for %v, got %v", startAt.Add(-reminderLead), remindAt)
+	}
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "reminder-ts", nil).Times(1)
+	clock.Advance(time.Hour - reminderLead)
+}
+
+// TestScheduledGameWithLeadTime verifies that a scheduled game with a lead time is only announced at the lead time.
+func TestScheduledGameWithLeadTime(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	clock := newFakeClock()
+	gameMgr := NewGameManager(mockSlackClient, WithClock(clock))
+	defer gameMgr.Shutdown(context.TODO())
+
+	channelID := "test-channel"
+	channel := SlackChannel(channelID)
+	startAt := clock.Now().Add(time.Hour)
+
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, "p1", gomock.Any()).
+		Return("timestamp", nil).Times(1)
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), channelID, "ts").Times(1)
+
+	gameMgr.CreateGame(channel, "p1", GameOpts{gameType: GameTypeTwoVsTwo, startAt: startAt, lead: time.Hour - 50*time.Millisecond})
+
+	if id := gameMgr.LobbyByMessage(channel, ""); id != "" {
+		t.Error("Expected the game request to have no message before its announcement")
+	}
+
+	// the announcement is only posted at the lead time
+	clock.Advance(40 * time.Millisecond)
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "ts", nil).Times(1)
+	clock.Advance(10 * time.Millisecond)
+
+	gameReq, exists := gameMgr.getGameRequest(channel, gameMgr.LobbyByMessage(channel, "ts"))
+	if !exists {
+		t.Fatal("Expected the announced game request to be open")
+	}
+	if deadline, scheduled := gameMgr.scheduler.when(timeoutJob(gameReq.id)); !scheduled || !deadline.Equal(startAt) {
+		t.Errorf("Expected the timeout to be armed for the start %v, got %v", startAt, deadline)
+	}
+}
+
+// TestScheduledGameExpiresAtStart verifies that a scheduled game that isn't full by its start time expires.
+func TestScheduledGameExpiresAtStart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	clock := newFakeClock()
+	gameMgr := NewGameManager(mockSlackClient, WithClock(clock))
+	defer gameMgr.Shutdown(context.TODO())
+
+	channelID := "test-channel"
+	channel := SlackChannel(channelID)
+
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "ts", nil).Times(1)
+	mockSlackClient.EXPECT().
+		UpdateMessage(channelID, "ts", gomock.Any()).
+		Return(channelID, "ts", "text", nil).Times(1)
+
+	gameMgr.CreateGame(channel, "p1", GameOpts{timeout: time.Hour, gameType: GameTypeOneVsOne, startAt: clock.Now().Add(50 * time.Millisecond)})
+
+	clock.Advance(50 * time.Millisecond)
+
+	inspect(gameMgr, func() {
+		if len(gameMgr.gameRequests) != 0 {
+			t.Error("Expected the scheduled game to expire at its start")
+		}
+	})
+}
+
+// TestTimeoutWarning verifies that the message of a game request warns about the timeout shortly before it expires.
+func TestTimeoutWarning(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	clock := newFakeClock()
+	gameMgr := NewGameManager(mockSlackClient, WithTimeoutWarning(100*time.Millisecond), WithClock(clock))
+	defer gameMgr.Shutdown(context.TODO())
+
+	channelID := "test-channel"
+	channel := SlackChannel(channelID)
+
+	var updates []time.Time
+	var updatesMu sync.Mutex
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "ts", nil).Times(1)
+	// the warning and the timeout
+	mockSlackClient.EXPECT().
+		UpdateMessage(channelID, "ts", gomock.Any()).
+		DoAndReturn(func(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+			updatesMu.Lock()
+			defer updatesMu.Unlock()
+			updates = append(updates, clock.Now())
+			return channelID, timestamp, "text", nil
+		}).Times(2)
+
+	created := clock.Now()
+	gameMgr.CreateGame(channel, "p1", GameOpts{timeout: 150 * time.Millisecond, gameType: GameTypeTwoVsTwo})
+	clock.Advance(50 * time.Millisecond)
+	gameMgr.updates.flush(context.TODO())
+	clock.Advance(100 * time.Millisecond)
+	gameMgr.updates.flush(context.TODO())
+
+	updatesMu.Lock()
+	defer updatesMu.Unlock()
+	if len(updates) != 2 {
+		t.Fatalf("Expected a warning and a timeout update, got %d updates", len(updates))
+	}
+	if warnedAfter := updates[0].Sub(created); warnedAfter != 50*time.Millisecond {
+		t.Errorf("Expected the warning 100ms before the timeout, got it after %v", warnedAfter)
+	}
+}
+
+// TestExtendGame verifies that only the creator can push back the timeout of a game request
+// and that the game request doesn't expire at its original deadline.
+func TestExtendGame(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	clock := newFakeClock()
+	gameMgr := NewGameManager(mockSlackClient, WithTimeoutWarning(0), WithClock(clock))
+	defer gameMgr.Shutdown(context.TODO())
+
+	channelID := "test-channel"
+	channel := SlackChannel(channelID)
+
+	var announcement slack.MsgOption
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		DoAndReturn(func(channelID string, options ...slack.MsgOption) (string, string, error) {
+			announcement = options[0]
+			return channelID, "ts", nil
+		}).Times(1)
+	// the join and the extension, but no timeout
+	mockSlackClient.EXPECT().
+		UpdateMessage(channelID, "ts", gomock.Any()).
+		Return(channelID, "ts", "text", nil).Times(2)
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, "p2", gomock.Any()).
+		Return("timestamp", nil).Times(1)
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, "p1", gomock.Any()).
+		Return("timestamp", nil).Times(1)
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), channelID, "ts").Times(1)
+
+	gameMgr.CreateGame(channel, "p1", GameOpts{timeout: 50 * time.Millisecond, gameType: GameTypeTwoVsTwo})
+	if blocks := msgBlocks(t, announcement); !strings.Contains(blocks, ACTION_EXTEND_ROUND) {
+		t.Errorf("Expected the game to have an extend button, got %s", blocks)
+	}
+	id := lobbyIn(gameMgr, channel)
+	gameMgr.JoinGame(channel, id, "p2")
+
+	gameReq, _ := gameMgr.getGameRequest(channel, id)
+	var deadline time.Time
+	inspectLobby(gameReq, func() {
+		deadline = gameReq.deadline
+	})
+
+	gameMgr.ExtendGame(channel, id, "p2")
+	gameMgr.ExtendGame(channel, id, "p1")
+
+	clock.Advance(100 * time.Millisecond)
+
+	if _, exists := gameMgr.getGameRequest(channel, id); !exists {
+		t.Fatal("Expected the extended game request to be open after its original deadline")
+	}
+	inspectLobby(gameReq, func() {
+		if !gameReq.deadline.Equal(deadline.Add(extendBy)) {
+			t.Errorf("Expected the deadline to be pushed back by %v, got %v instead of %v", extendBy, gameReq.deadline, deadline)
+		}
+	})
+}
+
+// TestRundlaufStartsAtTimeout verifies that a game request of a format with a range of players waits for the number
+// of players it was created for, but starts with the players it has at its timeout once it reached the minimum.
+func TestRundlaufStartsAtTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	clock := newFakeClock()
+	gameMgr := NewGameManager(mockSlackClient, WithTimeoutWarning(0), WithClock(clock))
+	defer gameMgr.Shutdown(context.TODO())
+
+	channelID := "test-channel"
+	channel := SlackChannel(channelID)
+	players := []string{"p1", "p2", "p3", "p4", "p5"}
+
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "ts", nil).Times(1)
+	// 4 joins and the game start at the timeout instead of the timeout message
+	var lastUpdate slack.MsgOption
+	var updateMu sync.Mutex
+	mockSlackClient.EXPECT().
+		UpdateMessage(channelID, "ts", gomock.Any()).
+		DoAndReturn(func(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+			updateMu.Lock()
+			defer updateMu.Unlock()
+			lastUpdate = options[0]
+			return channelID, timestamp, "text", nil
+		}).Times(5)
+	for _, player := range players {
+		mockSlackClient.EXPECT().
+			PostEphemeral(channelID, player, gomock.Any()).
+			Return("timestamp", nil).Times(1)
+	}
+
+	gameMgr.CreateGame(channel, "p1", GameOpts{timeout: 100 * time.Millisecond, gameType: GameTypeRundlauf, players: 6})
+	id := lobbyIn(gameMgr, channel)
+	for _, player := range players[1:] {
+		gameMgr.JoinGame(channel, id, player)
+	}
+	if _, exists := gameMgr.getGameRequest(channel, id); !exists {
+		t.Fatal("Expected the game request to wait for its sixth player")
+	}
+
+	clock.Advance(100 * time.Millisecond)
+
+	if _, exists := gameMgr.getGameRequest(channel, id); exists {
+		t.Fatal("Expected the game request to start at its timeout")
+	}
+	matches := matchesOf(gameMgr)
+	if len(matches) != 1 {
+		t.Fatalf("Expected one match, got %d", len(matches))
+	}
+	for _, match := range matches {
+		if match.GameType != GameTypeRundlauf || len(match.Players) != 5 || len(match.Teams[0]) != 0 {
+			t.Errorf("Expected a Rundlauf of five players without teams, got %+v", match)
+		}
+	}
+	updateMu.Lock()
+	defer updateMu.Unlock()
+	_, values, err := slack.UnsafeApplyMsgOptions("token", channelID, "https://slack.com/api/", lastUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blocks := values.Get("blocks"); !strings.Contains(blocks, "Reihenfolge am Tisch") || strings.Contains(blocks, ACTION_RECORD_RESULT) {
+		t.Errorf("Expected the order around the table and no result button, got %s", blocks)
+	}
+}
+
+// TestHandicapTeamProposal verifies that the players of a 2 vs 1 game are split into a team of two and a single
+// player who can be reshuffled.
+func TestHandicapTeamProposal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+
+	channelID := "test-channel"
+	channel := SlackChannel(channelID)
+
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "ts", nil).Times(1)
+	mockSlackClient.EXPECT().
+		PostEphemeral(channelID, gomock.Any(), gomock.Any()).
+		Return("timestamp", nil).Times(3)
+	// 1 join, the game start and 1 reshuffle
+	mockSlackClient.EXPECT().
+		UpdateMessage(channelID, "ts", gomock.Any()).
+		Return(channelID, "ts", "text", nil).Times(3)
+
+	gameMgr.CreateGame(channel, "p1", GameOpts{timeout: time.Minute * 30, gameType: GameTypeTwoVsOne})
+	for _, player := range []string{"p2", "p3"} {
+		gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), player)
+	}
+
+	var match MatchRecord
+	for _, m := range matchesOf(gameMgr) {
+		match = m
+	}
+	if len(match.Teams[0]) != 2 || len(match.Teams[1]) != 1 {
+		t.Fatalf("Expected a team of two against a single player, got %v", match.Teams)
+	}
+
+	gameMgr.ShuffleTeams(channel, match.ID, "p1")
+	reshuffled := matchesOf(gameMgr)[match.ID]
+	if reshuffled.Teams[1][0] == match.Teams[1][0] {
+		t.Errorf("Expected another player to play alone after the reshuffle, got %v before and %v after", match.Teams, reshuffled.Teams)
+	}
+}
+
+// BenchmarkConcurrentJoinsAndLeaves measures how fast players join and leave a game request concurrently while
+// every update of its message takes as long as a call of the Slack API. Every operation is a join followed by a
+// leave of one of the players. The rate limits and the debounce interval are lifted, so that only the handling of
+// the lobby is measured.
+func BenchmarkConcurrentJoinsAndLeaves(b *testing.B) {
+	const nPlayers = 8
+	const slackLatency = 2 * time.Millisecond
+
+	ctrl := gomock.NewController(b)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	unlimited := RateLimitConfig{Tier3: math.MaxInt32, Tier4: math.MaxInt32, PostMessage: math.MaxInt32}
+	gameMgr := NewGameManager(mockSlackClient, WithRateLimitConfig(unlimited))
+	defer gameMgr.Shutdown(context.TODO())
+
+	mockSlackClient.EXPECT().
+		UpdateMessage(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+			time.Sleep(slackLatency)
+			return channelID, timestamp, "text", nil
+		}).AnyTimes()
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	// the game request never fills up, so that the players can join and leave it forever
+	gameMgr.gameRequests["lobby"] = &GameRequest{
+		id:        "lobby",
+		channel:   "C1",
+		players:   []string{"creator"},
+		quorum:    nPlayers + 2,
+		messageTs: "ts",
+	}
+
+	b.ResetTimer()
+	var wg sync.WaitGroup
+	for i := range nPlayers {
+		wg.Add(1)
+		go func(player string, n int) {
+			defer wg.Done()
+			for range n {
+				gameMgr.JoinGame("C1", "lobby", player)
+				gameMgr.LeaveGame("C1", "lobby", player)
+			}
+		}(fmt.Sprintf("p%d", i), (b.N+i)/nPlayers)
+	}
+	wg.Wait()
+}
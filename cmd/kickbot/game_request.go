@@ -1,7 +1,9 @@
 package main
 
 import (
-	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
 	"sync"
 	"time"
 )
@@ -11,28 +13,198 @@ type GameType int
 const (
 	GameTypeTwoVsTwo GameType = iota // 2 vs 2 football table game
 	GameTypeOneVsOne                 // 1 vs 1 football table game
+	GameTypeTwoVsOne                 // 2 vs 1 handicap game, the single player defends alone
+	GameTypeRundlauf                 // "Rundlauf" around the table with 5 to 8 players and no teams
 )
 
-// quorumMap maps game types to their quorum values
-var quorumMap = map[GameType]int{
-	GameTypeTwoVsTwo: 4, // 2 vs 2 game requires 4 players
-	GameTypeOneVsOne: 2, // 1 vs 1 game requires 2 players
+// LobbyID identifies a game request. A channel can have several game requests forming at the same time.
+type LobbyID string
+
+// newLobbyID returns a random lobby ID that is short enough to be typed in slash commands.
+func newLobbyID() LobbyID {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return LobbyID(hex.EncodeToString(b))
+}
+
+// lobbyOutcome is the way a game request finished.
+type lobbyOutcome int
+
+const (
+	lobbyDiscarded lobbyOutcome = iota // the game request was never announced, there is nothing to remember
+	lobbyStarted                       // the game reached its quorum
+	lobbyCancelled                     // the game request was cancelled by its creator
+	lobbyExpired                       // the game request timed out before reaching its quorum
+	lobbyAbandoned                     // all players left and the message was deleted
+)
+
+// finishedLobby remembers a finished game request, so that actions on its stale message can be answered.
+type finishedLobby struct {
+	channel   SlackChannel
+	messageTs string
+	outcome   lobbyOutcome
 }
 
 type GameRequest struct {
-	players         []string
-	quorum          int         // number of players needed for the game
-	messageTs       string      // slack timestamp for the message of the game request sent by the bot
-	timer           *time.Timer // Timeout timer
-	timerCancelFunc context.CancelFunc
-	mu              *sync.Mutex
+	id         LobbyID
+	channel    SlackChannel
+	players    []string
+	waitlist   []string // players who joined after the game was full, they seed the next game request
+	gameType   GameType
+	quorum     int    // number of players at which the game is full and starts
+	messageTs  string // slack timestamp for the message of the game request sent by the bot
+	timeout    time.Duration
+	deadline   time.Time // point in time at which the game request times out, see timeoutJob
+	startAt    time.Time // start of a scheduled game, zero for games that start once they are full
+	announceAt time.Time // point in time at which a scheduled game is announced, zero to announce it right away
+	table      string    // name of the table the game is played on, empty if none was chosen
+	queuedAt   time.Time // when the game request was queued for its busy table, zero if it doesn't wait for it
+	createdAt  time.Time // when the game request was opened, zero for game requests persisted before it was recorded
+	closed     bool      // set once the game request is removed from the game manager
+	starting   bool      // set once the game start message was submitted, until the players were pinged
+	closing    bool      // set while the message of a cancelled or abandoned game request is replaced or deleted
+
+	// The state of a game request is owned by its goroutine, which runs the commands sent with do one after
+	// another. Other goroutines read it with a command that copies what they need, see snapshot.
+	inbox     chan func()
+	quit      chan struct{}
+	stopped   chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
 }
 
-func NewGameRequest(gameType GameType, player string) *GameRequest {
+func NewGameRequest(gameType GameType, player string, createdAt time.Time) *GameRequest {
 	return &GameRequest{
+		id:        newLobbyID(),
 		players:   []string{player},
-		quorum:    quorumMap[gameType],
+		gameType:  gameType,
+		quorum:    gameType.Format().MinPlayers,
 		messageTs: "",
-		mu:        &sync.Mutex{},
+		createdAt: createdAt,
+	}
+}
+
+// do runs the command on the goroutine of the game request and waits for it. The commands of a game request run
+// one after another, so a command sees and changes the game request without interference and the updates of its
+// message reach Slack in the order they were made. A command returns what is left to do once it finished, e.g.
+// notifying players, or nil. That runs on the caller's goroutine, so that it doesn't hold up the next command.
+// Commands must not wait for other commands of the same game request. They may wait for the commands of the game
+// manager, whose commands never wait for game requests, see GameManager.do.
+//
+// The goroutine ends once the game request is closed or stopped. Later commands run on the caller's goroutine,
+// where they find the game request closed.
+func (gameReq *GameRequest) do(cmd func() func()) {
+	gameReq.startOnce.Do(gameReq.start)
+
+	var after func()
+	done := make(chan struct{})
+	select {
+	case gameReq.inbox <- func() {
+		defer close(done)
+		after = cmd()
+	}:
+		<-done
+	case <-gameReq.stopped:
+		after = cmd()
+	}
+	if after != nil {
+		after()
+	}
+}
+
+// start starts the goroutine of the game request. It is started on the first command, so that game requests
+// are set up by their first command before other commands can reach them.
+func (gameReq *GameRequest) start() {
+	gameReq.inbox = make(chan func())
+	gameReq.quit = make(chan struct{})
+	gameReq.stopped = make(chan struct{})
+	go func() {
+		defer close(gameReq.stopped)
+		for !gameReq.closed {
+			select {
+			case cmd := <-gameReq.inbox:
+				cmd()
+			case <-gameReq.quit:
+				gameReq.closed = true
+			}
+		}
+	}()
+}
+
+// stop closes the game request once its current command finished, e.g. on shutdown.
+func (gameReq *GameRequest) stop() {
+	gameReq.startOnce.Do(gameReq.start)
+	gameReq.stopOnce.Do(func() { close(gameReq.quit) })
+}
+
+// announced reports whether the message of the game request was posted. Only scheduled games with a lead time
+// and game requests queued for a busy table are announced later than they are created. The caller must run on the
+// goroutine of the game request.
+func (gameReq *GameRequest) announced() bool {
+	return gameReq.queuedAt.IsZero() && (gameReq.announceAt.IsZero() || gameReq.messageTs != "")
+}
+
+// record returns a snapshot of the game request that can be persisted in a GameStore.
+// The caller must run on the goroutine of the game request.
+func (gameReq *GameRequest) record() GameRequestRecord {
+	return GameRequestRecord{
+		ID:         gameReq.id,
+		Channel:    gameReq.channel,
+		GameType:   gameReq.gameType,
+		Players:    slices.Clone(gameReq.players),
+		Waitlist:   slices.Clone(gameReq.waitlist),
+		Quorum:     gameReq.quorum,
+		MessageTs:  gameReq.messageTs,
+		Timeout:    gameReq.timeout,
+		Deadline:   gameReq.deadline,
+		StartAt:    gameReq.startAt,
+		AnnounceAt: gameReq.announceAt,
+		Table:      gameReq.table,
+		QueuedAt:   gameReq.queuedAt,
+		CreatedAt:  gameReq.createdAt,
+	}
+}
+
+// lobbySnapshot is a copy of the state of a game request for readers on other goroutines, see snapshot.
+type lobbySnapshot struct {
+	creator   string
+	messageTs string
+	announced bool
+	queuedAt  time.Time
+	closed    bool
+}
+
+// snapshot copies the state of the game request on its goroutine.
+func (gameReq *GameRequest) snapshot() lobbySnapshot {
+	var snapshot lobbySnapshot
+	gameReq.do(func() func() {
+		snapshot = lobbySnapshot{messageTs: gameReq.messageTs, announced: gameReq.announced(), queuedAt: gameReq.queuedAt, closed: gameReq.closed}
+		if len(gameReq.players) > 0 {
+			snapshot.creator = gameReq.players[0]
+		}
+		return nil
+	})
+	return snapshot
+}
+
+// gameRequestFromRecord rebuilds a game request from its persisted snapshot.
+func gameRequestFromRecord(record GameRequestRecord) *GameRequest {
+	return &GameRequest{
+		id:         record.ID,
+		channel:    record.Channel,
+		players:    slices.Clone(record.Players),
+		waitlist:   slices.Clone(record.Waitlist),
+		gameType:   record.GameType,
+		quorum:     record.Quorum,
+		messageTs:  record.MessageTs,
+		timeout:    record.Timeout,
+		deadline:   record.Deadline,
+		startAt:    record.StartAt,
+		announceAt: record.AnnounceAt,
+		table:      record.Table,
+		queuedAt:   record.QueuedAt,
+		createdAt:  record.CreatedAt,
 	}
 }
@@ -2,13 +2,14 @@ package main
 
 import (
 	"testing"
+	"time"
 )
 
 func TestNewGameRequest(t *testing.T) {
 	player := "Player1"
 
 	// Test for 2 vs 2 Game
-	gameReq1 := NewGameRequest(GameTypeTwoVsTwo, player)
+	gameReq1 := NewGameRequest(GameTypeTwoVsTwo, player, time.Now())
 	if gameReq1.quorum != 4 {
 		t.Errorf("Expected quorum of 4 for TwoVsTwoGame, got %d", gameReq1.quorum)
 	}
@@ -17,7 +18,7 @@ func TestNewGameRequest(t *testing.T) {
 	}
 
 	// Test for 1 vs 1 Game
-	gameReq2 := NewGameRequest(GameTypeOneVsOne, player)
+	gameReq2 := NewGameRequest(GameTypeOneVsOne, player, time.Now())
 	if gameReq2.quorum != 2 {
 		t.Errorf("Expected quorum of 2 for OneVsOneGame, got %d", gameReq2.quorum)
 	}
@@ -2,16 +2,27 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/slack-go/slack"
 )
 
-func handleSlackCommand(gm *GameManager) http.HandlerFunc {
+// errInvalidRequest is returned for slash commands and interactions the bot doesn't know.
+var errInvalidRequest = errors.New("invalid request")
+
+// handleSlackCommand acknowledges slash commands right away and runs them on the command pool of their workspace,
+// see queueCommand. Deliveries that Slack retried (X-Slack-Retry-Num) are dropped if the command was already
+// received, so that a slow acknowledgement doesn't start a game twice.
+func handleSlackCommand(ws *Workspaces) http.HandlerFunc {
+	dedup := newEventDeduplicator(eventDedupWindow)
 	return func(w http.ResponseWriter, r *http.Request) {
 		cmd, err := slack.SlashCommandParse(r)
 		if err != nil {
@@ -20,23 +31,71 @@ func handleSlackCommand(gm *GameManager) http.HandlerFunc {
 			return
 		}
 
-		switch cmd.Command {
-		case CMD_START_ROUND:
-			var gameOptions = parseFlags(cmd.Text)
-			gm.CreateGame(SlackChannel(cmd.ChannelID), cmd.UserID, gameOptions)
-		case CMD_CANCEL_ROUND:
-			gm.CancelGame(SlackChannel(cmd.ChannelID), cmd.UserID)
-		default:
+		gm, err := ws.Manager(Workspace{EnterpriseID: cmd.EnterpriseID, TeamID: cmd.TeamID})
+		if err != nil {
+			slog.Warn("Received a command from another workspace", "error", err)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if retry := r.Header.Get("X-Slack-Retry-Num"); !dedup.firstDelivery(commandID(cmd), time.Now()) && retry != "" {
+			slog.Info("Ignoring a retried command", "command", cmd.Command, "retry", retry)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		switch err := queueCommand(gm, cmd); {
+		case errors.Is(err, errCommandsBusy):
+			slog.Warn("Turning away a command, the queue is full", "command", cmd.Command)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(commandBusyMsg(gm, cmd))
+			return
+		case err != nil:
 			slog.Warn("Recieved an invalid command", "command", cmd.Command, "sender", r.RemoteAddr)
 			w.WriteHeader(http.StatusBadRequest)
 			return
-
 		}
 		w.WriteHeader(http.StatusOK)
 	}
 }
 
-func handleSlackEvent(gm *GameManager) http.HandlerFunc {
+// dispatchCommand runs a slash command on the command pool, see queueCommand. Invalid parameters are answered
+// through the response_url of the command.
+func dispatchCommand(gm *GameManager, cmd slack.SlashCommand) error {
+	// the command names are configurable, so they are looked up in the current settings
+	settings := gm.Settings()
+	switch cmd.Command {
+	case settings.Commands.Start:
+		var gameOptions = parseGameFlags(cmd.Text, settings.Channel(SlackChannel(cmd.ChannelID)), gm.localNow())
+		if gameOptions.err != nil {
+			gm.respond(SlackChannel(cmd.ChannelID), cmd.UserID, cmd.ResponseURL, msgInvalidGameOptions, gameOptions.err, settings.Commands.Start)
+			break
+		}
+		gameOptions.responseURL = cmd.ResponseURL
+		gm.CreateGame(SlackChannel(cmd.ChannelID), cmd.UserID, gameOptions)
+	case settings.Commands.Cancel:
+		gm.CancelGame(SlackChannel(cmd.ChannelID), cmd.UserID, LobbyID(strings.TrimSpace(cmd.Text)))
+	case settings.Commands.Stats:
+		statsOptions := parseStatsFlags(cmd.Text)
+		if statsOptions.err != nil {
+			gm.respond(SlackChannel(cmd.ChannelID), cmd.UserID, cmd.ResponseURL, msgInvalidStatsOptions, statsOptions.err, settings.Commands.Stats)
+			break
+		}
+		gm.PostStats(SlackChannel(cmd.ChannelID), cmd.UserID, statsOptions)
+	case settings.Commands.Position:
+		position, ok := ParsePosition(cmd.Text)
+		if !ok {
+			gm.respond(SlackChannel(cmd.ChannelID), cmd.UserID, cmd.ResponseURL, msgInvalidPosition, settings.Commands.Position)
+			break
+		}
+		gm.SetPosition(SlackChannel(cmd.ChannelID), cmd.UserID, position)
+	default:
+		return errInvalidRequest
+	}
+	return nil
+}
+
+// handleSlackInteraction handles the interactivity payloads Slack posts for clicks on buttons and submissions of
+// modals, sent as form with a JSON payload.
+func handleSlackInteraction(ws *Workspaces) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		var interactionCallback slack.InteractionCallback
@@ -47,57 +106,336 @@ func handleSlackEvent(gm *GameManager) http.HandlerFunc {
 			return
 		}
 
-		actions := interactionCallback.ActionCallback.BlockActions
-		if len(actions) < 1 {
-			slog.Warn("Invalid or empty block action callback", "payload", interactionCallback)
-			w.WriteHeader(http.StatusBadRequest)
+		gm, err := ws.Manager(interactionWorkspace(interactionCallback))
+		if err != nil {
+			slog.Warn("Received an interaction from another workspace", "error", err)
+			w.WriteHeader(http.StatusForbidden)
 			return
 		}
-		channel := SlackChannel(interactionCallback.Channel.ID)
-		player := interactionCallback.User.ID
-		switch actions[0].ActionID {
-		case ACTION_JOIN_ROUND:
-			gm.JoinGame(channel, player)
-		case ACTION_LEAVE_ROUND:
-			gm.LeaveGame(channel, player)
-		default:
-			slog.Warn("Invalid Action Id", "actionId", interactionCallback.ActionID, "sender", r.RemoteAddr)
+		response, err := dispatchInteraction(gm, interactionCallback)
+		if err != nil {
+			slog.Warn("Invalid interaction", "error", err, "sender", r.RemoteAddr)
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
+		if response != nil {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(response)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 	}
 }
 
-func parseFlags(params string) GameOpts {
-	var timeout time.Duration
-	var duel bool
+// interactionWorkspace returns the workspace that owns the modal or the message the user interacted with. In shared
+// channels the user may belong to another workspace than the lobby, so the team of the user is only the fallback.
+func interactionWorkspace(interactionCallback slack.InteractionCallback) Workspace {
+	team := interactionCallback.View.TeamID
+	if team == "" {
+		team = interactionCallback.Message.Team
+	}
+	if team == "" {
+		team = interactionCallback.Team.ID
+	}
+	return Workspace{EnterpriseID: interactionCallback.Enterprise.ID, TeamID: team}
+}
+
+// dispatchInteraction runs a click on a button or the submission of a modal, no matter whether it arrived over
+// HTTP or Socket Mode. Submissions with validation errors return the response that keeps the modal open.
+func dispatchInteraction(gm *GameManager, interactionCallback slack.InteractionCallback) (*slack.ViewSubmissionResponse, error) {
+	if interactionCallback.Type == slack.InteractionTypeViewSubmission {
+		return handleViewSubmission(gm, interactionCallback)
+	}
+
+	actions := interactionCallback.ActionCallback.BlockActions
+	if len(actions) < 1 {
+		return nil, fmt.Errorf("%w: empty block action callback", errInvalidRequest)
+	}
+	channel := SlackChannel(interactionCallback.Channel.ID)
+	player := interactionCallback.User.ID
+	switch actions[0].ActionID {
+	case ACTION_JOIN_ROUND:
+		gm.JoinGame(channel, actionLobby(gm, channel, interactionCallback), player)
+	case ACTION_LEAVE_ROUND:
+		gm.LeaveGame(channel, actionLobby(gm, channel, interactionCallback), player)
+	case ACTION_EXTEND_ROUND:
+		gm.ExtendGame(channel, LobbyID(actions[0].Value), player)
+	case ACTION_QUEUE_TABLE:
+		// the button carries the parameters of the start command that queue the game request
+		gameOptions := parseGameFlags(actions[0].Value, gm.Settings().Channel(channel), gm.localNow())
+		if gameOptions.err != nil {
+			return nil, fmt.Errorf("%w: queue parameters %q: %w", errInvalidRequest, actions[0].Value, gameOptions.err)
+		}
+		gm.CreateGame(channel, player, gameOptions)
+	case ACTION_RECORD_RESULT:
+		gm.OpenResultForm(channel, actions[0].Value, player, interactionCallback.TriggerID)
+	case ACTION_SHUFFLE_TEAMS:
+		gm.ShuffleTeams(channel, actions[0].Value, player)
+	default:
+		return nil, fmt.Errorf("%w: action id %q", errInvalidRequest, actions[0].ActionID)
+	}
+	return nil, nil
+}
+
+// actionLobby returns the lobby ID carried by the value of a join or leave button. Messages posted before the
+// buttons carried lobby IDs have the action ID as value, their lobby is looked up by the message timestamp.
+func actionLobby(gm *GameManager, channel SlackChannel, interactionCallback slack.InteractionCallback) LobbyID {
+	action := interactionCallback.ActionCallback.BlockActions[0]
+	if action.Value == "" || action.Value == action.ActionID {
+		return gm.LobbyByMessage(channel, interactionCallback.Container.MessageTs)
+	}
+	return LobbyID(action.Value)
+}
+
+// handleViewSubmission handles the submission of modals. Validation errors are sent back to Slack so that
+// they are shown next to the offending inputs and the modal stays open.
+func handleViewSubmission(gm *GameManager, interactionCallback slack.InteractionCallback) (*slack.ViewSubmissionResponse, error) {
+	view := interactionCallback.View
+	if view.CallbackID != VIEW_RECORD_RESULT {
+		return nil, fmt.Errorf("%w: view callback id %q", errInvalidRequest, view.CallbackID)
+	}
+
+	if view.State == nil {
+		return nil, fmt.Errorf("%w: view submission %q without state", errInvalidRequest, view.CallbackID)
+	}
+
+	values := view.State.Values
+	result := MatchResult{
+		TeamOne: values[resultBlockTeam][resultActionTeam].SelectedUsers,
+		Score: [2]int{
+			parseScore(values[resultBlockScore1][resultActionScore].Value),
+			parseScore(values[resultBlockScore2][resultActionScore].Value),
+		},
+		Winner: -1,
+	}
+	if winner, err := strconv.Atoi(values[resultBlockWinner][resultActionWinner].SelectedOption.Value); err == nil {
+		result.Winner = winner
+	}
+
+	if errs := gm.RecordResult(view.PrivateMetadata, interactionCallback.User.ID, result); errs != nil {
+		return slack.NewErrorsViewSubmissionResponse(errs), nil
+	}
+	return nil, nil
+}
+
+// maxScheduleAhead is how far in the future a game can be scheduled.
+const maxScheduleAhead = 24 * time.Hour
+
+// parseGameFlags parses the parameters of the /kicker command. The timeout and game type default to the settings
+// of the channel and only the game types allowed in the channel can be started. The game type is picked by the name
+// of its format (--format 2v1), by the number of players (--players 3) or as 1v1 with --duel. Given both a format
+// and a number of players, the game request waits for that many players. A start time given with --at is the next
+// occurrence of that time of day after now. The table given with --table is looked up when the game is created.
+func parseGameFlags(params string, settings ChannelSettings, now time.Time) GameOpts {
+	var timeout, in, lead time.Duration
+	var at, format, table string
+	var duel, queue bool
+	var players int
 
 	flagSet := flag.NewFlagSet("gameParameters", flag.ContinueOnError)
-	flagSet.DurationVar(&timeout, "timeout", time.Minute*30, "")
+	flagSet.DurationVar(&timeout, "timeout", settings.Timeout, "")
 	flagSet.DurationVar(&timeout, "t", timeout, "")
 	flagSet.BoolVar(&duel, "duel", false, "")
 	flagSet.BoolVar(&duel, "d", duel, "")
+	flagSet.StringVar(&format, "format", "", "")
+	flagSet.StringVar(&format, "f", format, "")
+	flagSet.IntVar(&players, "players", 0, "")
+	flagSet.IntVar(&players, "p", players, "")
+	flagSet.StringVar(&at, "at", "", "")
+	flagSet.DurationVar(&in, "in", 0, "")
+	flagSet.DurationVar(&lead, "lead", 0, "")
+	flagSet.StringVar(&table, "table", "", "")
+	flagSet.BoolVar(&queue, "queue", false, "")
 	err := flagSet.Parse(strings.Fields(params))
 
 	if err != nil {
-		slog.Error("error parsing flags in game request", err)
+		slog.Error("error parsing flags in game request", "error", err)
 	}
 
-	var gameType GameType
-	if duel {
-		gameType = GameTypeOneVsOne
-	} else {
-		gameType = GameTypeTwoVsTwo
+	gameType := settings.GameType
+	if err == nil {
+		gameType, err = selectGameType(settings.GameType, format, duel, players)
+	}
+	if err == nil && !slices.Contains(settings.AllowedGameTypes, gameType) {
+		err = fmt.Errorf("%s games are not allowed in this channel", gameType)
 	}
 
-	return GameOpts{
+	gameOptions := GameOpts{
 		timeout:  timeout,
 		gameType: gameType,
+		players:  players,
+		table:    table,
+		queue:    queue,
+		err:      err,
+	}
+	if err == nil {
+		gameOptions.startAt, gameOptions.err = parseStart(at, in, now)
+	}
+	switch {
+	case gameOptions.err != nil || lead == 0:
+	case lead < 0:
+		gameOptions.err = fmt.Errorf("invalid lead time %s", lead)
+	case gameOptions.startAt.IsZero():
+		gameOptions.err = errors.New("--lead needs a start time given with --at or --in")
+	default:
+		gameOptions.lead = lead
+	}
+	switch {
+	case gameOptions.err != nil || !queue:
+	case table == "":
+		gameOptions.err = errors.New("--queue needs a table given with --table")
+	case !gameOptions.startAt.IsZero():
+		gameOptions.err = errors.New("--queue can't be combined with a start time")
+	}
+	return gameOptions
+}
+
+// selectGameType returns the game type picked by the --format, --duel and --players flags, falling back to the
+// default game type of the channel. The number of players must fit the picked format.
+func selectGameType(defaultType GameType, format string, duel bool, players int) (GameType, error) {
+	gameType := defaultType
+	switch {
+	case format != "" && duel:
+		return gameType, errors.New("--format and --duel can't be combined")
+	case format != "":
+		var ok bool
+		if gameType, ok = ParseGameType(format); !ok {
+			return gameType, fmt.Errorf("unknown format %q, expected one of %s", format, gameTypeNames())
+		}
+	case duel:
+		gameType = GameTypeOneVsOne
+	case players != 0:
+		var ok bool
+		if gameType, ok = gameTypeForPlayers(players); !ok {
+			return gameType, fmt.Errorf("no format for %d players", players)
+		}
+	}
+	if players != 0 {
+		if err := gameType.Format().checkPlayers(players); err != nil {
+			return gameType, err
+		}
 	}
+	return gameType, nil
+}
+
+// parseStart returns the start time of a scheduled game given as time of day (12:30) or as duration from now (45m).
+// The start time is zero if neither is given.
+func parseStart(at string, in time.Duration, now time.Time) (time.Time, error) {
+	var startAt time.Time
+	switch {
+	case at != "" && in != 0:
+		return time.Time{}, errors.New("--at and --in can't be combined")
+	case at != "":
+		clock, err := time.ParseInLocation("15:04", at, now.Location())
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid start time %q", at)
+		}
+		startAt = time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+		if !startAt.After(now) {
+			startAt = startAt.AddDate(0, 0, 1)
+		}
+	case in < 0:
+		return time.Time{}, fmt.Errorf("invalid start in %s", in)
+	case in > 0:
+		startAt = now.Add(in)
+	}
+	if startAt.After(now.Add(maxScheduleAhead)) {
+		return time.Time{}, fmt.Errorf("start time %s is more than %s ahead", startAt.Format(time.DateTime), maxScheduleAhead)
+	}
+	return startAt, nil
 }
 
 type GameOpts struct {
-	timeout  time.Duration
-	gameType GameType
+	timeout     time.Duration
+	gameType    GameType
+	players     int           // number of players the game request waits for, zero for the minimum of its format
+	startAt     time.Time     // start of a scheduled game, zero for games that start once they are full
+	lead        time.Duration // how long before the start a scheduled game is announced, zero to announce it right away
+	table       string        // name of the table the game is played on, empty if none was chosen
+	queue       bool          // wait until the table is free instead of giving up if it is busy
+	err         error         // set if the parameters are invalid
+	responseURL string        // of the slash command, errors are answered through it; empty for buttons
+}
+
+// queueParams returns the parameters of the start command that queue the game request for its busy table, or an
+// empty string if the game request can't be queued because it is scheduled.
+func (gameOptions GameOpts) queueParams() string {
+	if gameOptions.table == "" || !gameOptions.startAt.IsZero() {
+		return ""
+	}
+	params := fmt.Sprintf("--format %s --timeout %s --table %s --queue", gameOptions.gameType, gameOptions.timeout, gameOptions.table)
+	if gameOptions.players > 0 {
+		params += fmt.Sprintf(" --players %d", gameOptions.players)
+	}
+	return params
+}
+
+func parseStatsFlags(params string) StatsOpts {
+	var since time.Duration
+	var format string
+	var duel, workspace, public bool
+
+	sinceFlag := func(value string) error {
+		d, err := parseSince(value)
+		since = d
+		return err
+	}
+
+	flagSet := flag.NewFlagSet("statsParameters", flag.ContinueOnError)
+	flagSet.Func("since", "", sinceFlag)
+	flagSet.Func("s", "", sinceFlag)
+	flagSet.BoolVar(&duel, "duel", false, "")
+	flagSet.BoolVar(&duel, "d", duel, "")
+	flagSet.StringVar(&format, "format", "", "")
+	flagSet.StringVar(&format, "f", format, "")
+	flagSet.BoolVar(&workspace, "workspace", false, "")
+	flagSet.BoolVar(&workspace, "w", workspace, "")
+	flagSet.BoolVar(&public, "public", false, "")
+	err := flagSet.Parse(strings.Fields(params))
+
+	var gameType GameType
+	switch parsed, ok := ParseGameType(format); {
+	case ok:
+		gameType = parsed
+	case format != "":
+		if err == nil {
+			err = fmt.Errorf("unknown format %q", format)
+		}
+		gameType = GameTypeTwoVsTwo
+	case duel:
+		gameType = GameTypeOneVsOne
+	default:
+		gameType = GameTypeTwoVsTwo
+	}
+
+	return StatsOpts{
+		gameType:  gameType,
+		since:     since,
+		workspace: workspace,
+		public:    public,
+		err:       err,
+	}
+}
+
+// parseSince parses the time window of the leaderboard. In addition to Go durations it accepts days (30d)
+// and weeks (2w), which are far more common for statistics.
+func parseSince(value string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, found := strings.CutSuffix(value, suffix); found {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid time window %q", value)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	return time.ParseDuration(value)
+}
+
+type StatsOpts struct {
+	gameType  GameType
+	since     time.Duration // only matches within this window are counted, zero counts all matches
+	workspace bool          // count the matches of all channels instead of only the current one
+	public    bool          // post the leaderboard to the channel instead of only to the requester
+	err       error         // set if the parameters are invalid
 }
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -14,6 +16,11 @@ import (
 	gomock "go.uber.org/mock/gomock"
 )
 
+// parseFlags parses the parameters of the /kicker command at the current time.
+func parseFlags(params string, settings ChannelSettings) GameOpts {
+	return parseGameFlags(params, settings, time.Now())
+}
+
 func TestSlashCommandHandlerWithValidCommands(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -94,7 +101,8 @@ func TestSlashCommandHandlerWithValidCommands(t *testing.T) {
 			req := httptest.NewRequest(http.MethodPost, "/commands", strings.NewReader(formData.Encode()))
 			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-			handleSlackCommand(gameMgr)(rr, req)
+			handleSlackCommand(SingleWorkspace(gameMgr))(rr, req)
+			gameMgr.commands.flush(context.TODO())
 
 			if rr.Result().StatusCode != tc.expectedStatus {
 				t.Errorf("Status code returned, %d, did not match expected code %d", rr.Result().StatusCode, http.StatusOK)
@@ -162,7 +170,7 @@ func TestSlashCommandHandlerWithInvalidCommands(t *testing.T) {
 			req := httptest.NewRequest(http.MethodPost, "/commands", strings.NewReader(formData.Encode()))
 			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-			handleSlackCommand(gameMgr)(rr, req)
+			handleSlackCommand(SingleWorkspace(gameMgr))(rr, req)
 
 			if rr.Result().StatusCode != http.StatusBadRequest {
 				t.Errorf("Status code returned, %d, did not match expected code %d", rr.Result().StatusCode, http.StatusBadRequest)
@@ -267,12 +275,12 @@ func TestSlackInteractionCallbackHandler(t *testing.T) {
 				form.Set("payload", string(payload))
 				requestBody = strings.NewReader(form.Encode())
 			}
-			req := httptest.NewRequest(http.MethodPost, "/events", requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/interactions", requestBody)
 			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 			rr := httptest.NewRecorder()
 
-			handleSlackEvent(gameMgr)(rr, req)
+			handleSlackInteraction(SingleWorkspace(gameMgr))(rr, req)
 
 			if rr.Result().StatusCode != tc.expectHTTPCode {
 				t.Errorf("Status code returned, %d, did not match expected code %d", rr.Result().StatusCode, tc.expectHTTPCode)
@@ -319,7 +327,7 @@ func TestParsingGameOptionFlags(t *testing.T) {
 	// Loop through each test case
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			var gameOptions = parseFlags(tc.inputParams)
+			var gameOptions = parseFlags(tc.inputParams, DefaultSettings().Defaults)
 
 			if gameOptions.gameType != tc.gameType {
 				t.Errorf("Parameters' and GameOptions' GameType doesn't match")
@@ -330,3 +338,392 @@ func TestParsingGameOptionFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestResultViewSubmissionHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+
+	match := newMatchRecord("test-channel", GameTypeOneVsOne, []string{"p1", "p2"}, "ts", time.Now())
+	gameMgr.matches[match.ID] = match
+
+	// Only the valid submission updates the game message
+	mockSlackClient.EXPECT().
+		UpdateMessage("test-channel", "ts", gomock.Any()).
+		Return("test-channel", "ts", "text", nil).Times(1)
+
+	submit := func(score1, score2, winner string) *httptest.ResponseRecorder {
+		callback := slack.InteractionCallback{Type: slack.InteractionTypeViewSubmission}
+		callback.User.ID = "p1"
+		callback.View.CallbackID = VIEW_RECORD_RESULT
+		callback.View.PrivateMetadata = match.ID
+		callback.View.State = &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+			resultBlockTeam:   {resultActionTeam: {SelectedUsers: []string{"p2"}}},
+			resultBlockScore1: {resultActionScore: {Value: score1}},
+			resultBlockScore2: {resultActionScore: {Value: score2}},
+			resultBlockWinner: {resultActionWinner: {SelectedOption: slack.OptionBlockObject{Value: winner}}},
+		}}
+		payload, _ := json.Marshal(callback)
+		form := url.Values{}
+		form.Set("payload", string(payload))
+
+		req := httptest.NewRequest(http.MethodPost, "/interactions", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		handleSlackInteraction(SingleWorkspace(gameMgr))(rr, req)
+		return rr
+	}
+
+	rr := submit("10", "10", "0")
+	var response slack.ViewSubmissionResponse
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Expected a view submission response for an invalid result: %v", err)
+	}
+	if response.ResponseAction != slack.RAErrors || response.Errors[resultBlockScore2] == "" {
+		t.Errorf("Expected an error for the score, got %+v", response)
+	}
+
+	rr = submit("4", "10", "1")
+	if rr.Result().StatusCode != http.StatusOK || rr.Body.Len() != 0 {
+		t.Errorf("Expected an empty 200 response for a valid result, got %d %q", rr.Result().StatusCode, rr.Body.String())
+	}
+	if recorded := gameMgr.matches[match.ID]; !recorded.IsRecorded() || recorded.Teams[0][0] != "p2" {
+		t.Errorf("Expected the result to be recorded with p2 in team 1, got %+v", recorded)
+	}
+}
+
+func TestParsingStatsFlags(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		inputParams string
+		expected    StatsOpts
+	}{
+		{
+			name:        "no params 2v2 all time in channel and ephemeral",
+			inputParams: "",
+			expected:    StatsOpts{gameType: GameTypeTwoVsTwo},
+		},
+		{
+			name:        "duel for the last 30 days",
+			inputParams: "--duel --since 30d",
+			expected:    StatsOpts{gameType: GameTypeOneVsOne, since: 30 * 24 * time.Hour},
+		},
+		{
+			name:        "short flags",
+			inputParams: "-d -s 2w -w --public",
+			expected:    StatsOpts{gameType: GameTypeOneVsOne, since: 14 * 24 * time.Hour, workspace: true, public: true},
+		},
+		{
+			name:        "go duration window for the whole workspace",
+			inputParams: "--since 12h --workspace --public",
+			expected:    StatsOpts{gameType: GameTypeTwoVsTwo, since: 12 * time.Hour, workspace: true, public: true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if statsOptions := parseStatsFlags(tc.inputParams); statsOptions != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, statsOptions)
+			}
+		})
+	}
+
+	for _, params := range []string{"--since dreißigd", "--format tischtennis", "--verbose", "-p"} {
+		if statsOptions := parseStatsFlags(params); statsOptions.err == nil {
+			t.Errorf("Expected the parameters %q to be rejected", params)
+		}
+	}
+
+	if _, err := parseSince("dreißigd"); err == nil {
+		t.Error("Expected an invalid time window to be rejected")
+	}
+}
+
+func TestStatsCommandHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+
+	// The ephemeral leaderboard is only shown to the requester, the public one is posted to the channel
+	mockSlackClient.EXPECT().
+		PostEphemeral("test-channel", "test-user", gomock.Any()).
+		Return("timestamp", nil).Times(1)
+	mockSlackClient.EXPECT().
+		PostMessage("test-channel", gomock.Any()).
+		Return("test-channel", "timestamp", nil).Times(1)
+
+	for _, params := range []string{"", "--public"} {
+		formData := url.Values{
+			"channel_id": {"test-channel"},
+			"user_id":    {"test-user"},
+			"command":    {CMD_STATS},
+			"text":       {params},
+		}
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/commands", strings.NewReader(formData.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		handleSlackCommand(SingleWorkspace(gameMgr))(rr, req)
+		gameMgr.commands.flush(context.TODO())
+
+		if rr.Result().StatusCode != http.StatusOK {
+			t.Errorf("Status code returned, %d, did not match expected code %d", rr.Result().StatusCode, http.StatusOK)
+		}
+	}
+}
+
+// TestInteractionRoutesByLobbyID verifies that a click on a join button reaches the game request whose lobby ID
+// the button carries, even if several game requests are open in the channel.
+func TestInteractionRoutesByLobbyID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+
+	channelID := "test-channel"
+	channel := SlackChannel(channelID)
+
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "first-ts", nil).Times(1)
+	mockSlackClient.EXPECT().
+		PostMessage(channelID, gomock.Any()).
+		Return(channelID, "second-ts", nil).Times(1)
+	mockSlackClient.EXPECT().
+		UpdateMessage(channelID, "second-ts", gomock.Any()).
+		Return(channelID, "second-ts", "text", nil).Times(1)
+
+	gameOptions := GameOpts{timeout: time.Minute * 30, gameType: GameTypeTwoVsTwo}
+	gameMgr.CreateGame(channel, "p1", gameOptions)
+	gameMgr.CreateGame(channel, "p2", gameOptions)
+	second := gameMgr.LobbyByMessage(channel, "second-ts")
+
+	callback := slack.InteractionCallback{}
+	callback.Channel.ID = channelID
+	callback.User.ID = "p3"
+	callback.Container.MessageTs = "first-ts"
+	callback.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: ACTION_JOIN_ROUND, Value: string(second)}}
+	payload, _ := json.Marshal(callback)
+	form := url.Values{}
+	form.Set("payload", string(payload))
+	req := httptest.NewRequest(http.MethodPost, "/interactions", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rr := httptest.NewRecorder()
+	handleSlackInteraction(SingleWorkspace(gameMgr))(rr, req)
+
+	if rr.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Result().StatusCode)
+	}
+	gameReq, _ := gameMgr.getGameRequest(channel, second)
+	inspectLobby(gameReq, func() {
+		if !slices.Equal(gameReq.players, []string{"p2", "p3"}) {
+			t.Errorf("Expected p3 to join the game request of the button, found %v", gameReq.players)
+		}
+	})
+}
+
+func TestParsingScheduleFlags(t *testing.T) {
+	now := time.Date(2026, time.October, 17, 10, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name        string
+		inputParams string
+		startAt     time.Time
+		lead        time.Duration
+		invalid     bool
+	}{
+		{name: "no start time", inputParams: "-d", startAt: time.Time{}},
+		{name: "later today", inputParams: "--at 12:30", startAt: now.Add(2*time.Hour + 30*time.Minute)},
+		{name: "passed time is tomorrow", inputParams: "--at 09:00", startAt: now.Add(23 * time.Hour)},
+		{name: "relative start", inputParams: "--in 45m", startAt: now.Add(45 * time.Minute)},
+		{name: "lead time", inputParams: "--in 1h --lead 15m", startAt: now.Add(time.Hour), lead: 15 * time.Minute},
+		{name: "invalid time of day", inputParams: "--at 25:00", invalid: true},
+		{name: "at and in combined", inputParams: "--at 12:30 --in 5m", invalid: true},
+		{name: "too far ahead", inputParams: "--in 48h", invalid: true},
+		{name: "lead without start", inputParams: "--lead 10m", invalid: true},
+		{name: "unknown flag", inputParams: "--tomorrow", invalid: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gameOptions := parseGameFlags(tc.inputParams, DefaultSettings().Defaults, now)
+
+			if tc.invalid {
+				if gameOptions.err == nil {
+					t.Errorf("Expected an error for %q", tc.inputParams)
+				}
+				return
+			}
+			if gameOptions.err != nil {
+				t.Fatalf("Unexpected error for %q: %v", tc.inputParams, gameOptions.err)
+			}
+			if !gameOptions.startAt.Equal(tc.startAt) {
+				t.Errorf("Expected start %v, got %v", tc.startAt, gameOptions.startAt)
+			}
+			if gameOptions.lead != tc.lead {
+				t.Errorf("Expected lead %v, got %v", tc.lead, gameOptions.lead)
+			}
+		})
+	}
+}
+
+// TestScheduleInTimezone verifies that start times are given and shown in the time zone of the settings, not in
+// the one of the server.
+func TestScheduleInTimezone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockSlackClient := NewMockSlackClient(ctrl)
+	clock := newFakeClock() // 12:00 UTC, 13:00 in Berlin
+	settings := DefaultSettings()
+	settings.Timezone = Timezone{location: berlin}
+	gameMgr := NewGameManager(mockSlackClient, WithSettings(settings), WithClock(clock))
+	defer gameMgr.Shutdown(context.TODO())
+
+	var announcement slack.MsgOption
+	mockSlackClient.EXPECT().
+		PostMessage("C1", gomock.Any()).
+		DoAndReturn(func(channelID string, options ...slack.MsgOption) (string, string, error) {
+			announcement = options[0]
+			return channelID, "ts", nil
+		}).Times(1)
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), "C1", "ts").
+		Return("C1", "ts", nil).Times(1)
+
+	gameOptions := parseGameFlags("--at 13:30", settings.Defaults, gameMgr.localNow())
+	if expected := time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC); !gameOptions.startAt.Equal(expected) {
+		t.Fatalf("Expected --at 13:30 to start at %v, got %v", expected, gameOptions.startAt)
+	}
+	gameMgr.CreateGame("C1", "p1", gameOptions)
+	if text := msgBlocks(t, announcement); !strings.Contains(text, "13:30 Uhr") {
+		t.Errorf("Expected the start time in the time zone of the settings, got %s", text)
+	}
+
+	// shortly after midnight in Berlin it is still the day before in UTC
+	now := time.Date(2024, time.March, 1, 23, 30, 0, 0, time.UTC).In(berlin)
+	if text := clockTime(LanguageEnglish, time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC), now); text != "01:00" {
+		t.Errorf("Expected the time of today in Berlin, got %q", text)
+	}
+}
+
+func TestSlashCommandsFollowReloadedSettings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+
+	command := func(name string) int {
+		formData := url.Values{
+			"channel_id": {"test-channel"},
+			"user_id":    {"test-user"},
+			"command":    {name},
+			"text":       {""},
+		}
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/commands", strings.NewReader(formData.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		handleSlackCommand(SingleWorkspace(gameMgr))(rr, req)
+		gameMgr.commands.flush(context.TODO())
+		return rr.Result().StatusCode
+	}
+
+	settings := DefaultSettings()
+	settings.Commands.Cancel = "/foosball-cancel"
+	gameMgr.ApplySettings(settings)
+
+	// the old name is unknown after the reload, the new one cancels
+	if status := command(CMD_CANCEL_ROUND); status != http.StatusBadRequest {
+		t.Errorf("Expected the old command name to be rejected, got status %d", status)
+	}
+	mockSlackClient.EXPECT().PostEphemeral("test-channel", "test-user", gomock.Any()).Times(1)
+	if status := command("/foosball-cancel"); status != http.StatusOK {
+		t.Errorf("Expected the new command name to be handled, got status %d", status)
+	}
+}
+
+func TestParsingFormatFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		inputParams string
+		gameType    GameType
+		players     int
+		valid       bool
+	}{
+		{name: "players pick the format", inputParams: "--players 3", gameType: GameTypeTwoVsOne, players: 3, valid: true},
+		{name: "players of a range", inputParams: "-p 7", gameType: GameTypeRundlauf, players: 7, valid: true},
+		{name: "format by name", inputParams: "--format rundlauf", gameType: GameTypeRundlauf, valid: true},
+		{name: "format and players", inputParams: "-f rundlauf -p 6", gameType: GameTypeRundlauf, players: 6, valid: true},
+		{name: "players outside of the format", inputParams: "--format 2v2 --players 3"},
+		{name: "players without format", inputParams: "--players 9"},
+		{name: "unknown format", inputParams: "--format 3v3"},
+		{name: "format and duel", inputParams: "--format 2v1 --duel"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gameOptions := parseFlags(tc.inputParams, DefaultSettings().Defaults)
+			if !tc.valid {
+				if gameOptions.err == nil {
+					t.Errorf("Expected an error, got %+v", gameOptions)
+				}
+				return
+			}
+			if gameOptions.err != nil || gameOptions.gameType != tc.gameType || gameOptions.players != tc.players {
+				t.Errorf("Expected %s with %d players, got %+v", tc.gameType, tc.players, gameOptions)
+			}
+		})
+	}
+}
+
+func TestParsingTableFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		inputParams string
+		table       string
+		queue       bool
+		valid       bool
+	}{
+		{name: "table", inputParams: "--table dach", table: "dach", valid: true},
+		{name: "queue for a table", inputParams: "--table dach --queue", table: "dach", queue: true, valid: true},
+		{name: "queue without table", inputParams: "--queue"},
+		{name: "queue for a scheduled game", inputParams: "--table dach --queue --in 30m"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gameOptions := parseFlags(tc.inputParams, DefaultSettings().Defaults)
+			if !tc.valid {
+				if gameOptions.err == nil {
+					t.Errorf("Expected an error, got %+v", gameOptions)
+				}
+				return
+			}
+			if gameOptions.err != nil || gameOptions.table != tc.table || gameOptions.queue != tc.queue {
+				t.Errorf("Expected table %q with queue %t, got %+v", tc.table, tc.queue, gameOptions)
+			}
+		})
+	}
+
+	// the queue button repeats the game options
+	gameOptions := parseFlags("-f rundlauf -p 6 --timeout 10m --table dach", DefaultSettings().Defaults)
+	queued := parseFlags(gameOptions.queueParams(), DefaultSettings().Defaults)
+	if queued.err != nil || !queued.queue || queued.gameType != GameTypeRundlauf || queued.players != 6 || queued.timeout != 10*time.Minute || queued.table != "dach" {
+		t.Errorf("Expected the queue parameters to repeat the game options, got %+v", queued)
+	}
+	if scheduled := parseFlags("--table dach --in 30m", DefaultSettings().Defaults); scheduled.queueParams() != "" {
+		t.Errorf("Expected scheduled games not to be queued, got %q", scheduled.queueParams())
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// readyTimeout bounds the checks of a readiness probe, which usually gives up after a few seconds.
+const readyTimeout = 3 * time.Second
+
+// handleHealthz answers the liveness probe: the bot is alive as long as its HTTP server answers.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("ok\n"))
+}
+
+// handleReadyz answers the readiness probe, see Workspaces.Ready. Failed checks are answered with 503 and the
+// reason, so that the bot gets no traffic until Slack and the store are reachable again.
+func handleReadyz(ws *Workspaces) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/plain")
+		if err := ws.Ready(ctx); err != nil {
+			slog.Warn("Readiness check failed", "error", err)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(err.Error() + "\n"))
+			return
+		}
+		w.Write([]byte("ready\n"))
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/mock/gomock"
+)
+
+// TestProbesWithoutSignature verifies that the probes are answered without a Slack signature, while the Slack
+// endpoints still require one.
+func TestProbesWithoutSignature(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	mockSlackClient.EXPECT().
+		AuthTestContext(gomock.Any()).
+		Return(&slack.AuthTestResponse{TeamID: "T1"}, nil).Times(1)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+	handler := newServer("4000", SingleWorkspace(gameMgr), "secret", nil, true).Handler
+
+	for _, tc := range []struct {
+		path           string
+		expectedStatus int
+	}{
+		{"/healthz", http.StatusOK},
+		{"/readyz", http.StatusOK},
+		{"/commands", http.StatusUnauthorized},
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rr.Code != tc.expectedStatus {
+			t.Errorf("Expected status %d for %s, got %d", tc.expectedStatus, tc.path, rr.Code)
+		}
+	}
+}
+
+// TestReadiness verifies that the bot is not ready if Slack rejects its token, if its store can't be written or
+// if its timeouts are no longer handled.
+func TestReadiness(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "kickbot.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := newFakeClock()
+	gameMgr := NewGameManager(mockSlackClient, WithGameStore(store), WithClock(clock))
+
+	mockSlackClient.EXPECT().
+		AuthTestContext(gomock.Any()).
+		Return(nil, errors.New("invalid_auth")).Times(1)
+	if err := gameMgr.Ready(context.TODO()); err == nil {
+		t.Error("Expected a rejected token to fail the readiness check")
+	}
+
+	mockSlackClient.EXPECT().
+		AuthTestContext(gomock.Any()).
+		Return(&slack.AuthTestResponse{TeamID: "T1"}, nil).Times(1)
+	if err := gameMgr.Ready(context.TODO()); err != nil {
+		t.Errorf("Expected the game manager to be ready, got %v", err)
+	}
+
+	// the accepted token is trusted for a while, the store is checked every time
+	path := store.file.path
+	store.file.path = filepath.Join(t.TempDir(), "missing", "kickbot.json")
+	if err := gameMgr.Ready(context.TODO()); err == nil {
+		t.Error("Expected an unwritable store to fail the readiness check")
+	}
+	store.file.path = path
+
+	// the token is tested again once the interval passed on the clock of the game manager
+	clock.Advance(authTestInterval + time.Second)
+	mockSlackClient.EXPECT().
+		AuthTestContext(gomock.Any()).
+		Return(nil, errors.New("token_revoked")).Times(1)
+	if err := gameMgr.Ready(context.TODO()); err == nil {
+		t.Error("Expected a revoked token to fail the readiness check")
+	}
+	mockSlackClient.EXPECT().
+		AuthTestContext(gomock.Any()).
+		Return(&slack.AuthTestResponse{TeamID: "T1"}, nil).Times(1)
+	if err := gameMgr.Ready(context.TODO()); err != nil {
+		t.Errorf("Expected the game manager to be ready again, got %v", err)
+	}
+
+	gameMgr.Shutdown(context.TODO())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := gameMgr.Ready(ctx); err == nil {
+		t.Error("Expected the readiness check to fail once the timeouts are no longer handled")
+	}
+}
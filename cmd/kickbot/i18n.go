@@ -0,0 +1,516 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Language selects the bundle of the message catalog the bot talks in.
+type Language string
+
+const (
+	LanguageGerman  Language = "de"
+	LanguageEnglish Language = "en"
+)
+
+// DefaultLanguage is used for all messages unless a channel or user language is configured.
+const DefaultLanguage = LanguageGerman
+
+// userLocaleTTL is how long the Slack locale of a user is cached before it is looked up again.
+const userLocaleTTL = 24 * time.Hour
+
+// ParseLanguage parses a language code or a Slack locale such as "en-US". It reports false for languages
+// without a bundle in the catalog.
+func ParseLanguage(value string) (Language, bool) {
+	code, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(value)), "-")
+	lang := Language(code)
+	if _, exists := catalog[lang]; !exists {
+		return "", false
+	}
+	return lang, true
+}
+
+// ParseChannelLanguages parses per channel languages given as comma separated list of channel=language pairs,
+// e.g. "C0123=en,C0456=de".
+func ParseChannelLanguages(value string) (map[SlackChannel]Language, error) {
+	languages := make(map[SlackChannel]Language)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		channel, code, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(channel) == "" {
+			return nil, fmt.Errorf("invalid channel language %q, expected channel=language", pair)
+		}
+		lang, ok := ParseLanguage(code)
+		if !ok {
+			return nil, fmt.Errorf("unknown language %q for channel %s", code, channel)
+		}
+		languages[SlackChannel(strings.TrimSpace(channel))] = lang
+	}
+	return languages, nil
+}
+
+// userLocale is a cached language of a user's Slack locale.
+type userLocale struct {
+	lang      Language // empty if the locale has no bundle in the catalog
+	fetchedAt time.Time
+}
+
+// channelLanguage returns the language of the messages posted to the channel.
+func (gameMgr *GameManager) channelLanguage(channel SlackChannel) Language {
+	return gameMgr.Settings().Channel(channel).Language
+}
+
+// userLanguage returns the language of the messages that only the user sees in the channel. The locale of the user
+// is looked up in Slack if enabled, users whose locale isn't in the catalog get the language of the channel.
+func (gameMgr *GameManager) userLanguage(channel SlackChannel, user string) Language {
+	if !gameMgr.Settings().UserLocale {
+		return gameMgr.channelLanguage(channel)
+	}
+
+	gameMgr.localesMu.Lock()
+	locale, cached := gameMgr.locales[user]
+	gameMgr.localesMu.Unlock()
+
+	if !cached || gameMgr.clock.Now().Sub(locale.fetchedAt) > userLocaleTTL {
+		info, err := gameMgr.apiClient.GetUserInfo(user)
+		if err != nil {
+			slog.Warn("Failed to look up user locale", "user", user, "error", err)
+			return gameMgr.channelLanguage(channel)
+		}
+		locale = userLocale{fetchedAt: gameMgr.clock.Now()}
+		locale.lang, _ = ParseLanguage(info.Locale)
+		gameMgr.localesMu.Lock()
+		gameMgr.locales[user] = locale
+		gameMgr.localesMu.Unlock()
+	}
+
+	if locale.lang == "" {
+		return gameMgr.channelLanguage(channel)
+	}
+	return locale.lang
+}
+
+// pruneLocales drops the cached locales that expired, so that the cache doesn't keep the users who no longer play.
+func (gameMgr *GameManager) pruneLocales() {
+	now := gameMgr.clock.Now()
+	gameMgr.localesMu.Lock()
+	defer gameMgr.localesMu.Unlock()
+
+	for user, locale := range gameMgr.locales {
+		if now.Sub(locale.fetchedAt) > userLocaleTTL {
+			delete(gameMgr.locales, user)
+		}
+	}
+}
+
+// notify sends a message of the catalog to the player as ephemeral message in the player's language.
+func (gameMgr *GameManager) notify(channel SlackChannel, player string, key MessageKey, args ...any) (string, error) {
+	text := gameMgr.userLanguage(channel, player).Text(key, args...)
+	return gameMgr.apiClient.PostEphemeral(string(channel), player, slack.MsgOptionText(text, false))
+}
+
+// respond answers a slash command with a message that only the player sees. It goes through the response_url of
+// the command, which doesn't depend on the bot token and the rate limits of the Web API, so that the answer reaches
+// the player while the API is slow. Without a response_url, e.g. for clicks on buttons, the player is notified.
+func (gameMgr *GameManager) respond(channel SlackChannel, player, responseURL string, key MessageKey, args ...any) {
+	if responseURL == "" {
+		gameMgr.notify(channel, player, key, args...)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), responseTimeout)
+	defer cancel()
+	msg := &slack.WebhookMessage{
+		ResponseType: slack.ResponseTypeEphemeral,
+		Text:         gameMgr.userLanguage(channel, player).Text(key, args...),
+	}
+	if err := slack.PostWebhookContext(ctx, responseURL, msg); err != nil {
+		slog.Warn("Failed to respond through the response_url, notifying the player instead", "error", err)
+		gameMgr.notify(channel, player, key, args...)
+	}
+}
+
+// reply returns the notification of the player for a command of a game request to send once it finished, see do.
+func (gameMgr *GameManager) reply(channel SlackChannel, player string, key MessageKey, args ...any) func() {
+	return func() {
+		gameMgr.notify(channel, player, key, args...)
+	}
+}
+
+// Text renders the message with the given key in the language. Messages missing in the bundle of the language
+// are rendered in the DefaultLanguage.
+func (lang Language) Text(key MessageKey, args ...any) string {
+	template, exists := catalog[lang][key]
+	if !exists {
+		template, exists = catalog[DefaultLanguage][key]
+	}
+	if !exists {
+		return string(key)
+	}
+	if len(args) == 0 {
+		return template
+	}
+	return fmt.Sprintf(template, args...)
+}
+
+// Render renders a message whose language wasn't known when it was created.
+func (lang Language) Render(msg Message) string {
+	return lang.Text(msg.Key, msg.Args...)
+}
+
+// Message is a message of the catalog along with its arguments, for messages that are built before the language
+// of their recipient is known.
+type Message struct {
+	Key  MessageKey
+	Args []any
+}
+
+func newMessage(key MessageKey, args ...any) Message {
+	return Message{Key: key, Args: args}
+}
+
+// MessageKey identifies a message in the catalog.
+type MessageKey string
+
+const (
+	msgJoinButton          MessageKey = "join_button"
+	msgLeaveButton         MessageKey = "leave_button"
+	msgLeaveConfirmTitle   MessageKey = "leave_confirm_title"
+	msgLeaveConfirmText    MessageKey = "leave_confirm_text"
+	msgLeaveConfirmYes     MessageKey = "leave_confirm_yes"
+	msgLeaveConfirmNo      MessageKey = "leave_confirm_no"
+	msgExtendButton        MessageKey = "extend_button"
+	msgLobbyContext        MessageKey = "lobby_context"
+	msgLobbyTable          MessageKey = "lobby_table"
+	msgNewDuel             MessageKey = "new_duel"
+	msgNewGame             MessageKey = "new_game"
+	msgNewHandicap         MessageKey = "new_handicap"
+	msgNewRundlauf         MessageKey = "new_rundlauf"
+	msgPlayersNeeded       MessageKey = "players_needed"
+	msgPlayersOptional     MessageKey = "players_optional"
+	msgTimeoutWarning      MessageKey = "timeout_warning"
+	msgSeeded              MessageKey = "seeded"
+	msgSeededFull          MessageKey = "seeded_full"
+	msgWaitlisted          MessageKey = "waitlisted"
+	msgScheduleHint        MessageKey = "schedule_hint"
+	msgClockToday          MessageKey = "clock_today"
+	msgClockOtherDay       MessageKey = "clock_other_day"
+	msgScheduledConfirm    MessageKey = "scheduled_confirm"
+	msgReminder            MessageKey = "reminder"
+	msgGameReady           MessageKey = "game_ready"
+	msgGameReadyScheduled  MessageKey = "game_ready_scheduled"
+	msgRecordResultButton  MessageKey = "record_result_button"
+	msgTeam                MessageKey = "team"
+	msgTableOrder          MessageKey = "table_order"
+	msgMatchTable          MessageKey = "match_table"
+	msgShuffleButton       MessageKey = "shuffle_button"
+	msgPositionAny         MessageKey = "position_any"
+	msgPositionDefense     MessageKey = "position_defense"
+	msgPositionAttack      MessageKey = "position_attack"
+	msgInvalidGameOptions  MessageKey = "invalid_game_options"
+	msgInvalidPosition     MessageKey = "invalid_position"
+	msgInvalidStatsOptions MessageKey = "invalid_stats_options"
+	msgMentionHelp         MessageKey = "mention_help"
+	msgWelcome             MessageKey = "welcome"
+	msgMatchResult         MessageKey = "match_result"
+	msgRecordedBy          MessageKey = "recorded_by"
+	msgResultTitle         MessageKey = "result_title"
+	msgResultSave          MessageKey = "result_save"
+	msgResultClose         MessageKey = "result_close"
+	msgResultPlayers       MessageKey = "result_players"
+	msgResultTeamHint      MessageKey = "result_team_hint"
+	msgTeamOne             MessageKey = "team_one"
+	msgTeamTwo             MessageKey = "team_two"
+	msgGoalsTeamOne        MessageKey = "goals_team_one"
+	msgGoalsTeamTwo        MessageKey = "goals_team_two"
+	msgWinner              MessageKey = "winner"
+	msgLeaderboardHeader   MessageKey = "leaderboard_header"
+	msgLeaderboardChannel  MessageKey = "leaderboard_channel"
+	msgLeaderboardAll      MessageKey = "leaderboard_workspace"
+	msgLeaderboardAllTime  MessageKey = "leaderboard_all_time"
+	msgLeaderboardSince    MessageKey = "leaderboard_since"
+	msgLeaderboardLastDay  MessageKey = "leaderboard_last_day"
+	msgLeaderboardLastDays MessageKey = "leaderboard_last_days"
+	msgLeaderboardEmpty    MessageKey = "leaderboard_empty"
+	msgLeaderboardLine     MessageKey = "leaderboard_line"
+	msgLobbyStarted        MessageKey = "lobby_started"
+	msgLobbyCancelled      MessageKey = "lobby_cancelled"
+	msgLobbyExpired        MessageKey = "lobby_expired"
+	msgLobbyAbandoned      MessageKey = "lobby_abandoned"
+	msgLobbyUnknown        MessageKey = "lobby_unknown"
+	msgLobbyClosing        MessageKey = "lobby_closing"
+	msgCancelled           MessageKey = "cancelled"
+	msgTimedOut            MessageKey = "timed_out"
+	msgError               MessageKey = "error"
+	msgCommandsBusy        MessageKey = "commands_busy"
+	msgInOtherLobby        MessageKey = "in_other_lobby"
+	msgLobbyLimit          MessageKey = "lobby_limit"
+	msgAnnounceFailed      MessageKey = "announce_failed"
+	msgTableBusy           MessageKey = "table_busy"
+	msgTableQueueOffer     MessageKey = "table_queue_offer"
+	msgQueueButton         MessageKey = "queue_button"
+	msgQueued              MessageKey = "queued"
+	msgTableFree           MessageKey = "table_free"
+	msgUnknownTable        MessageKey = "unknown_table"
+	msgNoTables            MessageKey = "no_tables"
+	msgExtendCreatorOnly   MessageKey = "extend_creator_only"
+	msgExtendScheduled     MessageKey = "extend_scheduled"
+	msgExtended            MessageKey = "extended"
+	msgNoActiveGame        MessageKey = "no_active_game"
+	msgCancelCreatorOnly   MessageKey = "cancel_creator_only"
+	msgCancelFailed        MessageKey = "cancel_failed"
+	msgAlreadyJoined       MessageKey = "already_joined"
+	msgAlreadyWaitlisted   MessageKey = "already_waitlisted"
+	msgJoinFailed          MessageKey = "join_failed"
+	msgGameFull            MessageKey = "game_full"
+	msgGameFullScheduled   MessageKey = "game_full_scheduled"
+	msgPromoted            MessageKey = "promoted"
+	msgLeftWaitlist        MessageKey = "left_waitlist"
+	msgNotInGame           MessageKey = "not_in_game"
+	msgLeaveFull           MessageKey = "leave_full"
+	msgLeaveFailed         MessageKey = "leave_failed"
+	msgUnknownMatch        MessageKey = "unknown_match"
+	msgResultPlayersOnly   MessageKey = "result_players_only"
+	msgResultRecorded      MessageKey = "result_recorded"
+	msgShufflePlayersOnly  MessageKey = "shuffle_players_only"
+	msgTeamSize            MessageKey = "team_size"
+	msgTeamForeignPlayer   MessageKey = "team_foreign_player"
+	msgInvalidScore        MessageKey = "invalid_score"
+	msgDraw                MessageKey = "draw"
+	msgNoWinner            MessageKey = "no_winner"
+	msgWinnerGoals         MessageKey = "winner_goals"
+	msgNoResult            MessageKey = "no_result"
+)
+
+// catalog holds the bundles of all supported languages. Every bundle must have the same keys and the same
+// format verbs, which TestCatalogBundlesAreComplete makes sure of.
+var catalog = map[Language]map[MessageKey]string{
+	LanguageGerman: {
+		msgJoinButton:         "Bin dabei!",
+		msgLeaveButton:        "Bin raus!",
+		msgLeaveConfirmTitle:  "Bist du sicher?",
+		msgLeaveConfirmText:   "Möchtest du wirklich das Spiel verlassen?",
+		msgLeaveConfirmYes:    "Nu",
+		msgLeaveConfirmNo:     "Nä",
+		msgExtendButton:       "+%d Min",
+		msgLobbyContext:       "Runde `%[1]s` · Abbrechen mit `%[2]s %[1]s`",
+		msgLobbyTable:         " · Tisch *%s*",
+		msgNewDuel:            "<@%[1]s> sucht einen Herausforderer für ein 1v1 Kicker-Duell. Wer traut sich",
+		msgNewGame:            "<@%[1]s> hat Bock auf Kicker! Wer macht mit? Noch %[2]d Leute gesucht!",
+		msgNewHandicap:        "<@%[1]s> sucht Leute für ein 2v1 Handicap-Spiel, einer spielt allein gegen zwei. Noch %[2]d Leute gesucht!",
+		msgNewRundlauf:        "<@%[1]s> lädt zum Rundlauf ein! Noch %[2]d Leute gesucht, bis zu %[3]d können mitspielen.",
+		msgPlayersNeeded:      "%s sind dabei. Noch %d Spieler gesucht!",
+		msgPlayersOptional:    "%s sind dabei, das reicht für eine Runde! Bis zum Anpfiff können noch %d Spieler dazukommen.",
+		msgTimeoutWarning:     ":hourglass_flowing_sand: Die Runde läuft in %d Min ab, wenn sich nicht noch %d Spieler finden.",
+		msgSeeded:             "Die nächste Runde steht schon: %s",
+		msgSeededFull:         "Die nächste Runde steht schon: %s sind dabei.",
+		msgWaitlisted:         "Das Spiel ist bereits voll. Du stehst auf Platz %d der Warteliste und bist in der nächsten Runde dabei.",
+		msgScheduleHint:       "\n:alarm_clock: Anpfiff um *%s*, ihr könnt euch schon jetzt eintragen.",
+		msgClockToday:         "15:04 Uhr",
+		msgClockOtherDay:      "02.01. 15:04 Uhr",
+		msgScheduledConfirm:   "Deine Runde um %s ist geplant und wird um %s angekündigt.",
+		msgReminder:           ":alarm_clock: %s, um %s geht's los. Ab zum Kickertisch! :kicker:",
+		msgGameReady:          "%s sind bereit. Los geht's!",
+		msgGameReadyScheduled: "%s sind dabei. Anpfiff um %s!",
+		msgRecordResultButton: "Ergebnis eintragen",
+		msgTeam:               "*Team %d:* %s",
+		msgTableOrder:         "Reihenfolge am Tisch: %s",
+		msgMatchTable:         ":round_pushpin: Tisch *%s*",
+		msgShuffleButton:      "Neu mischen",
+		msgPositionAny:        "Alles klar, du spielst auf jeder Position.",
+		msgPositionDefense:    "Alles klar, du wirst bevorzugt in der Abwehr :shield: eingeteilt.",
+		msgPositionAttack:     "Alles klar, du wirst bevorzugt im Sturm :zap: eingeteilt.",
+		msgInvalidGameOptions: "Ungültige Parameter (%[1]s). Beispiele: `%[2]s`, `%[2]s --duel`, `%[2]s --players 3`, " +
+			"`%[2]s --format rundlauf --players 6`, `%[2]s --timeout 45m`, `%[2]s --at 12:30`, `%[2]s --in 45m --lead 15m`.",
+		msgInvalidPosition: "Unbekannte Position. Benutze `%[1]s abwehr`, `%[1]s sturm` oder `%[1]s egal`.",
+		msgInvalidStatsOptions: "Ungültige Parameter (%[1]s). Beispiele: `%[2]s`, `%[2]s --duel`, `%[2]s --since 30d`, " +
+			"`%[2]s --format 1v1 --workspace`, `%[2]s --public`.",
+		msgMentionHelp:         "Starte eine Runde mit `%s`, brich sie mit `%s` ab, schau dir die Rangliste mit `%s` an und wähle deine Lieblingsposition mit `%s`.",
+		msgWelcome:             "Willkommen! Hier wird Kicker gespielt, starte eine Runde mit `%s`. :soccer:",
+		msgMatchResult:         "Ergebnis: %s *%d : %d* %s\n:trophy: Glückwunsch %s!",
+		msgRecordedBy:          "Eingetragen von <@%s>",
+		msgResultTitle:         "Ergebnis eintragen",
+		msgResultSave:          "Speichern",
+		msgResultClose:         "Abbrechen",
+		msgResultPlayers:       "Spieler: %s",
+		msgResultTeamHint:      "Alle anderen Spieler bilden Team 2.",
+		msgTeamOne:             "Team 1",
+		msgTeamTwo:             "Team 2",
+		msgGoalsTeamOne:        "Tore Team 1",
+		msgGoalsTeamTwo:        "Tore Team 2",
+		msgWinner:              "Gewinner",
+		msgLeaderboardHeader:   ":trophy: *Kicker-Rangliste %s* (%s, %s)",
+		msgLeaderboardChannel:  "dieser Kanal",
+		msgLeaderboardAll:      "alle Kanäle",
+		msgLeaderboardAllTime:  "seit Beginn",
+		msgLeaderboardSince:    "seit %s",
+		msgLeaderboardLastDay:  "letzter Tag",
+		msgLeaderboardLastDays: "letzte %d Tage",
+		msgLeaderboardEmpty:    "Noch keine Ergebnisse eingetragen.",
+		msgLeaderboardLine:     "%d. <@%s> – *%.0f* Punkte, %dS/%dN, Siegesserie: %s",
+		msgLobbyStarted:        "Diese Runde ist schon voll und hat begonnen.",
+		msgLobbyCancelled:      "Diese Runde wurde abgebrochen.",
+		msgLobbyExpired:        "Diese Runde ist abgelaufen.",
+		msgLobbyAbandoned:      "Diese Runde wurde aufgelöst, weil alle Spieler raus sind.",
+		msgLobbyUnknown:        "Diese Runde gibt es nicht mehr.",
+		msgLobbyClosing:        "Diese Runde wird gerade beendet.",
+		msgCancelled:           "Die Runde wurde abgebrochen.",
+		msgTimedOut:            "Die Kicker-Runde ist abgelaufen. Nicht genug Spieler gefunden.",
+		msgError:               "Ein Fehler ist aufgetreten!",
+		msgCommandsBusy:        "Gerade ist viel los, bitte versuche es gleich noch einmal.",
+		msgInOtherLobby:        "Du bist bereits in einer anderen Runde in diesem Kanal.",
+		msgLobbyLimit:          "Es werden bereits %d Runden vorbereitet!",
+		msgAnnounceFailed:      "Deine Runde konnte nicht angekündigt werden.",
+		msgTableBusy:           "Am Tisch *%s* wird gerade gespielt, voraussichtlich bis %s.",
+		msgTableQueueOffer:     "Soll deine Runde angekündigt werden, sobald der Tisch frei ist?",
+		msgQueueButton:         "Anstellen",
+		msgQueued:              "Deine Runde wird angekündigt, sobald der Tisch *%[1]s* frei ist. Du bist Nummer %[2]d in der Schlange.",
+		msgTableFree:           "Der Tisch *%s* ist frei, deine Runde wurde angekündigt.",
+		msgUnknownTable:        "Den Tisch `%s` gibt es nicht. Verfügbare Tische: %s",
+		msgNoTables:            "Es sind keine Tische eingerichtet.",
+		msgExtendCreatorOnly:   "Nur der Ersteller der Runde kann sie verlängern.",
+		msgExtendScheduled:     "Geplante Runden starten zur geplanten Zeit und können nicht verlängert werden.",
+		msgExtended:            "Die Runde läuft jetzt bis %s.",
+		msgNoActiveGame:        "Kein Spiel ist derzeit aktiv.",
+		msgCancelCreatorOnly:   "Nur der Ersteller des Spiels kann es abbrechen.",
+		msgCancelFailed:        "Die Runde konnte wegen eines technischen Problems nicht abgebrochen werden.",
+		msgAlreadyJoined:       "Du bist bereits im Spiel.",
+		msgAlreadyWaitlisted:   "Du stehst bereits auf der Warteliste.",
+		msgJoinFailed:          "Es gab ein technisches Problem beim Beitritt zum Spiel.",
+		msgGameFull:            "Die Runde ist voll, %s zum Kickertisch! :kicker:",
+		msgGameFullScheduled:   "Die Runde ist voll, %s seid um %s dabei! :kicker:",
+		msgPromoted:            "Du warst auf der Warteliste und bist jetzt in der nächsten Runde dabei!",
+		msgLeftWaitlist:        "Du hast die Warteliste verlassen.",
+		msgNotInGame:           "Du bist nicht in der aktuellen Runde.",
+		msgLeaveFull:           "Die Runde ist bereits voll und geht gleich los.",
+		msgLeaveFailed:         "Es gab ein technisches Problem beim Verlassen des Spiels, du bist noch dabei.",
+		msgUnknownMatch:        "Dieses Spiel ist nicht bekannt.",
+		msgResultPlayersOnly:   "Nur Spieler der Runde können das Ergebnis eintragen.",
+		msgResultRecorded:      "Das Ergebnis wurde bereits eingetragen.",
+		msgShufflePlayersOnly:  "Nur Spieler der Runde können die Teams neu mischen.",
+		msgTeamSize:            "Team 1 muss aus %d Spieler(n) bestehen.",
+		msgTeamForeignPlayer:   "Team 1 darf nur Spieler dieser Runde enthalten.",
+		msgInvalidScore:        "Bitte gib eine gültige Toranzahl ein.",
+		msgDraw:                "Beim Kicker gibt es kein Unentschieden.",
+		msgNoWinner:            "Bitte wähle den Gewinner aus.",
+		msgWinnerGoals:         "Der Gewinner muss mehr Tore haben.",
+		msgNoResult:            "Für Spiele im Format %s wird kein Ergebnis eingetragen.",
+	},
+	LanguageEnglish: {
+		msgJoinButton:         "I'm in!",
+		msgLeaveButton:        "I'm out!",
+		msgLeaveConfirmTitle:  "Are you sure?",
+		msgLeaveConfirmText:   "Do you really want to leave the game?",
+		msgLeaveConfirmYes:    "Yes",
+		msgLeaveConfirmNo:     "No",
+		msgExtendButton:       "+%d min",
+		msgLobbyContext:       "Game `%[1]s` · Cancel with `%[2]s %[1]s`",
+		msgLobbyTable:         " · Table *%s*",
+		msgNewDuel:            "<@%[1]s> is looking for a challenger for a 1v1 foosball duel. Who dares?",
+		msgNewGame:            "<@%[1]s> is up for foosball! Who's in? %[2]d more players needed!",
+		msgNewHandicap:        "<@%[1]s> is up for a 2v1 handicap game, one of you plays alone against two. %[2]d more players needed!",
+		msgNewRundlauf:        "<@%[1]s> invites you to a Rundlauf around the table! %[2]d more players needed, up to %[3]d can play.",
+		msgPlayersNeeded:      "%s are in. %d more players needed!",
+		msgPlayersOptional:    "%s are in, that's enough for a game! Up to %d more players can join before kick-off.",
+		msgTimeoutWarning:     ":hourglass_flowing_sand: The game expires in %d min unless %d more players join.",
+		msgSeeded:             "The next game is already forming: %s",
+		msgSeededFull:         "The next game is already set: %s are in.",
+		msgWaitlisted:         "The game is already full. You are number %d on the waitlist and will be in the next game.",
+		msgScheduleHint:       "\n:alarm_clock: Kick-off at *%s*, you can sign up right away.",
+		msgClockToday:         "15:04",
+		msgClockOtherDay:      "Jan 2, 15:04",
+		msgScheduledConfirm:   "Your game at %s is scheduled and will be announced at %s.",
+		msgReminder:           ":alarm_clock: %s, kick-off is at %s. Off to the table! :kicker:",
+		msgGameReady:          "%s are ready. Let's go!",
+		msgGameReadyScheduled: "%s are in. Kick-off at %s!",
+		msgRecordResultButton: "Enter result",
+		msgTeam:               "*Team %d:* %s",
+		msgTableOrder:         "Order around the table: %s",
+		msgMatchTable:         ":round_pushpin: Table *%s*",
+		msgShuffleButton:      "Reshuffle",
+		msgPositionAny:        "Got it, you play any position.",
+		msgPositionDefense:    "Got it, you'll preferably play defense :shield:.",
+		msgPositionAttack:     "Got it, you'll preferably play attack :zap:.",
+		msgInvalidGameOptions: "Invalid parameters (%[1]s). Examples: `%[2]s`, `%[2]s --duel`, `%[2]s --players 3`, " +
+			"`%[2]s --format rundlauf --players 6`, `%[2]s --timeout 45m`, `%[2]s --at 12:30`, `%[2]s --in 45m --lead 15m`.",
+		msgInvalidPosition: "Unknown position. Use `%[1]s defense`, `%[1]s attack` or `%[1]s any`.",
+		msgInvalidStatsOptions: "Invalid parameters (%[1]s). Examples: `%[2]s`, `%[2]s --duel`, `%[2]s --since 30d`, " +
+			"`%[2]s --format 1v1 --workspace`, `%[2]s --public`.",
+		msgMentionHelp:         "Start a game with `%s`, cancel it with `%s`, see the leaderboard with `%s` and pick your favourite position with `%s`.",
+		msgWelcome:             "Welcome! This channel plays foosball, start a game with `%s`. :soccer:",
+		msgMatchResult:         "Result: %s *%d : %d* %s\n:trophy: Congratulations %s!",
+		msgRecordedBy:          "Entered by <@%s>",
+		msgResultTitle:         "Enter result",
+		msgResultSave:          "Save",
+		msgResultClose:         "Cancel",
+		msgResultPlayers:       "Players: %s",
+		msgResultTeamHint:      "All other players form team 2.",
+		msgTeamOne:             "Team 1",
+		msgTeamTwo:             "Team 2",
+		msgGoalsTeamOne:        "Goals team 1",
+		msgGoalsTeamTwo:        "Goals team 2",
+		msgWinner:              "Winner",
+		msgLeaderboardHeader:   ":trophy: *Foosball leaderboard %s* (%s, %s)",
+		msgLeaderboardChannel:  "this channel",
+		msgLeaderboardAll:      "all channels",
+		msgLeaderboardAllTime:  "all time",
+		msgLeaderboardSince:    "last %s",
+		msgLeaderboardLastDay:  "last day",
+		msgLeaderboardLastDays: "last %d days",
+		msgLeaderboardEmpty:    "No results entered yet.",
+		msgLeaderboardLine:     "%d. <@%s> – *%.0f* points, %dW/%dL, win streak: %s",
+		msgLobbyStarted:        "This game is already full and has started.",
+		msgLobbyCancelled:      "This game was cancelled.",
+		msgLobbyExpired:        "This game has expired.",
+		msgLobbyAbandoned:      "This game was closed because all players left.",
+		msgLobbyUnknown:        "This game doesn't exist anymore.",
+		msgLobbyClosing:        "This game is being closed.",
+		msgCancelled:           "The game was cancelled.",
+		msgTimedOut:            "The foosball game has expired. Not enough players found.",
+		msgError:               "Something went wrong!",
+		msgCommandsBusy:        "The bot is busy right now, please try again in a moment.",
+		msgInOtherLobby:        "You are already in another game in this channel.",
+		msgLobbyLimit:          "There are already %d games forming!",
+		msgAnnounceFailed:      "Your game couldn't be announced.",
+		msgTableBusy:           "The table *%s* is in use, probably until %s.",
+		msgTableQueueOffer:     "Should your game be announced as soon as the table is free?",
+		msgQueueButton:         "Queue",
+		msgQueued:              "Your game will be announced as soon as the table *%[1]s* is free. You are number %[2]d in the queue.",
+		msgTableFree:           "The table *%s* is free, your game was announced.",
+		msgUnknownTable:        "There is no table `%s`. Available tables: %s",
+		msgNoTables:            "No tables are set up.",
+		msgExtendCreatorOnly:   "Only the creator of the game can extend it.",
+		msgExtendScheduled:     "Scheduled games start at their scheduled time and can't be extended.",
+		msgExtended:            "The game now runs until %s.",
+		msgNoActiveGame:        "There is no active game.",
+		msgCancelCreatorOnly:   "Only the creator of the game can cancel it.",
+		msgCancelFailed:        "The game could not be cancelled because of a technical problem.",
+		msgAlreadyJoined:       "You are already in the game.",
+		msgAlreadyWaitlisted:   "You are already on the waitlist.",
+		msgJoinFailed:          "There was a technical problem joining the game.",
+		msgGameFull:            "The game is full, %s off to the table! :kicker:",
+		msgGameFullScheduled:   "The game is full, %s you're playing at %s! :kicker:",
+		msgPromoted:            "You were on the waitlist and are now in the next game!",
+		msgLeftWaitlist:        "You left the waitlist.",
+		msgNotInGame:           "You are not in this game.",
+		msgLeaveFull:           "The game is already full and about to start.",
+		msgLeaveFailed:         "There was a technical problem leaving the game, you are still in it.",
+		msgUnknownMatch:        "This game is unknown.",
+		msgResultPlayersOnly:   "Only players of the game can enter the result.",
+		msgResultRecorded:      "The result was already entered.",
+		msgShufflePlayersOnly:  "Only players of the game can reshuffle the teams.",
+		msgTeamSize:            "Team 1 must have %d player(s).",
+		msgTeamForeignPlayer:   "Team 1 may only contain players of this game.",
+		msgInvalidScore:        "Please enter a valid number of goals.",
+		msgDraw:                "There are no draws in foosball.",
+		msgNoWinner:            "Please select the winner.",
+		msgWinnerGoals:         "The winner must have scored more goals.",
+		msgNoResult:            "No result is entered for %s games.",
+	},
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/mock/gomock"
+)
+
+// msgText returns the plain text of a message as it is sent to Slack.
+func msgText(t *testing.T, msg slack.MsgOption) string {
+	t.Helper()
+	_, values, err := slack.UnsafeApplyMsgOptions("token", "channel", "https://slack.com/api/", msg)
+	if err != nil {
+		t.Fatalf("Failed to apply message options: %v", err)
+	}
+	return values.Get("text")
+}
+
+// msgBlocks returns the JSON encoded blocks of a message as they are sent to Slack.
+func msgBlocks(t *testing.T, msg slack.MsgOption) string {
+	t.Helper()
+	_, values, err := slack.UnsafeApplyMsgOptions("token", "channel", "https://slack.com/api/", msg)
+	if err != nil {
+		t.Fatalf("Failed to apply message options: %v", err)
+	}
+	return values.Get("blocks")
+}
+
+func TestCatalogBundlesAreComplete(t *testing.T) {
+	reference := catalog[DefaultLanguage]
+	for lang, bundle := range catalog {
+		for key, template := range reference {
+			translated, exists := bundle[key]
+			if !exists {
+				t.Errorf("Bundle %s misses message %s", lang, key)
+				continue
+			}
+			if strings.Count(translated, "%") != strings.Count(template, "%") {
+				t.Errorf("Message %s of bundle %s has different format verbs than the %s bundle", key, lang, DefaultLanguage)
+			}
+		}
+		for key := range bundle {
+			if _, exists := reference[key]; !exists {
+				t.Errorf("Bundle %s has message %s which the %s bundle doesn't have", lang, key, DefaultLanguage)
+			}
+		}
+	}
+}
+
+func TestParseLanguage(t *testing.T) {
+	tests := map[string]Language{
+		"de":    LanguageGerman,
+		"de-DE": LanguageGerman,
+		"en-US": LanguageEnglish,
+		" EN ":  LanguageEnglish,
+		"fr-FR": "",
+		"":      "",
+	}
+	for value, expected := range tests {
+		lang, ok := ParseLanguage(value)
+		if lang != expected || ok != (expected != "") {
+			t.Errorf("ParseLanguage(%q) = %q, %v, expected %q", value, lang, ok, expected)
+		}
+	}
+
+	channels, err := ParseChannelLanguages("C1=en, C2=de-DE,")
+	if err != nil || len(channels) != 2 || channels["C1"] != LanguageEnglish || channels["C2"] != LanguageGerman {
+		t.Errorf("Unexpected channel languages %v, %v", channels, err)
+	}
+	if _, err := ParseChannelLanguages("C1=fr"); err == nil {
+		t.Error("Expected an error for a language without bundle")
+	}
+	if _, err := ParseChannelLanguages("C1"); err == nil {
+		t.Error("Expected an error for a channel without language")
+	}
+}
+
+func TestChannelLanguage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockSlackClient := NewMockSlackClient(ctrl)
+
+	settings := DefaultSettings()
+	settings.Defaults.Language = LanguageEnglish
+	german := settings.Defaults
+	german.Language = LanguageGerman
+	settings.Channels = map[SlackChannel]ChannelSettings{"german-channel": german}
+	gameMgr := NewGameManager(mockSlackClient, WithSettings(settings))
+	defer gameMgr.Shutdown(context.TODO())
+
+	if lang := gameMgr.channelLanguage("german-channel"); lang != LanguageGerman {
+		t.Errorf("Expected the channel language to override the default language, got %s", lang)
+	}
+	if lang := gameMgr.channelLanguage("other-channel"); lang != LanguageEnglish {
+		t.Errorf("Expected the default language of the settings, got %s", lang)
+	}
+	// without user locales the users get the language of the channel, so Slack is never asked for the user's locale
+	if lang := gameMgr.userLanguage("german-channel", "user"); lang != LanguageGerman {
+		t.Errorf("Expected the channel language for the user, got %s", lang)
+	}
+
+	unconfigured := NewGameManager(mockSlackClient)
+	defer unconfigured.Shutdown(context.TODO())
+	if lang := unconfigured.channelLanguage("channel"); lang != DefaultLanguage {
+		t.Errorf("Expected the default language without configuration, got %s", lang)
+	}
+}
+
+func TestUserLocale(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockSlackClient := NewMockSlackClient(ctrl)
+
+	settings := DefaultSettings()
+	settings.UserLocale = true
+	gameMgr := NewGameManager(mockSlackClient, WithSettings(settings))
+	defer gameMgr.Shutdown(context.TODO())
+
+	// the locale of each user is looked up once and then cached
+	mockSlackClient.EXPECT().GetUserInfo("english-user").Return(&slack.User{ID: "english-user", Locale: "en-GB"}, nil).Times(1)
+	mockSlackClient.EXPECT().GetUserInfo("french-user").Return(&slack.User{ID: "french-user", Locale: "fr-FR"}, nil).Times(1)
+	mockSlackClient.EXPECT().GetUserInfo("unknown-user").Return(nil, errors.New("user_not_found")).Times(2)
+
+	var texts []string
+	mockSlackClient.EXPECT().
+		PostEphemeral("channel", gomock.Any(), gomock.Any()).
+		DoAndReturn(func(channel, user string, options ...slack.MsgOption) (string, error) {
+			texts = append(texts, msgText(t, options[0]))
+			return "ts", nil
+		}).Times(2)
+
+	for range 2 {
+		if lang := gameMgr.userLanguage("channel", "english-user"); lang != LanguageEnglish {
+			t.Errorf("Expected the language of the user's locale, got %s", lang)
+		}
+		// locales without bundle and failed lookups fall back to the channel language
+		if lang := gameMgr.userLanguage("channel", "french-user"); lang != DefaultLanguage {
+			t.Errorf("Expected the channel language for a locale without bundle, got %s", lang)
+		}
+		if lang := gameMgr.userLanguage("channel", "unknown-user"); lang != DefaultLanguage {
+			t.Errorf("Expected the channel language if the lookup fails, got %s", lang)
+		}
+	}
+
+	gameMgr.notify("channel", "english-user", msgAlreadyJoined)
+	gameMgr.notify("channel", "french-user", msgAlreadyJoined)
+	if len(texts) != 2 || texts[0] != "You are already in the game." || texts[1] != "Du bist bereits im Spiel." {
+		t.Errorf("Unexpected notifications %q", texts)
+	}
+}
+
+func TestMessagesRenderInLanguage(t *testing.T) {
+	if text := msgText(t, timeoutMSG(LanguageEnglish)); text != catalog[LanguageEnglish][msgTimedOut] {
+		t.Errorf("Unexpected english timeout message %q", text)
+	}
+	if text := msgText(t, timeoutMSG(LanguageGerman)); text != catalog[LanguageGerman][msgTimedOut] {
+		t.Errorf("Unexpected german timeout message %q", text)
+	}
+
+	_, values, err := slack.UnsafeApplyMsgOptions("token", "channel", "https://slack.com/api/",
+		GameRequestUpdateMsg(lobbyStyle{lang: LanguageEnglish}, "lobby", GameTypeTwoVsTwo, []string{"p1"}, 4, time.Time{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := values.Get("blocks")
+	for _, expected := range []string{"are in. 3 more players needed!", "I'm in!", "I'm out!", "+15 min"} {
+		if !strings.Contains(blocks, expected) {
+			t.Errorf("Expected the english game request message to contain %q, got %s", expected, blocks)
+		}
+	}
+
+	// Unknown messages render as their key instead of failing
+	if text := LanguageEnglish.Text("missing"); text != "missing" {
+		t.Errorf("Expected the key of an unknown message, got %q", text)
+	}
+}
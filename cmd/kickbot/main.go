@@ -12,10 +12,14 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // the time zones of the settings, the container image has no zoneinfo
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
 )
 
 func main() {
@@ -23,43 +27,107 @@ func main() {
 	// Environment Variables
 	token := os.Getenv("KICKBOT_TOKEN")
 	signingSecret := os.Getenv("KICKBOT_SIGNING_SECRET")
+	appToken := os.Getenv("KICKBOT_APP_TOKEN")
+	clientID := os.Getenv("KICKBOT_CLIENT_ID")
+	clientSecret := os.Getenv("KICKBOT_CLIENT_SECRET")
 	envPort := os.Getenv("KICKBOT_PORT")
+	envConfigPath := os.Getenv("KICKBOT_CONFIG")
 
 	// Flags
-	port := flag.String("port", "4000", "Define the port on which the server will listen")
+	port := flag.String("port", "", "Define the port on which the server will listen (default 4000)")
+	configPath := flag.String("config", "", "Path of the YAML configuration file, reloaded on SIGHUP")
 	flag.Parse()
-	if envPort != "" {
-		*port = envPort
+	if *configPath == "" {
+		*configPath = envConfigPath
 	}
 
-	// Game Manager
-	gameMgr := NewGameManager(slack.New(token))
-	// Routes
-	r := chi.NewRouter()
-
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.RealIP)
-	r.Use(SlackVerifyMiddleware(signingSecret))
-
-	r.HandleFunc("/commands", handleSlackCommand(gameMgr))
-	r.HandleFunc("/events", handleSlackEvent(gameMgr))
+	// Configuration, the environment variables take precedence over the flags and the configuration file
+	config, err := LoadConfig(*configPath, os.Getenv)
+	if err != nil {
+		log.Fatalf("config: %s\n", err)
+	}
+	if *port != "" && envPort == "" {
+		config.Port = *port
+	}
+	if config.SocketMode && appToken == "" {
+		log.Fatalf("config: socket mode needs the app-level token in KICKBOT_APP_TOKEN\n")
+	}
+	// with the client ID and secret of the Slack app the bot is installed in workspaces through OAuth, otherwise
+	// it serves the single workspace of the bot token
+	oauth := clientID != "" && clientSecret != ""
+	if oauth && config.SocketMode {
+		log.Fatalf("config: socket mode can't be combined with the OAuth installation, which needs the HTTP endpoints\n")
+	}
+	api := slack.New(token, slack.OptionAppLevelToken(appToken))
 
-	// Server
-	srv := &http.Server{
-		Addr:           fmt.Sprintf(":%s", *port),
-		Handler:        r,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
-		IdleTimeout:    60 * time.Second,
-		MaxHeaderBytes: 1 << 20,
+	// Game Managers
+	var store *FileStore
+	if config.StorePath != "" {
+		store, err = NewFileStore(config.StorePath)
+		if err != nil {
+			log.Fatalf("store: %s\n", err)
+		}
 	}
+	ratingConfig := DefaultRatingConfig
+	ratingConfig.DecayAfter = config.Rating.DecayAfter
+	ratingConfig.DecayRate = config.Rating.DecayRate
+	metrics := NewMetrics(prometheus.DefaultRegisterer)
+	gameMgrOpts := []GameManagerOption{WithRatingConfig(ratingConfig), WithTimeoutWarning(config.TimeoutWarning), WithMetrics(metrics)}
 
+	var workspaces *Workspaces
+	var installer *OAuthInstaller
+	if oauth {
+		var workspaceStore WorkspaceStore
+		if store != nil {
+			workspaceStore = store
+		} else {
+			slog.Warn("Without store_path the installations are lost on restart")
+		}
+		newClient := func(token string) SlackClient { return slack.New(token) }
+		workspaces = NewWorkspaces(workspaceStore, newClient, config.Settings, gameMgrOpts...)
+		if err := workspaces.Restore(); err != nil {
+			log.Fatalf("restore: %s\n", err)
+		}
+		installer = NewOAuthInstaller(clientID, clientSecret, config.OAuthRedirectURL, workspaces)
+	} else {
+		if store != nil {
+			gameMgrOpts = append(gameMgrOpts, WithGameStore(store))
+		}
+		gameMgrOpts = append(gameMgrOpts, WithSettings(config.Settings))
+		gameMgr := NewGameManager(api, gameMgrOpts...)
+		if err := gameMgr.RestoreGames(); err != nil {
+			log.Fatalf("restore: %s\n", err)
+		}
+		workspaces = SingleWorkspace(gameMgr)
+	}
 	shutdownChan := make(chan os.Signal, 1)
 	signal.Notify(shutdownChan, os.Interrupt, syscall.SIGTERM)
 
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
 	go func() {
-		slog.Info(fmt.Sprintf("Server running on port %s", *port))
+		for range reloadChan {
+			reloadConfig(workspaces, *configPath, config)
+		}
+	}()
+
+	// Slack requests arrive either over Socket Mode or at the HTTP endpoints, the metrics and probes are served
+	// over HTTP in both cases
+	socketCtx, stopSocketMode := context.WithCancel(context.Background())
+	socketDone := make(chan struct{})
+	if config.SocketMode {
+		go func() {
+			defer close(socketDone)
+			if err := runSocketMode(socketCtx, workspaces, socketmode.New(api)); err != nil {
+				log.Fatalf("socket mode: %s\n", err)
+			}
+		}()
+	} else {
+		close(socketDone)
+	}
+	srv := newServer(config.Port, workspaces, signingSecret, installer, !config.SocketMode)
+	go func() {
+		slog.Info(fmt.Sprintf("Server running on port %s", config.Port))
 		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("listen: %s\n", err)
 		}
@@ -75,7 +143,67 @@ func main() {
 		slog.Error("HTTP Server failed to shutdown gracefully", "error", err.Error())
 	}
 	slog.Info("HTTP Server successfully shutdown")
-	gameMgr.Shutdown(ctx)
-	slog.Info("Game Manager successfully shutdown")
+	stopSocketMode()
+	select {
+	case <-socketDone:
+	case <-ctx.Done():
+	}
+	workspaces.Shutdown(ctx)
+	slog.Info("Game Managers successfully shutdown")
 	slog.Info("Shutdown complete. Server exiting.")
 }
+
+// newServer returns the HTTP server of the Slack endpoints, which only accepts requests signed by Slack. With an
+// installer it also serves the pages of the OAuth installation, which are opened by users in their browser.
+// The Prometheus metrics on /metrics and the probes on /healthz and /readyz are requested without signature.
+// In Socket Mode the Slack endpoints are left out.
+func newServer(port string, workspaces *Workspaces, signingSecret string, installer *OAuthInstaller, slackEndpoints bool) *http.Server {
+	r := chi.NewRouter()
+
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+	r.Use(middleware.RealIP)
+
+	r.Handle("/metrics", promhttp.Handler())
+	r.Get("/healthz", handleHealthz)
+	r.Get("/readyz", handleReadyz(workspaces))
+	if slackEndpoints {
+		r.Group(func(r chi.Router) {
+			r.Use(SlackVerifyMiddleware(signingSecret))
+
+			r.HandleFunc("/commands", handleSlackCommand(workspaces))
+			r.HandleFunc("/events", handleSlackEvents(workspaces))
+			r.HandleFunc("/interactions", handleSlackInteraction(workspaces))
+		})
+	}
+	if installer != nil {
+		r.Get("/slack/install", installer.HandleInstall)
+		r.Get("/slack/oauth_redirect", installer.HandleRedirect)
+	}
+
+	return &http.Server{
+		Addr:           fmt.Sprintf(":%s", port),
+		Handler:        r,
+		ReadTimeout:    10 * time.Second,
+		WriteTimeout:   10 * time.Second,
+		IdleTimeout:    60 * time.Second,
+		MaxHeaderBytes: 1 << 20,
+	}
+}
+
+// reloadConfig reads the configuration file again and applies its settings to the game manager. An invalid
+// configuration is rejected as a whole and the bot keeps running with its current settings. Changes to settings
+// that are only read on startup are logged, since they need a restart.
+func reloadConfig(workspaces *Workspaces, path string, current Config) {
+	config, err := LoadConfig(path, os.Getenv)
+	if err != nil {
+		slog.Error("Failed to reload configuration, keeping the current settings", "error", err)
+		return
+	}
+	if config.StorePath != current.StorePath || config.TimeoutWarning != current.TimeoutWarning || config.Rating != current.Rating ||
+		config.OAuthRedirectURL != current.OAuthRedirectURL {
+		slog.Warn("Changes to store_path, timeout_warning, rating and oauth_redirect_url need a restart")
+	}
+	workspaces.ApplySettings(config.Settings)
+	slog.Info("Reloaded configuration", "path", path)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Block and action ids of the result modal
+const (
+	resultBlockTeam    = "RESULT_TEAM"
+	resultActionTeam   = "RESULT_TEAM_PLAYERS"
+	resultBlockScore1  = "RESULT_SCORE_1"
+	resultBlockScore2  = "RESULT_SCORE_2"
+	resultActionScore  = "RESULT_SCORE"
+	resultBlockWinner  = "RESULT_WINNER"
+	resultActionWinner = "RESULT_WINNER_TEAM"
+)
+
+// MatchRecord is a game that was played after a game request reached its quorum.
+// The record is created when the game starts and completed once a player enters the result.
+type MatchRecord struct {
+	ID         string       `json:"id"`
+	Channel    SlackChannel `json:"channel"`
+	GameType   GameType     `json:"game_type"`
+	Players    []string     `json:"players"`
+	Teams      [2][]string  `json:"teams"`
+	Score      [2]int       `json:"score"`
+	Winner     int          `json:"winner"`          // index of the winning team in Teams
+	MessageTs  string       `json:"message_ts"`      // slack timestamp of the game start message
+	Table      string       `json:"table,omitempty"` // name of the table the match is played on, empty if none was chosen
+	StartedAt  time.Time    `json:"started_at"`
+	RecordedAt time.Time    `json:"recorded_at"` // zero until the result is entered
+	RecordedBy string       `json:"recorded_by,omitempty"`
+	Team       string       `json:"team,omitempty"` // set by the store, see FileStore.Workspace
+}
+
+// MatchResult is the result of a match as entered by a player.
+type MatchResult struct {
+	TeamOne []string // players of the first team, all remaining players form the second team
+	Score   [2]int
+	Winner  int
+}
+
+func newMatchRecord(channel SlackChannel, gameType GameType, players []string, messageTs string, startedAt time.Time) MatchRecord {
+	return MatchRecord{
+		ID:        newID(),
+		Channel:   channel,
+		GameType:  gameType,
+		Players:   slices.Clone(players),
+		MessageTs: messageTs,
+		StartedAt: startedAt,
+	}
+}
+
+// IsRecorded reports whether the result of the match was entered.
+func (match MatchRecord) IsRecorded() bool {
+	return !match.RecordedAt.IsZero()
+}
+
+// HasPlayer reports whether the player took part in the match.
+func (match MatchRecord) HasPlayer(player string) bool {
+	return slices.Contains(match.Players, player)
+}
+
+// suggestedTeams returns the teams of the match or, if they aren't known yet, splits the players in join order.
+func (match MatchRecord) suggestedTeams() [2][]string {
+	if len(match.Teams[0]) > 0 {
+		return match.Teams
+	}
+	teamSize := min(match.GameType.Format().TeamSizes[0], len(match.Players))
+	return [2][]string{slices.Clone(match.Players[:teamSize]), slices.Clone(match.Players[teamSize:])}
+}
+
+// applyResult validates the result against the match and stores it in the record.
+// It returns the validation errors keyed by the block id of the offending input of the result modal.
+func (match *MatchRecord) applyResult(result MatchResult, recordedBy string, recordedAt time.Time) map[string]Message {
+	errs := make(map[string]Message)
+
+	format := match.GameType.Format()
+	if !format.HasTeams() {
+		errs[resultBlockTeam] = newMessage(msgNoResult, format.Name)
+		return errs
+	}
+	teamSize := format.TeamSizes[0]
+	if len(result.TeamOne) != teamSize {
+		errs[resultBlockTeam] = newMessage(msgTeamSize, teamSize)
+	}
+	for _, player := range result.TeamOne {
+		if !match.HasPlayer(player) {
+			errs[resultBlockTeam] = newMessage(msgTeamForeignPlayer)
+		}
+	}
+	if result.Score[0] < 0 {
+		errs[resultBlockScore1] = newMessage(msgInvalidScore)
+	}
+	if result.Score[1] < 0 {
+		errs[resultBlockScore2] = newMessage(msgInvalidScore)
+	}
+	if result.Score[0] < 0 || result.Score[1] < 0 {
+		return errs
+	}
+	if result.Score[0] == result.Score[1] {
+		errs[resultBlockScore2] = newMessage(msgDraw)
+	} else if result.Winner != 0 && result.Winner != 1 {
+		errs[resultBlockWinner] = newMessage(msgNoWinner)
+	} else if result.Score[result.Winner] < result.Score[1-result.Winner] {
+		errs[resultBlockWinner] = newMessage(msgWinnerGoals)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	teamTwo := make([]string, 0, len(match.Players)-teamSize)
+	for _, player := range match.Players {
+		if !slices.Contains(result.TeamOne, player) {
+			teamTwo = append(teamTwo, player)
+		}
+	}
+	match.Teams = [2][]string{slices.Clone(result.TeamOne), teamTwo}
+	match.Score = result.Score
+	match.Winner = result.Winner
+	match.RecordedAt = recordedAt
+	match.RecordedBy = recordedBy
+	return nil
+}
+
+// parseScore parses the number of goals entered in the result modal. Invalid input is reported as a negative score.
+func parseScore(value string) int {
+	score, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return -1
+	}
+	return score
+}
+
+// newID returns a random identifier for matches.
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
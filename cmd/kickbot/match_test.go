@@ -0,0 +1,89 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMatchApplyResult(t *testing.T) {
+	players := []string{"p1", "p2", "p3", "p4"}
+
+	tests := []struct {
+		name        string
+		result      MatchResult
+		errorBlocks []string
+	}{
+		{
+			name:   "valid result",
+			result: MatchResult{TeamOne: []string{"p1", "p3"}, Score: [2]int{10, 7}, Winner: 0},
+		},
+		{
+			name:        "team too small",
+			result:      MatchResult{TeamOne: []string{"p1"}, Score: [2]int{10, 7}, Winner: 0},
+			errorBlocks: []string{resultBlockTeam},
+		},
+		{
+			name:        "team with foreign player",
+			result:      MatchResult{TeamOne: []string{"p1", "stranger"}, Score: [2]int{10, 7}, Winner: 0},
+			errorBlocks: []string{resultBlockTeam},
+		},
+		{
+			name:        "invalid score",
+			result:      MatchResult{TeamOne: []string{"p1", "p2"}, Score: [2]int{-1, 7}, Winner: 1},
+			errorBlocks: []string{resultBlockScore1},
+		},
+		{
+			name:        "draw",
+			result:      MatchResult{TeamOne: []string{"p1", "p2"}, Score: [2]int{5, 5}, Winner: 1},
+			errorBlocks: []string{resultBlockScore2},
+		},
+		{
+			name:        "winner with less goals",
+			result:      MatchResult{TeamOne: []string{"p1", "p2"}, Score: [2]int{10, 7}, Winner: 1},
+			errorBlocks: []string{resultBlockWinner},
+		},
+		{
+			name:        "no winner",
+			result:      MatchResult{TeamOne: []string{"p1", "p2"}, Score: [2]int{10, 7}, Winner: -1},
+			errorBlocks: []string{resultBlockWinner},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			match := newMatchRecord("channel", GameTypeTwoVsTwo, players, "ts", time.Now())
+			errs := match.applyResult(tc.result, "p1", time.Now())
+
+			for _, block := range tc.errorBlocks {
+				if _, ok := errs[block]; !ok {
+					t.Errorf("Expected an error for block %s, got %v", block, errs)
+				}
+			}
+			if len(errs) != len(tc.errorBlocks) {
+				t.Errorf("Expected %d errors, got %v", len(tc.errorBlocks), errs)
+			}
+			if len(tc.errorBlocks) > 0 {
+				if match.IsRecorded() {
+					t.Error("Invalid result must not be recorded")
+				}
+				return
+			}
+
+			if !match.IsRecorded() || match.RecordedBy != "p1" {
+				t.Errorf("Expected match to be recorded by p1, got %+v", match)
+			}
+			if !slices.Equal(match.Teams[0], []string{"p1", "p3"}) || !slices.Equal(match.Teams[1], []string{"p2", "p4"}) {
+				t.Errorf("Unexpected teams %v", match.Teams)
+			}
+		})
+	}
+}
+
+func TestParseScore(t *testing.T) {
+	for input, expected := range map[string]int{"10": 10, " 7 ": 7, "0": 0, "": -1, "zehn": -1} {
+		if score := parseScore(input); score != expected {
+			t.Errorf("parseScore(%q) = %d, expected %d", input, score, expected)
+		}
+	}
+}
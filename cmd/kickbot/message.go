@@ -3,73 +3,345 @@ package main
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/slack-go/slack"
 )
 
-var joinBtn = slack.ButtonBlockElement{
-	Type:     "button",
-	Text:     slack.NewTextBlockObject("plain_text", "Bin dabei!", false, false),
-	ActionID: ACTION_JOIN_ROUND,
-	Value:    ACTION_JOIN_ROUND,
-	Style:    "primary",
+// lobbyStyle is how the messages of the game requests in a channel are rendered, see ChannelSettings.
+type lobbyStyle struct {
+	lang    Language
+	mention MentionStyle
+	cancel  string    // slash command to cancel a game request
+	table   string    // label of the table the game request targets, empty if none was chosen
+	now     time.Time // when the message is rendered, to tell whether a scheduled game starts today
 }
 
-var leaveBtn = slack.ButtonBlockElement{
-	Type:     "button",
-	Text:     slack.NewTextBlockObject("plain_text", "Bin raus!", false, false),
-	ActionID: ACTION_LEAVE_ROUND,
-	Value:    ACTION_LEAVE_ROUND,
-	Confirm: &slack.ConfirmationBlockObject{
-		Title:   slack.NewTextBlockObject("plain_text", "Bist du sicher?", false, false),
-		Text:    slack.NewTextBlockObject("plain_text", "Möchtest du wirklich das Spiel verlassen?", false, false),
-		Confirm: slack.NewTextBlockObject("plain_text", "Nu", false, false),
-		Deny:    slack.NewTextBlockObject("plain_text", "Nä", false, false),
-	},
-	Style: "danger",
+func joinBtn(lang Language, id LobbyID) *slack.ButtonBlockElement {
+	return &slack.ButtonBlockElement{
+		Type:     "button",
+		Text:     slack.NewTextBlockObject("plain_text", lang.Text(msgJoinButton), false, false),
+		ActionID: ACTION_JOIN_ROUND,
+		Value:    string(id),
+		Style:    "primary",
+	}
 }
 
-var actionBlock = slack.NewActionBlock("GAME_ACTIONS", joinBtn, leaveBtn)
+func leaveBtn(lang Language, id LobbyID) *slack.ButtonBlockElement {
+	return &slack.ButtonBlockElement{
+		Type:     "button",
+		Text:     slack.NewTextBlockObject("plain_text", lang.Text(msgLeaveButton), false, false),
+		ActionID: ACTION_LEAVE_ROUND,
+		Value:    string(id),
+		Confirm: &slack.ConfirmationBlockObject{
+			Title:   slack.NewTextBlockObject("plain_text", lang.Text(msgLeaveConfirmTitle), false, false),
+			Text:    slack.NewTextBlockObject("plain_text", lang.Text(msgLeaveConfirmText), false, false),
+			Confirm: slack.NewTextBlockObject("plain_text", lang.Text(msgLeaveConfirmYes), false, false),
+			Deny:    slack.NewTextBlockObject("plain_text", lang.Text(msgLeaveConfirmNo), false, false),
+		},
+		Style: "danger",
+	}
+}
 
-func NewGameRequestMsg(playerId string, gameType GameType) slack.MsgOption {
-	var text string
+// extendBtn pushes back the timeout of the game request, only its creator may use it.
+func extendBtn(lang Language, id LobbyID) *slack.ButtonBlockElement {
+	label := lang.Text(msgExtendButton, int(extendBy.Minutes()))
+	return slack.NewButtonBlockElement(ACTION_EXTEND_ROUND, string(id), slack.NewTextBlockObject("plain_text", label, false, false))
+}
 
-	if gameType == GameTypeOneVsOne {
-		text = fmt.Sprintf("<!here>, <@%s> sucht einen Herausforderer für ein 1v1 Kicker-Duell. Wer traut sich", playerId)
-	} else {
-		text = fmt.Sprintf("<!here>, <@%s> hat Bock auf Kicker! Wer macht mit? Noch 3 Leute gesucht!", playerId)
+// actionBlock holds the join, leave and extend buttons of a game request. The buttons carry the lobby ID as value,
+// so that clicks on the message of an old game request never end up in another game request. Scheduled games
+// start at their time and can't be extended, they get no extend button.
+func actionBlock(lang Language, id LobbyID, startAt time.Time) *slack.ActionBlock {
+	if !startAt.IsZero() {
+		return slack.NewActionBlock("GAME_ACTIONS", joinBtn(lang, id), leaveBtn(lang, id))
 	}
+	return slack.NewActionBlock("GAME_ACTIONS", joinBtn(lang, id), leaveBtn(lang, id), extendBtn(lang, id))
+}
+
+// lobbyBlock shows the lobby ID, which tells apart the game requests of a channel in slash commands, and the table.
+func lobbyBlock(style lobbyStyle, id LobbyID) slack.Block {
+	text := style.lang.Text(msgLobbyContext, id, style.cancel)
+	if style.table != "" {
+		text += style.lang.Text(msgLobbyTable, style.table)
+	}
+	return slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", text, false, false))
+}
+
+// NewGameRequestMsg announces a new game request with the text of its format.
+func NewGameRequestMsg(style lobbyStyle, id LobbyID, playerId string, gameType GameType, quorum int, startAt time.Time) slack.MsgOption {
+	format := gameType.Format()
+	text := style.mention.prefix() + style.lang.Text(format.announce, playerId, format.MinPlayers-1, quorum)
+	text += scheduleText(style.lang, startAt, style.now)
 	textBlock := slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil)
-	return slack.MsgOptionBlocks(textBlock, slack.NewDividerBlock(), actionBlock)
+	return slack.MsgOptionBlocks(textBlock, lobbyBlock(style, id), slack.NewDividerBlock(), actionBlock(style.lang, id, startAt))
 
 }
 
-func GameRequestUpdateMsg(playerIds []string, quorum int) slack.MsgOption {
-	needed := quorum - len(playerIds)
-	playerMentions := make([]string, len(playerIds))
+// lobbyStatusText tells who joined a game request and how many players are still missing. Once a game request of
+// a format with a range of players has enough players, it tells how many more can join before it starts.
+func lobbyStatusText(lang Language, gameType GameType, playerIds []string, quorum int) string {
+	if missing := gameType.Format().MinPlayers - len(playerIds); missing > 0 {
+		return lang.Text(msgPlayersNeeded, mentions(playerIds), missing)
+	}
+	return lang.Text(msgPlayersOptional, mentions(playerIds), quorum-len(playerIds))
+}
 
-	for i, id := range playerIds {
-		playerMentions[i] = fmt.Sprintf("<@%s>", id)
+// GameRequestUpdateMsg renders the game request message of a game that is still looking for players.
+func GameRequestUpdateMsg(style lobbyStyle, id LobbyID, gameType GameType, playerIds []string, quorum int, startAt time.Time) slack.MsgOption {
+	text := lobbyStatusText(style.lang, gameType, playerIds, quorum) + scheduleText(style.lang, startAt, style.now)
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+		lobbyBlock(style, id),
+		actionBlock(style.lang, id, startAt),
 	}
 
-	playerMentionText := strings.Join(playerMentions, " ")
-	var text string
-	var blocks []slack.Block
+	return slack.MsgOptionBlocks(blocks...)
+}
 
-	if needed > 0 {
-		text = fmt.Sprintf("%s sind dabei. Noch %d Spieler gesucht!", playerMentionText, needed)
-		blocks = []slack.Block{
-			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
-			actionBlock,
-		}
-	} else {
-		text = fmt.Sprintf("%s sind bereit. Los geht's!", playerMentionText)
-		blocks = []slack.Block{
-			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+// TimeoutWarningMsg renders the game request message of a game that is about to time out.
+func TimeoutWarningMsg(style lobbyStyle, id LobbyID, gameType GameType, playerIds []string, quorum int, startAt time.Time, remaining time.Duration) slack.MsgOption {
+	minutes := max(int(remaining.Round(time.Minute).Minutes()), 1)
+	text := lobbyStatusText(style.lang, gameType, playerIds, quorum) + scheduleText(style.lang, startAt, style.now)
+	warning := style.lang.Text(msgTimeoutWarning, minutes, gameType.Format().MinPlayers-len(playerIds))
+	return slack.MsgOptionBlocks(
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", warning, false, false), nil, nil),
+		lobbyBlock(style, id),
+		actionBlock(style.lang, id, startAt),
+	)
+}
+
+// SeededGameRequestMsg announces the game request that was opened for the waitlist of the previous game.
+func SeededGameRequestMsg(style lobbyStyle, id LobbyID, gameType GameType, playerIds []string, quorum int) slack.MsgOption {
+	text := style.mention.prefix() + style.lang.Text(msgSeeded, lobbyStatusText(style.lang, gameType, playerIds, quorum))
+	if len(playerIds) >= quorum {
+		text = style.mention.prefix() + style.lang.Text(msgSeededFull, mentions(playerIds))
+	}
+	textBlock := slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil)
+	return slack.MsgOptionBlocks(textBlock, lobbyBlock(style, id), slack.NewDividerBlock(), actionBlock(style.lang, id, time.Time{}))
+}
+
+// WaitlistMsg tells a player who joined a full game their position on the waitlist.
+func WaitlistMsg(lang Language, position int) slack.MsgOption {
+	return slack.MsgOptionText(lang.Text(msgWaitlisted, position), false)
+}
+
+// scheduleText tells when a scheduled game starts, it is empty for games that start once they are full.
+func scheduleText(lang Language, startAt, now time.Time) string {
+	if startAt.IsZero() {
+		return ""
+	}
+	return lang.Text(msgScheduleHint, clockTime(lang, startAt, now))
+}
+
+// clockTime formats the time of day of a scheduled game in the time zone of now, including the date if it isn't
+// the day of now. The layouts are part of the catalog, since every language writes times differently.
+func clockTime(lang Language, t, now time.Time) string {
+	t = t.In(now.Location())
+	if y, m, d := t.Date(); y == now.Year() && m == now.Month() && d == now.Day() {
+		return t.Format(lang.Text(msgClockToday))
+	}
+	return t.Format(lang.Text(msgClockOtherDay))
+}
+
+// ScheduledConfirmationMsg tells the creator of a scheduled game when it will be announced.
+func ScheduledConfirmationMsg(lang Language, startAt, announceAt, now time.Time) slack.MsgOption {
+	text := lang.Text(msgScheduledConfirm, clockTime(lang, startAt, now), clockTime(lang, announceAt, now))
+	return slack.MsgOptionText(text, false)
+}
+
+// ReminderMsg reminds the players of a scheduled game shortly before its start.
+func ReminderMsg(lang Language, match MatchRecord, now time.Time) slack.MsgOption {
+	text := lang.Text(msgReminder, mentions(match.Players), clockTime(lang, match.StartedAt, now))
+	return slack.MsgOptionText(text, false)
+}
+
+// GameStartMsg replaces the game request message once the game reached its quorum.
+// It shows the proposed teams or, for formats without teams, the order of the players around the table. Players of
+// games with teams get buttons to reroll the teams and to enter the result of the match. Scheduled games show
+// their start time, as seen at now.
+func GameStartMsg(lang Language, match MatchRecord, now time.Time) slack.MsgOption {
+	text := lang.Text(msgGameReady, mentions(match.Players))
+	if match.StartedAt.After(now) {
+		text = lang.Text(msgGameReadyScheduled, mentions(match.Players), clockTime(lang, match.StartedAt, now))
+	}
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+	}
+	if match.Table != "" {
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", lang.Text(msgMatchTable, match.Table), false, false)))
+	}
+
+	format := match.GameType.Format()
+	if !format.HasTeams() {
+		orderText := lang.Text(msgTableOrder, strings.ReplaceAll(mentions(match.Players), " ", " → "))
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", orderText, false, false), nil, nil))
+		return slack.MsgOptionBlocks(blocks...)
+	}
+
+	buttons := []slack.BlockElement{
+		slack.NewButtonBlockElement(ACTION_RECORD_RESULT, match.ID, slack.NewTextBlockObject("plain_text", lang.Text(msgRecordResultButton), false, false)),
+	}
+	if len(match.Teams[0]) > 0 {
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", teamsText(lang, match.Teams), false, false), nil, nil))
+		buttons = append(buttons, slack.NewButtonBlockElement(ACTION_SHUFFLE_TEAMS, match.ID, slack.NewTextBlockObject("plain_text", lang.Text(msgShuffleButton), false, false)))
+	}
+
+	blocks = append(blocks, slack.NewActionBlock("GAME_RESULT_ACTIONS", buttons...))
+	return slack.MsgOptionBlocks(blocks...)
+}
+
+// teamsText renders the proposed teams, teams of two with the defense and attack position of their players.
+func teamsText(lang Language, teams [2][]string) string {
+	lines := make([]string, len(teams))
+	for i, team := range teams {
+		players := mentions(team)
+		if len(team) == 2 {
+			players = fmt.Sprintf(":shield: <@%s> :zap: <@%s>", team[0], team[1])
 		}
+		lines[i] = lang.Text(msgTeam, i+1, players)
 	}
+	return strings.Join(lines, "\n")
+}
 
+// TableBusyMsg tells a player that the chosen table is busy. Unless the parameters to queue the game request are
+// empty, the player is offered to queue until the table is free.
+func TableBusyMsg(lang Language, table string, until time.Time, queueParams string, now time.Time) slack.MsgOption {
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", lang.Text(msgTableBusy, table, clockTime(lang, until, now)), false, false), nil, nil),
+	}
+	if queueParams != "" {
+		queueBtn := slack.NewButtonBlockElement(ACTION_QUEUE_TABLE, queueParams, slack.NewTextBlockObject("plain_text", lang.Text(msgQueueButton), false, false))
+		queueBtn.Style = "primary"
+		blocks = append(blocks,
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", lang.Text(msgTableQueueOffer), false, false), nil, nil),
+			slack.NewActionBlock("TABLE_ACTIONS", queueBtn),
+		)
+	}
 	return slack.MsgOptionBlocks(blocks...)
 }
 
-var timeoutMSG = slack.MsgOptionText("Die Kicker-Runde ist abgelaufen. Nicht genug Spieler gefunden.", false)
+// positionConfirmationText confirms the preferred position set with the /kicker-position command.
+var positionConfirmationText = map[Position]MessageKey{
+	PositionAny:     msgPositionAny,
+	PositionDefense: msgPositionDefense,
+	PositionAttack:  msgPositionAttack,
+}
+
+// MatchResultMsg replaces the game start message once the result of the match was entered.
+func MatchResultMsg(lang Language, match MatchRecord) slack.MsgOption {
+	text := lang.Text(msgMatchResult, mentions(match.Teams[0]), match.Score[0], match.Score[1], mentions(match.Teams[1]), mentions(match.Teams[match.Winner]))
+	footer := lang.Text(msgRecordedBy, match.RecordedBy)
+
+	return slack.MsgOptionBlocks(
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+		slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", footer, false, false)),
+	)
+}
+
+// ResultModal builds the modal in which a player enters the result of a match.
+// The match ID is passed along as private metadata of the view.
+func ResultModal(lang Language, match MatchRecord) slack.ModalViewRequest {
+	teams := match.suggestedTeams()
+
+	teamSelect := slack.NewOptionsMultiSelectBlockElement(slack.MultiOptTypeUser, nil, resultActionTeam)
+	teamSelect.InitialUsers = teams[0]
+	teamHint := slack.NewTextBlockObject("plain_text", lang.Text(msgResultTeamHint), false, false)
+	teamBlock := slack.NewInputBlock(resultBlockTeam, slack.NewTextBlockObject("plain_text", lang.Text(msgTeamOne), false, false), teamHint, teamSelect)
+
+	scoreInput := func(blockID, label string) *slack.InputBlock {
+		input := slack.NewPlainTextInputBlockElement(slack.NewTextBlockObject("plain_text", "0", false, false), resultActionScore)
+		return slack.NewInputBlock(blockID, slack.NewTextBlockObject("plain_text", label, false, false), nil, input)
+	}
+
+	winnerOptions := []*slack.OptionBlockObject{
+		slack.NewOptionBlockObject("0", slack.NewTextBlockObject("plain_text", lang.Text(msgTeamOne), false, false), nil),
+		slack.NewOptionBlockObject("1", slack.NewTextBlockObject("plain_text", lang.Text(msgTeamTwo), false, false), nil),
+	}
+	winnerSelect := slack.NewRadioButtonsBlockElement(resultActionWinner, winnerOptions...)
+	winnerBlock := slack.NewInputBlock(resultBlockWinner, slack.NewTextBlockObject("plain_text", lang.Text(msgWinner), false, false), nil, winnerSelect)
+
+	return slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      VIEW_RECORD_RESULT,
+		PrivateMetadata: match.ID,
+		Title:           slack.NewTextBlockObject("plain_text", lang.Text(msgResultTitle), false, false),
+		Submit:          slack.NewTextBlockObject("plain_text", lang.Text(msgResultSave), false, false),
+		Close:           slack.NewTextBlockObject("plain_text", lang.Text(msgResultClose), false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", lang.Text(msgResultPlayers, mentions(match.Players)), false, false), nil, nil),
+			teamBlock,
+			scoreInput(resultBlockScore1, lang.Text(msgGoalsTeamOne)),
+			scoreInput(resultBlockScore2, lang.Text(msgGoalsTeamTwo)),
+			winnerBlock,
+		}},
+	}
+}
+
+// leaderboardSize is the number of players shown on the leaderboard
+const leaderboardSize = 20
+
+// LeaderboardMsg renders the leaderboard of the /kicker-stats command.
+func LeaderboardMsg(lang Language, standings []PlayerRating, statsOptions StatsOpts) slack.MsgOption {
+	mode := statsOptions.gameType.String()
+	scope := lang.Text(msgLeaderboardChannel)
+	if statsOptions.workspace {
+		scope = lang.Text(msgLeaderboardAll)
+	}
+	window := lang.Text(msgLeaderboardAllTime)
+	switch days := int(statsOptions.since.Hours() / 24); {
+	case statsOptions.since <= 0:
+	case days == 0:
+		window = lang.Text(msgLeaderboardSince, statsOptions.since.String())
+	case days == 1:
+		window = lang.Text(msgLeaderboardLastDay)
+	default:
+		window = lang.Text(msgLeaderboardLastDays, days)
+	}
+	header := lang.Text(msgLeaderboardHeader, mode, window, scope)
+
+	if len(standings) == 0 {
+		return slack.MsgOptionText(header+"\n"+lang.Text(msgLeaderboardEmpty), false)
+	}
+
+	standings = standings[:min(len(standings), leaderboardSize)]
+	lines := make([]string, len(standings))
+	for i, standing := range standings {
+		streak := "–"
+		if standing.Streak > 0 {
+			streak = fmt.Sprintf(":fire: %d", standing.Streak)
+		}
+		lines[i] = lang.Text(msgLeaderboardLine, i+1, standing.Player, standing.Rating, standing.Wins, standing.Losses, streak)
+	}
+
+	return slack.MsgOptionBlocks(
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", header, false, false), nil, nil),
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", strings.Join(lines, "\n"), false, false), nil, nil),
+	)
+}
+
+// mentions renders the given Slack user IDs as space separated user mentions.
+func mentions(playerIds []string) string {
+	playerMentions := make([]string, len(playerIds))
+	for i, id := range playerIds {
+		playerMentions[i] = fmt.Sprintf("<@%s>", id)
+	}
+	return strings.Join(playerMentions, " ")
+}
+
+// staleLobbyText tells a player who clicked a button of a finished game request what happened to it.
+var staleLobbyText = map[lobbyOutcome]MessageKey{
+	lobbyStarted:   msgLobbyStarted,
+	lobbyCancelled: msgLobbyCancelled,
+	lobbyExpired:   msgLobbyExpired,
+	lobbyAbandoned: msgLobbyAbandoned,
+}
+
+func cancelMSG(lang Language) slack.MsgOption {
+	return slack.MsgOptionText(lang.Text(msgCancelled), false)
+}
+
+func timeoutMSG(lang Language) slack.MsgOption {
+	return slack.MsgOptionText(lang.Text(msgTimedOut), false)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/slack-go/slack"
+)
+
+// Metrics are the Prometheus metrics of the game managers, served on /metrics. The game managers of all
+// workspaces share them, so they count the games of the whole bot.
+type Metrics struct {
+	lobbiesCreated *prometheus.CounterVec   // by game type
+	lobbiesClosed  *prometheus.CounterVec   // by game type and outcome
+	timeToFill     *prometheus.HistogramVec // by game type
+	joins          *prometheus.CounterVec   // by game type
+	leaves         *prometheus.CounterVec   // by game type
+	openLobbies    prometheus.Gauge
+	slackDuration  *prometheus.HistogramVec // by method
+	slackErrors    *prometheus.CounterVec   // by method
+}
+
+// NewMetrics creates the metrics and registers them with the registerer.
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	metrics := &Metrics{
+		lobbiesCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kickbot_lobbies_created_total",
+			Help: "Game requests opened, including the ones seeded by waitlists.",
+		}, []string{"game_type"}),
+		lobbiesClosed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kickbot_lobbies_closed_total",
+			Help: "Game requests closed, by outcome: filled, cancelled, timed_out, abandoned or discarded.",
+		}, []string{"game_type", "outcome"}),
+		timeToFill: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "kickbot_lobby_time_to_fill_seconds",
+			Help:    "Time from opening a game request until it started.",
+			Buckets: []float64{30, 60, 120, 300, 600, 900, 1800, 3600, 7200},
+		}, []string{"game_type"}),
+		joins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kickbot_lobby_joins_total",
+			Help: "Players who joined a game request, without the ones put on its waitlist.",
+		}, []string{"game_type"}),
+		leaves: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kickbot_lobby_leaves_total",
+			Help: "Players who left a game request, without the ones who left its waitlist.",
+		}, []string{"game_type"}),
+		openLobbies: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kickbot_lobbies_open",
+			Help: "Game requests currently open, including scheduled and queued ones.",
+		}),
+		slackDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "kickbot_slack_api_request_duration_seconds",
+			Help:    "Latency of the calls of the Slack Web API.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+		slackErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kickbot_slack_api_errors_total",
+			Help: "Failed calls of the Slack Web API.",
+		}, []string{"method"}),
+	}
+	registerer.MustRegister(
+		metrics.lobbiesCreated,
+		metrics.lobbiesClosed,
+		metrics.timeToFill,
+		metrics.joins,
+		metrics.leaves,
+		metrics.openLobbies,
+		metrics.slackDuration,
+		metrics.slackErrors,
+	)
+	return metrics
+}
+
+// outcomeLabel is the outcome label of a closed game request.
+var outcomeLabel = map[lobbyOutcome]string{
+	lobbyDiscarded: "discarded",
+	lobbyStarted:   "filled",
+	lobbyCancelled: "cancelled",
+	lobbyExpired:   "timed_out",
+	lobbyAbandoned: "abandoned",
+}
+
+// lobbyClosed counts a closed game request and, for started games, how long it took to fill until now. Both times
+// come from the clock of the game manager.
+func (metrics *Metrics) lobbyClosed(gameType GameType, outcome lobbyOutcome, createdAt, now time.Time) {
+	metrics.lobbiesClosed.WithLabelValues(gameType.String(), outcomeLabel[outcome]).Inc()
+	metrics.openLobbies.Dec()
+	if outcome == lobbyStarted && !createdAt.IsZero() {
+		metrics.timeToFill.WithLabelValues(gameType.String()).Observe(now.Sub(createdAt).Seconds())
+	}
+}
+
+// observe records the latency of a call of the Slack Web API and whether it failed.
+func (metrics *Metrics) observe(method string, start time.Time, err error) {
+	metrics.slackDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.slackErrors.WithLabelValues(method).Inc()
+	}
+}
+
+// instrumentedClient is a SlackClient that records the latency and errors of every call in the metrics.
+type instrumentedClient struct {
+	client  SlackClient
+	metrics *Metrics
+}
+
+func (client *instrumentedClient) PostEphemeral(channelID, userID string, options ...slack.MsgOption) (string, error) {
+	start := time.Now()
+	timestamp, err := client.client.PostEphemeral(channelID, userID, options...)
+	client.metrics.observe("chat.postEphemeral", start, err)
+	return timestamp, err
+}
+
+func (client *instrumentedClient) PostMessage(channelID string, options ...slack.MsgOption) (string, string, error) {
+	start := time.Now()
+	channel, timestamp, err := client.client.PostMessage(channelID, options...)
+	client.metrics.observe("chat.postMessage", start, err)
+	return channel, timestamp, err
+}
+
+func (client *instrumentedClient) ScheduleMessage(channelID, postAt string, options ...slack.MsgOption) (string, string, error) {
+	start := time.Now()
+	channel, scheduledMessageID, err := client.client.ScheduleMessage(channelID, postAt, options...)
+	client.metrics.observe("chat.scheduleMessage", start, err)
+	return channel, scheduledMessageID, err
+}
+
+func (client *instrumentedClient) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+	start := time.Now()
+	channel, ts, text, err := client.client.UpdateMessage(channelID, timestamp, options...)
+	client.metrics.observe("chat.update", start, err)
+	return channel, ts, text, err
+}
+
+func (client *instrumentedClient) DeleteMessage(channel, messageTimestamp string) (string, string, error) {
+	start := time.Now()
+	respChannel, timestamp, err := client.client.DeleteMessage(channel, messageTimestamp)
+	client.metrics.observe("chat.delete", start, err)
+	return respChannel, timestamp, err
+}
+
+func (client *instrumentedClient) OpenView(triggerID string, view slack.ModalViewRequest) (*slack.ViewResponse, error) {
+	start := time.Now()
+	response, err := client.client.OpenView(triggerID, view)
+	client.metrics.observe("views.open", start, err)
+	return response, err
+}
+
+func (client *instrumentedClient) GetUserInfo(user string) (*slack.User, error) {
+	start := time.Now()
+	info, err := client.client.GetUserInfo(user)
+	client.metrics.observe("users.info", start, err)
+	return info, err
+}
+
+func (client *instrumentedClient) DeleteMessageContext(ctx context.Context, channel, messageTimestamp string) (string, string, error) {
+	start := time.Now()
+	respChannel, timestamp, err := client.client.DeleteMessageContext(ctx, channel, messageTimestamp)
+	client.metrics.observe("chat.delete", start, err)
+	return respChannel, timestamp, err
+}
+
+func (client *instrumentedClient) AuthTestContext(ctx context.Context) (*slack.AuthTestResponse, error) {
+	start := time.Now()
+	response, err := client.client.AuthTestContext(ctx)
+	client.metrics.observe("auth.test", start, err)
+	return response, err
+}
+
+// compile-time assertion to ensure that `instrumentedClient` implements `SlackClient`
+var _ SlackClient = (*instrumentedClient)(nil)
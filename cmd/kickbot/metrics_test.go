@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/mock/gomock"
+)
+
+// TestGameMetrics verifies that the lifecycle of game requests and the calls of the Slack API are counted.
+func TestGameMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	mockSlackClient.EXPECT().
+		PostMessage(gomock.Any(), gomock.Any()).
+		Return("channelID", "timestamp", nil).Times(2)
+	mockSlackClient.EXPECT().
+		UpdateMessage(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("channelID", "timestamp", "text", nil).AnyTimes()
+	// the pings of the duel's players fail
+	mockSlackClient.EXPECT().
+		PostEphemeral(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("", errors.New("channel_not_found")).Times(2)
+
+	metrics := NewMetrics(prometheus.NewRegistry())
+	clock := newFakeClock()
+	gameMgr := NewGameManager(mockSlackClient, WithMetrics(metrics), WithClock(clock))
+	defer gameMgr.Shutdown(context.TODO())
+	gameOptions := GameOpts{timeout: 30 * time.Minute, gameType: GameTypeOneVsOne}
+
+	// a duel that is filled and one that is cancelled
+	gameMgr.CreateGame("C1", "U1", gameOptions)
+	if got := testutil.ToFloat64(metrics.openLobbies); got != 1 {
+		t.Errorf("Expected 1 open lobby, got %v", got)
+	}
+	clock.Advance(90 * time.Second)
+	gameMgr.JoinGame("C1", lobbyIn(gameMgr, "C1"), "U2")
+	gameMgr.CreateGame("C2", "U3", gameOptions)
+	gameMgr.CancelGame("C2", "U3", "")
+
+	duel := GameTypeOneVsOne.String()
+	for _, check := range []struct {
+		name      string
+		collector prometheus.Collector
+		expected  float64
+	}{
+		{"created", metrics.lobbiesCreated.WithLabelValues(duel), 2},
+		{"filled", metrics.lobbiesClosed.WithLabelValues(duel, "filled"), 1},
+		{"cancelled", metrics.lobbiesClosed.WithLabelValues(duel, "cancelled"), 1},
+		{"joins", metrics.joins.WithLabelValues(duel), 1},
+		{"open", metrics.openLobbies, 0},
+		{"chat.postEphemeral errors", metrics.slackErrors.WithLabelValues("chat.postEphemeral"), 2},
+		{"chat.postMessage errors", metrics.slackErrors.WithLabelValues("chat.postMessage"), 0},
+	} {
+		if got := testutil.ToFloat64(check.collector); got != check.expected {
+			t.Errorf("Expected %v for %s, got %v", check.expected, check.name, got)
+		}
+	}
+	if count := testutil.CollectAndCount(metrics.timeToFill); count != 1 {
+		t.Errorf("Expected the time to fill of 1 game type, got %d", count)
+	}
+	// the time to fill is measured on the clock of the game manager
+	if sum := histogramSum(t, metrics.timeToFill); sum != 90 {
+		t.Errorf("Expected a time to fill of 90s, got %vs", sum)
+	}
+	if count := testutil.CollectAndCount(metrics.slackDuration); count != 3 {
+		t.Errorf("Expected the latency of 3 Slack API methods, got %d", count)
+	}
+}
+
+// histogramSum returns the sum of the observations of a histogram with a single label combination.
+func histogramSum(t *testing.T, collector prometheus.Collector) float64 {
+	t.Helper()
+	metrics := make(chan prometheus.Metric, 1)
+	collector.Collect(metrics)
+	var metric dto.Metric
+	if err := (<-metrics).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetHistogram().GetSampleSum()
+}
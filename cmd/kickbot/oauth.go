@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// oauthScopes are the bot scopes the app asks for when it is installed in a workspace.
+const oauthScopes = "app_mentions:read,channels:read,chat:write,commands,groups:read,reactions:read,users:read"
+
+// oauthStateCookie holds the state of an installation in progress, which the redirect of Slack has to return.
+const oauthStateCookie = "kickbot_oauth_state"
+
+// oauthAuthorizeURL is where users grant the app its scopes in their workspace.
+const oauthAuthorizeURL = "https://slack.com/oauth/v2/authorize"
+
+// OAuthInstaller installs the app in workspaces with the OAuth v2 flow of Slack: /slack/install sends the user to
+// Slack to approve the installation and Slack sends them back to /slack/oauth_redirect with a code, which is
+// exchanged for the bot token of the workspace.
+type OAuthInstaller struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string // must match a redirect URL of the Slack app, empty for its only one
+	workspaces   *Workspaces
+	// exchange exchanges the code of the redirect for the tokens of the installation, replaced in tests
+	exchange func(ctx context.Context, code string) (*slack.OAuthV2Response, error)
+}
+
+// NewOAuthInstaller returns an OAuthInstaller that installs the app with the client ID and secret of the Slack app.
+func NewOAuthInstaller(clientID, clientSecret, redirectURL string, workspaces *Workspaces) *OAuthInstaller {
+	installer := &OAuthInstaller{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		workspaces:   workspaces,
+	}
+	installer.exchange = func(ctx context.Context, code string) (*slack.OAuthV2Response, error) {
+		return slack.GetOAuthV2ResponseContext(ctx, http.DefaultClient, clientID, clientSecret, code, redirectURL)
+	}
+	return installer
+}
+
+// HandleInstall redirects to Slack to approve the installation. The random state sent along is kept in a cookie,
+// so that the redirect can only complete an installation started in the same browser.
+func (installer *OAuthInstaller) HandleInstall(w http.ResponseWriter, r *http.Request) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		slog.Error("Failed to generate OAuth state", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	state := hex.EncodeToString(buf)
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/slack",
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := url.Values{}
+	query.Set("client_id", installer.clientID)
+	query.Set("scope", oauthScopes)
+	query.Set("state", state)
+	if installer.redirectURL != "" {
+		query.Set("redirect_uri", installer.redirectURL)
+	}
+	http.Redirect(w, r, oauthAuthorizeURL+"?"+query.Encode(), http.StatusFound)
+}
+
+// HandleRedirect completes an installation: it checks the state, exchanges the code for the bot token and stores
+// the installation of the workspace, or of the whole organization for installations in an Enterprise Grid.
+func (installer *OAuthInstaller) HandleRedirect(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(oauthStateCookie)
+	state := r.URL.Query().Get("state")
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		slog.Warn("Rejected OAuth redirect with invalid state", "sender", r.RemoteAddr)
+		http.Error(w, "The installation expired, please start it again.", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/slack", MaxAge: -1})
+
+	if reason := r.URL.Query().Get("error"); reason != "" {
+		slog.Info("Installation was not approved", "error", reason)
+		http.Error(w, "The installation was cancelled.", http.StatusForbidden)
+		return
+	}
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "The redirect is missing the code.", http.StatusBadRequest)
+		return
+	}
+
+	response, err := installer.exchange(r.Context(), code)
+	if err != nil {
+		slog.Error("Failed to exchange OAuth code", "error", err)
+		http.Error(w, "Slack refused the installation, please try again.", http.StatusBadGateway)
+		return
+	}
+	installation := Installation{
+		TeamID:            response.Team.ID,
+		TeamName:          response.Team.Name,
+		EnterpriseID:      response.Enterprise.ID,
+		EnterpriseInstall: response.Team.ID == "" && response.Enterprise.ID != "",
+		BotToken:          response.AccessToken,
+		BotUserID:         response.BotUserID,
+		InstalledAt:       time.Now(),
+	}
+	if err := installer.workspaces.Install(installation); err != nil {
+		slog.Error("Failed to install app", "error", err)
+		http.Error(w, "The installation could not be saved, please try again.", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("Installed app", "team", installation.TeamID, "enterprise", installation.EnterpriseID)
+
+	name := installation.TeamName
+	if installation.EnterpriseInstall {
+		name = response.Enterprise.Name
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "kickbot is installed in %s.\n", name)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/mock/gomock"
+)
+
+// TestOAuthInstallation verifies that the install page redirects to Slack with a state, that redirects without
+// the state of the browser are rejected and that a redirect with the state installs the app in the workspace.
+func TestOAuthInstallation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	workspaces := newTestWorkspaces(map[string]*MockSlackClient{"xoxb-1": NewMockSlackClient(ctrl)})
+	defer workspaces.Shutdown(context.TODO())
+	installer := NewOAuthInstaller("client-id", "client-secret", "", workspaces)
+	installer.exchange = func(ctx context.Context, code string) (*slack.OAuthV2Response, error) {
+		if code != "code-1" {
+			t.Errorf("Expected code-1 to be exchanged, got %q", code)
+		}
+		response := &slack.OAuthV2Response{AccessToken: "xoxb-1", BotUserID: "B1"}
+		response.Team.ID, response.Team.Name = "T1", "Kicker GmbH"
+		return response, nil
+	}
+
+	rr := httptest.NewRecorder()
+	installer.HandleInstall(rr, httptest.NewRequest(http.MethodGet, "/slack/install", nil))
+	if rr.Code != http.StatusFound {
+		t.Fatalf("Expected a redirect to Slack, got status %d", rr.Code)
+	}
+	location, _ := url.Parse(rr.Header().Get("Location"))
+	state := location.Query().Get("state")
+	if location.Host != "slack.com" || location.Query().Get("client_id") != "client-id" || state == "" {
+		t.Fatalf("Expected the authorization URL of Slack with client ID and state, got %s", location)
+	}
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != state {
+		t.Fatalf("Expected the state in a cookie, got %v", cookies)
+	}
+
+	// a redirect with another state was not started in this browser
+	rr = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/slack/oauth_redirect?code=code-1&state=forged", nil)
+	req.AddCookie(cookies[0])
+	installer.HandleRedirect(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected a forged state to be rejected, got status %d", rr.Code)
+	}
+	if _, err := workspaces.Manager(Workspace{TeamID: "T1"}); err == nil {
+		t.Fatal("Expected T1 not to be installed with a forged state")
+	}
+
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/slack/oauth_redirect?code=code-1&state="+state, nil)
+	req.AddCookie(cookies[0])
+	installer.HandleRedirect(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected the installation to succeed, got status %d: %s", rr.Code, rr.Body)
+	}
+	if _, err := workspaces.Manager(Workspace{TeamID: "T1"}); err != nil {
+		t.Errorf("Expected T1 to be installed, got %v", err)
+	}
+}
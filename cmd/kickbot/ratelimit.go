@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// RateLimitConfig configures how often the bot calls the Slack API in a workspace, so that it stays under the rate
+// limits of the API methods, see https://api.slack.com/apis/rate-limits.
+type RateLimitConfig struct {
+	Tier3       int           // calls per minute of the tier 3 methods, e.g. chat.update and chat.delete
+	Tier4       int           // calls per minute of the tier 4 methods, e.g. chat.postEphemeral and views.open
+	PostMessage int           // calls per minute of chat.postMessage, which has a limit of its own
+	Reserve     float64       // share of every tier that only critical calls may use, e.g. the pings of a full game
+	Debounce    time.Duration // how long the updates of a message are coalesced after one was sent during a burst
+}
+
+// DefaultRateLimitConfig follows the documented limits of Slack. During a burst of clicks messages are only updated
+// a few times a second, which coalesces the clicks without keeping the players waiting.
+var DefaultRateLimitConfig = RateLimitConfig{
+	Tier3:       50,
+	Tier4:       100,
+	PostMessage: 60,
+	Reserve:     0.2,
+	Debounce:    250 * time.Millisecond,
+}
+
+// rateLimiter holds a token bucket per rate limit tier of the Slack API methods. The buckets are shared by all calls
+// of a workspace, since Slack counts the calls per workspace and app. auth.test isn't limited.
+type rateLimiter struct {
+	buckets map[string]*tokenBucket // by method
+}
+
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	tier3 := newTokenBucket(config.Tier3, config.Reserve)
+	tier4 := newTokenBucket(config.Tier4, config.Reserve)
+	return &rateLimiter{buckets: map[string]*tokenBucket{
+		"chat.postMessage":     newTokenBucket(config.PostMessage, config.Reserve),
+		"chat.scheduleMessage": tier3,
+		"chat.update":          tier3,
+		"chat.delete":          tier3,
+		"chat.postEphemeral":   tier4,
+		"views.open":           tier4,
+		"users.info":           tier4,
+	}}
+}
+
+// wait blocks until the call of the method may be made or the context is done.
+func (limiter *rateLimiter) wait(ctx context.Context, method string, critical bool) error {
+	bucket, limited := limiter.buckets[method]
+	if !limited {
+		return nil
+	}
+	for {
+		delay := bucket.take(time.Now(), critical)
+		if delay == 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// tokenBucket allows as many calls per minute as it holds tokens, all of them at once after a quiet minute.
+// Regular calls leave the reserved tokens to critical ones, so that a busy workspace still pings its players
+// in time.
+type tokenBucket struct {
+	rate     float64 // tokens per second
+	capacity float64
+	reserved float64
+	tokens   float64
+	last     time.Time // when the tokens were last refilled
+	mu       sync.Mutex
+}
+
+func newTokenBucket(perMinute int, reserve float64) *tokenBucket {
+	capacity := float64(perMinute)
+	return &tokenBucket{
+		rate:     capacity / 60,
+		capacity: capacity,
+		reserved: capacity * reserve,
+		tokens:   capacity,
+		last:     time.Now(),
+	}
+}
+
+// take takes a token and returns zero, or returns how long to wait until a token can be taken.
+func (bucket *tokenBucket) take(now time.Time, critical bool) time.Duration {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	if now.After(bucket.last) {
+		bucket.tokens = min(bucket.capacity, bucket.tokens+now.Sub(bucket.last).Seconds()*bucket.rate)
+		bucket.last = now
+	}
+	floor := bucket.reserved
+	if critical {
+		floor = 0
+	}
+	if bucket.tokens-1 >= floor {
+		bucket.tokens--
+		return 0
+	}
+	return time.Duration((floor + 1 - bucket.tokens) / bucket.rate * float64(time.Second))
+}
+
+// throttledClient is a SlackClient whose calls wait for a token of their tier. Critical calls may use the reserved
+// tokens. Both kinds of calls of a workspace share a rateLimiter.
+type throttledClient struct {
+	client   SlackClient
+	limiter  *rateLimiter
+	critical bool
+}
+
+func (client *throttledClient) PostEphemeral(channelID, userID string, options ...slack.MsgOption) (string, error) {
+	if err := client.limiter.wait(context.Background(), "chat.postEphemeral", client.critical); err != nil {
+		return "", err
+	}
+	return client.client.PostEphemeral(channelID, userID, options...)
+}
+
+func (client *throttledClient) PostMessage(channelID string, options ...slack.MsgOption) (string, string, error) {
+	if err := client.limiter.wait(context.Background(), "chat.postMessage", client.critical); err != nil {
+		return "", "", err
+	}
+	return client.client.PostMessage(channelID, options...)
+}
+
+func (client *throttledClient) ScheduleMessage(channelID, postAt string, options ...slack.MsgOption) (string, string, error) {
+	if err := client.limiter.wait(context.Background(), "chat.scheduleMessage", client.critical); err != nil {
+		return "", "", err
+	}
+	return client.client.ScheduleMessage(channelID, postAt, options...)
+}
+
+func (client *throttledClient) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+	if err := client.limiter.wait(context.Background(), "chat.update", client.critical); err != nil {
+		return "", "", "", err
+	}
+	return client.client.UpdateMessage(channelID, timestamp, options...)
+}
+
+func (client *throttledClient) DeleteMessage(channel, messageTimestamp string) (string, string, error) {
+	if err := client.limiter.wait(context.Background(), "chat.delete", client.critical); err != nil {
+		return "", "", err
+	}
+	return client.client.DeleteMessage(channel, messageTimestamp)
+}
+
+func (client *throttledClient) OpenView(triggerID string, view slack.ModalViewRequest) (*slack.ViewResponse, error) {
+	if err := client.limiter.wait(context.Background(), "views.open", client.critical); err != nil {
+		return nil, err
+	}
+	return client.client.OpenView(triggerID, view)
+}
+
+func (client *throttledClient) GetUserInfo(user string) (*slack.User, error) {
+	if err := client.limiter.wait(context.Background(), "users.info", client.critical); err != nil {
+		return nil, err
+	}
+	return client.client.GetUserInfo(user)
+}
+
+func (client *throttledClient) DeleteMessageContext(ctx context.Context, channel, messageTimestamp string) (string, string, error) {
+	if err := client.limiter.wait(ctx, "chat.delete", client.critical); err != nil {
+		return "", "", err
+	}
+	return client.client.DeleteMessageContext(ctx, channel, messageTimestamp)
+}
+
+func (client *throttledClient) AuthTestContext(ctx context.Context) (*slack.AuthTestResponse, error) {
+	if err := client.limiter.wait(ctx, "auth.test", client.critical); err != nil {
+		return nil, err
+	}
+	return client.client.AuthTestContext(ctx)
+}
+
+// compile-time assertion to ensure that `throttledClient` implements `SlackClient`
+var _ SlackClient = (*throttledClient)(nil)
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTokenBucketReservesCriticalCalls verifies that regular calls leave the reserved tokens to critical calls and
+// that the tokens are refilled at the rate of the tier.
+func TestTokenBucketReservesCriticalCalls(t *testing.T) {
+	bucket := newTokenBucket(60, 0.2)
+	now := bucket.last
+
+	for i := range 48 {
+		if delay := bucket.take(now, false); delay != 0 {
+			t.Fatalf("Expected regular call %d to be made right away, got a delay of %v", i+1, delay)
+		}
+	}
+	if delay := bucket.take(now, false); delay != time.Second {
+		t.Errorf("Expected the next regular call to wait for a second, got %v", delay)
+	}
+	for i := range 12 {
+		if delay := bucket.take(now, true); delay != 0 {
+			t.Fatalf("Expected critical call %d to use the reserved tokens, got a delay of %v", i+1, delay)
+		}
+	}
+	if delay := bucket.take(now, true); delay != time.Second {
+		t.Errorf("Expected the next critical call to wait for a second, got %v", delay)
+	}
+
+	if delay := bucket.take(now.Add(time.Second), true); delay != 0 {
+		t.Errorf("Expected a critical call to be made once a token was refilled, got a delay of %v", delay)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// RatingConfig configures the Elo ratings of the players.
+type RatingConfig struct {
+	Initial    float64       // rating of a player without any matches
+	KFactor    float64       // maximum rating change of a single match
+	DecayAfter time.Duration // inactivity after which a rating starts to decay, zero disables the decay
+	DecayRate  float64       // share of the rating above Initial that is lost per week of inactivity after DecayAfter
+}
+
+var DefaultRatingConfig = RatingConfig{
+	Initial: 1000,
+	KFactor: 32,
+}
+
+// PlayerRating is the rating and match statistic of a single player for one game type.
+type PlayerRating struct {
+	Player     string
+	Rating     float64
+	Wins       int
+	Losses     int
+	Streak     int // current streak, positive for consecutive wins and negative for consecutive losses
+	LastPlayed time.Time
+}
+
+// Ratings keeps the Elo ratings of all players per game type. Ratings are derived from the recorded matches only,
+// so they can always be recomputed from the match history. In team games every player of a team is rated against
+// the average rating of the opposing team and gains or loses the same amount as their teammates.
+type Ratings struct {
+	config  RatingConfig
+	players map[GameType]map[string]*PlayerRating
+	latest  map[GameType]time.Time // start of the latest rated match, see Apply
+	mu      sync.Mutex
+}
+
+func NewRatings(config RatingConfig) *Ratings {
+	return &Ratings{
+		config:  config,
+		players: make(map[GameType]map[string]*PlayerRating),
+		latest:  make(map[GameType]time.Time),
+	}
+}
+
+// ComputeRatings rates all recorded matches of the history in the order they were played.
+func ComputeRatings(config RatingConfig, matches []MatchRecord) *Ratings {
+	ratings := NewRatings(config)
+	ratings.Recompute(matches)
+	return ratings
+}
+
+// Recompute discards all ratings and rates the recorded matches of the history in the order they were played.
+// Matches that started at the same time are rated in the order their results were recorded.
+func (ratings *Ratings) Recompute(matches []MatchRecord) {
+	matches = slices.Clone(matches)
+	slices.SortStableFunc(matches, func(a, b MatchRecord) int {
+		if c := a.StartedAt.Compare(b.StartedAt); c != 0 {
+			return c
+		}
+		return a.RecordedAt.Compare(b.RecordedAt)
+	})
+
+	ratings.mu.Lock()
+	defer ratings.mu.Unlock()
+
+	clear(ratings.players)
+	clear(ratings.latest)
+	for _, match := range matches {
+		ratings.apply(match)
+	}
+}
+
+// Apply updates the ratings of the players of a recorded match and reports whether the ratings are up to date.
+// Matches without a result are ignored. Since Elo depends on the order of the matches, a match that started before
+// the latest rated match of its game type isn't rated, e.g. if the results of two tables are entered out of order;
+// the ratings have to be recomputed from the history then.
+func (ratings *Ratings) Apply(match MatchRecord) bool {
+	ratings.mu.Lock()
+	defer ratings.mu.Unlock()
+
+	if rateable(match) && match.StartedAt.Before(ratings.latest[match.GameType]) {
+		return false
+	}
+	ratings.apply(match)
+	return true
+}
+
+// rateable reports whether a match has a result and two teams to rate.
+func rateable(match MatchRecord) bool {
+	return match.IsRecorded() && len(match.Teams[0]) > 0 && len(match.Teams[1]) > 0
+}
+
+// apply rates a match, the caller must hold the lock.
+func (ratings *Ratings) apply(match MatchRecord) {
+	if !rateable(match) {
+		return
+	}
+	if match.StartedAt.After(ratings.latest[match.GameType]) {
+		ratings.latest[match.GameType] = match.StartedAt
+	}
+
+	table, exists := ratings.players[match.GameType]
+	if !exists {
+		table = make(map[string]*PlayerRating)
+		ratings.players[match.GameType] = table
+	}
+
+	// Inactivity decay is applied for good once a player plays again
+	var teams [2][]*PlayerRating
+	var teamRatings [2]float64
+	for i, team := range match.Teams {
+		for _, player := range team {
+			rating, exists := table[player]
+			if !exists {
+				rating = &PlayerRating{Player: player, Rating: ratings.config.Initial}
+				table[player] = rating
+			}
+			rating.Rating = ratings.decayed(*rating, match.StartedAt)
+			teams[i] = append(teams[i], rating)
+			teamRatings[i] += rating.Rating
+		}
+		teamRatings[i] /= float64(len(team))
+	}
+
+	for i, team := range teams {
+		expected := 1 / (1 + math.Pow(10, (teamRatings[1-i]-teamRatings[i])/400))
+		won := match.Winner == i
+		var score float64
+		if won {
+			score = 1
+		}
+		change := ratings.config.KFactor * (score - expected)
+
+		for _, rating := range team {
+			rating.Rating += change
+			rating.LastPlayed = match.StartedAt
+			if won {
+				rating.Wins++
+				rating.Streak = max(rating.Streak, 0) + 1
+			} else {
+				rating.Losses++
+				rating.Streak = min(rating.Streak, 0) - 1
+			}
+		}
+	}
+}
+
+// Rating returns the rating of a player for a game type at the given point in time, including the decay of
+// the player's inactivity. Players without matches have the initial rating.
+func (ratings *Ratings) Rating(gameType GameType, player string, now time.Time) PlayerRating {
+	ratings.mu.Lock()
+	defer ratings.mu.Unlock()
+
+	rating, exists := ratings.players[gameType][player]
+	if !exists {
+		return PlayerRating{Player: player, Rating: ratings.config.Initial}
+	}
+	result := *rating
+	result.Rating = ratings.decayed(result, now)
+	return result
+}
+
+// HasRating reports whether the player played at least one rated match of the game type.
+func (ratings *Ratings) HasRating(gameType GameType, player string) bool {
+	ratings.mu.Lock()
+	defer ratings.mu.Unlock()
+
+	_, exists := ratings.players[gameType][player]
+	return exists
+}
+
+// Standings returns the ratings of all players of a game type at the given point in time, best first.
+func (ratings *Ratings) Standings(gameType GameType, now time.Time) []PlayerRating {
+	ratings.mu.Lock()
+	defer ratings.mu.Unlock()
+
+	standings := make([]PlayerRating, 0, len(ratings.players[gameType]))
+	for _, rating := range ratings.players[gameType] {
+		result := *rating
+		result.Rating = ratings.decayed(result, now)
+		standings = append(standings, result)
+	}
+	slices.SortFunc(standings, func(a, b PlayerRating) int {
+		if c := cmp.Compare(b.Rating, a.Rating); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Player, b.Player)
+	})
+	return standings
+}
+
+// decayed returns the rating reduced by the configured decay for the inactivity of the player up to now.
+// Only the part of the rating above the initial rating decays, inactivity never raises a rating.
+func (ratings *Ratings) decayed(rating PlayerRating, now time.Time) float64 {
+	cfg := ratings.config
+	if cfg.DecayAfter <= 0 || cfg.DecayRate <= 0 || rating.Rating <= cfg.Initial {
+		return rating.Rating
+	}
+
+	inactive := now.Sub(rating.LastPlayed) - cfg.DecayAfter
+	if inactive <= 0 {
+		return rating.Rating
+	}
+	weeks := inactive.Hours() / (24 * 7)
+	return cfg.Initial + (rating.Rating-cfg.Initial)*math.Pow(1-cfg.DecayRate, weeks)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func recordedMatch(gameType GameType, teamOne, teamTwo []string, winner int, startedAt time.Time) MatchRecord {
+	return MatchRecord{
+		ID:         newID(),
+		GameType:   gameType,
+		Players:    append(append([]string{}, teamOne...), teamTwo...),
+		Teams:      [2][]string{teamOne, teamTwo},
+		Score:      [2]int{10 * (1 - winner), 10 * winner},
+		Winner:     winner,
+		StartedAt:  startedAt,
+		RecordedAt: startedAt,
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestRatingsOneVsOne(t *testing.T) {
+	now := time.Now()
+	ratings := NewRatings(DefaultRatingConfig)
+
+	ratings.Apply(recordedMatch(GameTypeOneVsOne, []string{"p1"}, []string{"p2"}, 0, now))
+
+	// Equal ratings expect a score of 0.5, so the winner gains half of the K-factor
+	if r := ratings.Rating(GameTypeOneVsOne, "p1", now); !almostEqual(r.Rating, 1016) || r.Wins != 1 || r.Streak != 1 {
+		t.Errorf("Unexpected rating of the winner %+v", r)
+	}
+	if r := ratings.Rating(GameTypeOneVsOne, "p2", now); !almostEqual(r.Rating, 984) || r.Losses != 1 || r.Streak != -1 {
+		t.Errorf("Unexpected rating of the loser %+v", r)
+	}
+
+	// Ratings are kept per game type
+	if ratings.HasRating(GameTypeTwoVsTwo, "p1") {
+		t.Error("1v1 matches must not rate 2v2 games")
+	}
+	if r := ratings.Rating(GameTypeTwoVsTwo, "p1", now); r.Rating != DefaultRatingConfig.Initial {
+		t.Errorf("Expected the initial rating for an unrated player, got %v", r.Rating)
+	}
+
+	// An upset gains more than a win of the favorite
+	ratings.Apply(recordedMatch(GameTypeOneVsOne, []string{"p1"}, []string{"p2"}, 1, now.Add(time.Minute)))
+	if r := ratings.Rating(GameTypeOneVsOne, "p2", now); r.Rating <= 1000 || r.Streak != 1 {
+		t.Errorf("Expected the upset to bring p2 above the initial rating, got %+v", r)
+	}
+}
+
+func TestRatingsTwoVsTwoUsesTeamAverage(t *testing.T) {
+	now := time.Now()
+	ratings := NewRatings(DefaultRatingConfig)
+
+	// Make p1 strong and p2 weak, then team them up against two new players of the same average
+	ratings.Apply(recordedMatch(GameTypeTwoVsTwo, []string{"p1", "x1"}, []string{"p2", "x2"}, 0, now))
+
+	before1 := ratings.Rating(GameTypeTwoVsTwo, "p1", now).Rating
+	before2 := ratings.Rating(GameTypeTwoVsTwo, "p2", now).Rating
+	ratings.Apply(recordedMatch(GameTypeTwoVsTwo, []string{"p1", "p2"}, []string{"n1", "n2"}, 0, now.Add(time.Minute)))
+
+	gain1 := ratings.Rating(GameTypeTwoVsTwo, "p1", now).Rating - before1
+	gain2 := ratings.Rating(GameTypeTwoVsTwo, "p2", now).Rating - before2
+	if !almostEqual(gain1, gain2) || !almostEqual(gain1, 16) {
+		t.Errorf("Expected both teammates to gain 16 against an equally rated team, got %v and %v", gain1, gain2)
+	}
+}
+
+func TestRatingsDecay(t *testing.T) {
+	now := time.Now()
+	config := DefaultRatingConfig
+	config.DecayAfter = time.Hour * 24 * 30
+	config.DecayRate = 0.5
+	ratings := NewRatings(config)
+
+	ratings.Apply(recordedMatch(GameTypeOneVsOne, []string{"p1"}, []string{"p2"}, 0, now))
+
+	if r := ratings.Rating(GameTypeOneVsOne, "p1", now.Add(config.DecayAfter)); !almostEqual(r.Rating, 1016) {
+		t.Errorf("Expected no decay before DecayAfter, got %v", r.Rating)
+	}
+	if r := ratings.Rating(GameTypeOneVsOne, "p1", now.Add(config.DecayAfter+time.Hour*24*7)); !almostEqual(r.Rating, 1008) {
+		t.Errorf("Expected half of the rating above the initial rating to decay after a week, got %v", r.Rating)
+	}
+	// Ratings below the initial rating don't rise through inactivity
+	if r := ratings.Rating(GameTypeOneVsOne, "p2", now.Add(time.Hour*24*365)); !almostEqual(r.Rating, 984) {
+		t.Errorf("Expected the rating of the loser to stay, got %v", r.Rating)
+	}
+}
+
+func TestRatingsRecomputeMatchesIncrementalUpdates(t *testing.T) {
+	now := time.Now()
+	matches := []MatchRecord{
+		recordedMatch(GameTypeOneVsOne, []string{"p1"}, []string{"p2"}, 0, now),
+		recordedMatch(GameTypeOneVsOne, []string{"p2"}, []string{"p3"}, 0, now.Add(time.Minute)),
+		recordedMatch(GameTypeOneVsOne, []string{"p3"}, []string{"p1"}, 1, now.Add(2*time.Minute)),
+		{ID: "unrecorded", GameType: GameTypeOneVsOne, Players: []string{"p1", "p2"}, StartedAt: now},
+	}
+
+	incremental := NewRatings(DefaultRatingConfig)
+	for _, match := range matches {
+		incremental.Apply(match)
+	}
+
+	// The history is recomputed in the order the matches were played, regardless of the input order
+	recomputed := ComputeRatings(DefaultRatingConfig, []MatchRecord{matches[2], matches[3], matches[0], matches[1]})
+
+	for _, player := range []string{"p1", "p2", "p3"} {
+		a := incremental.Rating(GameTypeOneVsOne, player, now)
+		b := recomputed.Rating(GameTypeOneVsOne, player, now)
+		if !almostEqual(a.Rating, b.Rating) || a.Wins != b.Wins || a.Losses != b.Losses || a.Streak != b.Streak {
+			t.Errorf("Ratings of %s differ: incremental %+v, recomputed %+v", player, a, b)
+		}
+	}
+
+	standings := recomputed.Standings(GameTypeOneVsOne, now)
+	if len(standings) != 3 || standings[0].Player != "p1" {
+		t.Errorf("Expected p1 to lead the standings, got %+v", standings)
+	}
+}
+
+// TestRatingsRejectMatchesOutOfOrder verifies that a match that started before the latest rated match isn't rated
+// live, so that the ratings after a recompute of the history are the same as before.
+func TestRatingsRejectMatchesOutOfOrder(t *testing.T) {
+	now := time.Now()
+	earlier := recordedMatch(GameTypeOneVsOne, []string{"p1"}, []string{"p2"}, 0, now)
+	later := recordedMatch(GameTypeOneVsOne, []string{"p2"}, []string{"p3"}, 0, now.Add(time.Minute))
+
+	ratings := NewRatings(DefaultRatingConfig)
+	if !ratings.Apply(later) {
+		t.Fatal("Expected the first match to be rated")
+	}
+	if ratings.Apply(earlier) {
+		t.Fatal("Expected the match that started earlier not to be rated live")
+	}
+	if r := ratings.Rating(GameTypeOneVsOne, "p2", now); r.Wins != 1 || r.Losses != 0 || !r.LastPlayed.Equal(later.StartedAt) {
+		t.Errorf("Expected the rejected match to leave the ratings untouched, got %+v", r)
+	}
+
+	ratings.Recompute([]MatchRecord{later, earlier})
+	inOrder := ComputeRatings(DefaultRatingConfig, []MatchRecord{earlier, later})
+	for _, player := range []string{"p1", "p2", "p3"} {
+		a := ratings.Rating(GameTypeOneVsOne, player, now)
+		b := inOrder.Rating(GameTypeOneVsOne, player, now)
+		if !almostEqual(a.Rating, b.Rating) || a.Streak != b.Streak || !a.LastPlayed.Equal(b.LastPlayed) {
+			t.Errorf("Ratings of %s differ: recomputed %+v, in order %+v", player, a, b)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// RetryConfig configures how calls of the Slack API are retried and when Slack is considered down.
+type RetryConfig struct {
+	MaxAttempts      int           // attempts of a call, including the first one
+	BaseDelay        time.Duration // delay before the first retry, doubled for every further one
+	MaxDelay         time.Duration // upper bound of the delay between two attempts, also for rate limits
+	BreakerThreshold int           // consecutive failed calls after which the circuit breaker opens
+	BreakerCooldown  time.Duration // how long the open circuit breaker rejects calls before it tries Slack again
+}
+
+// DefaultRetryConfig retries a call for a few seconds, which keeps the answers to button clicks timely.
+var DefaultRetryConfig = RetryConfig{
+	MaxAttempts:      4,
+	BaseDelay:        250 * time.Millisecond,
+	MaxDelay:         4 * time.Second,
+	BreakerThreshold: 5,
+	BreakerCooldown:  30 * time.Second,
+}
+
+// errCircuitOpen is returned without calling Slack while the circuit breaker is open.
+var errCircuitOpen = errors.New("slack is unavailable, circuit breaker is open")
+
+// retryingClient is a SlackClient that retries calls failing with transient errors, waiting with jittered
+// exponential backoff or as long as Slack asks for when it rate limits the bot, unless that is longer than the
+// maximum delay. Calls that post messages are
+// only retried if Slack surely didn't process them, i.e. on rate limits, so that a lost response doesn't post a
+// message twice. After too many consecutive failures the circuit breaker opens and calls fail right away until
+// Slack answers again.
+type retryingClient struct {
+	client  SlackClient
+	config  RetryConfig
+	breaker *circuitBreaker
+}
+
+func newRetryingClient(client SlackClient, config RetryConfig) *retryingClient {
+	return &retryingClient{
+		client:  client,
+		config:  config,
+		breaker: &circuitBreaker{threshold: config.BreakerThreshold, cooldown: config.BreakerCooldown},
+	}
+}
+
+// do runs the call until it succeeds, fails permanently, runs out of attempts or the context is done. A retry that
+// would only happen after the deadline of the context isn't attempted.
+func (client *retryingClient) do(ctx context.Context, method string, idempotent bool, call func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err := client.breaker.allow(time.Now()); err != nil {
+			return fmt.Errorf("%s: %w", method, err)
+		}
+		err = call(ctx)
+		client.breaker.record(err, time.Now())
+
+		delay, retryable := client.retryDelay(err, attempt, idempotent)
+		if !retryable || attempt >= client.config.MaxAttempts {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return err
+		}
+		slog.Warn("Retrying Slack API call", "method", method, "attempt", attempt, "delay", delay, "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay returns how long to wait before the next attempt of a failed call and whether it is retried at all.
+// Calls that Slack asks to wait for longer than the maximum delay fail right away, since an earlier retry would
+// only be rate limited again.
+func (client *retryingClient) retryDelay(err error, attempt int, idempotent bool) (time.Duration, bool) {
+	if err == nil {
+		return 0, false
+	}
+	var rateLimited *slack.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		return rateLimited.RetryAfter, rateLimited.RetryAfter <= client.config.MaxDelay
+	}
+	if !idempotent || !transient(err) {
+		return 0, false
+	}
+	backoff := min(client.config.BaseDelay<<(attempt-1), client.config.MaxDelay)
+	// full jitter spreads the retries of calls that failed at the same time
+	return rand.N(backoff + 1), true
+}
+
+// transient reports whether a call failed because Slack or the network was unavailable, rather than because Slack
+// refused it, e.g. for a deleted channel. Rate limits aren't counted, since Slack is available when it sends them.
+func transient(err error) bool {
+	var statusCode slack.StatusCodeError
+	if errors.As(err, &statusCode) {
+		return statusCode.Code >= 500
+	}
+	var slackErr slack.SlackErrorResponse
+	if errors.As(err, &slackErr) {
+		switch slackErr.Err {
+		case "internal_error", "fatal_error", "service_unavailable", "request_timeout":
+			return true
+		}
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// circuitBreaker counts consecutive transient failures. Once they reach the threshold the breaker opens and
+// rejects calls for the cooldown. Afterwards it lets a single call through: its success closes the breaker again,
+// its failure reopens it.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time // zero while the breaker is closed
+	probing   bool      // a trial call is in flight after the cooldown
+	mu        sync.Mutex
+}
+
+// allow returns errCircuitOpen if the call must not be made.
+func (breaker *circuitBreaker) allow(now time.Time) error {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	if breaker.openedAt.IsZero() {
+		return nil
+	}
+	if now.Sub(breaker.openedAt) < breaker.cooldown || breaker.probing {
+		return errCircuitOpen
+	}
+	breaker.probing = true
+	return nil
+}
+
+// record counts the result of a call.
+func (breaker *circuitBreaker) record(err error, now time.Time) {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	breaker.probing = false
+	if err == nil || !transient(err) {
+		if !breaker.openedAt.IsZero() {
+			slog.Info("Slack is available again, closing circuit breaker")
+		}
+		breaker.failures = 0
+		breaker.openedAt = time.Time{}
+		return
+	}
+	breaker.failures++
+	if breaker.failures >= breaker.threshold {
+		if breaker.openedAt.IsZero() {
+			slog.Error("Slack is unavailable, opening circuit breaker", "failures", breaker.failures, "error", err)
+		}
+		breaker.openedAt = now
+	}
+}
+
+func (client *retryingClient) PostEphemeral(channelID, userID string, options ...slack.MsgOption) (string, error) {
+	var timestamp string
+	err := client.do(context.Background(), "chat.postEphemeral", false, func(context.Context) (err error) {
+		timestamp, err = client.client.PostEphemeral(channelID, userID, options...)
+		return err
+	})
+	return timestamp, err
+}
+
+func (client *retryingClient) PostMessage(channelID string, options ...slack.MsgOption) (string, string, error) {
+	var channel, timestamp string
+	err := client.do(context.Background(), "chat.postMessage", false, func(context.Context) (err error) {
+		channel, timestamp, err = client.client.PostMessage(channelID, options...)
+		return err
+	})
+	return channel, timestamp, err
+}
+
+func (client *retryingClient) ScheduleMessage(channelID, postAt string, options ...slack.MsgOption) (string, string, error) {
+	var channel, scheduledMessageID string
+	err := client.do(context.Background(), "chat.scheduleMessage", false, func(context.Context) (err error) {
+		channel, scheduledMessageID, err = client.client.ScheduleMessage(channelID, postAt, options...)
+		return err
+	})
+	return channel, scheduledMessageID, err
+}
+
+func (client *retryingClient) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+	var channel, ts, text string
+	err := client.do(context.Background(), "chat.update", true, func(context.Context) (err error) {
+		channel, ts, text, err = client.client.UpdateMessage(channelID, timestamp, options...)
+		return err
+	})
+	return channel, ts, text, err
+}
+
+func (client *retryingClient) DeleteMessage(channel, messageTimestamp string) (string, string, error) {
+	var respChannel, timestamp string
+	err := client.do(context.Background(), "chat.delete", true, func(context.Context) (err error) {
+		respChannel, timestamp, err = client.client.DeleteMessage(channel, messageTimestamp)
+		return err
+	})
+	return respChannel, timestamp, err
+}
+
+func (client *retryingClient) OpenView(triggerID string, view slack.ModalViewRequest) (*slack.ViewResponse, error) {
+	// trigger IDs expire after three seconds, so a retry after a lost response would fail anyway
+	var response *slack.ViewResponse
+	err := client.do(context.Background(), "views.open", false, func(context.Context) (err error) {
+		response, err = client.client.OpenView(triggerID, view)
+		return err
+	})
+	return response, err
+}
+
+func (client *retryingClient) GetUserInfo(user string) (*slack.User, error) {
+	var info *slack.User
+	err := client.do(context.Background(), "users.info", true, func(context.Context) (err error) {
+		info, err = client.client.GetUserInfo(user)
+		return err
+	})
+	return info, err
+}
+
+func (client *retryingClient) DeleteMessageContext(ctx context.Context, channel, messageTimestamp string) (string, string, error) {
+	var respChannel, timestamp string
+	err := client.do(ctx, "chat.delete", true, func(ctx context.Context) (err error) {
+		respChannel, timestamp, err = client.client.DeleteMessageContext(ctx, channel, messageTimestamp)
+		return err
+	})
+	return respChannel, timestamp, err
+}
+
+func (client *retryingClient) AuthTestContext(ctx context.Context) (*slack.AuthTestResponse, error) {
+	var response *slack.AuthTestResponse
+	err := client.do(ctx, "auth.test", true, func(ctx context.Context) (err error) {
+		response, err = client.client.AuthTestContext(ctx)
+		return err
+	})
+	return response, err
+}
+
+// compile-time assertion to ensure that `retryingClient` implements `SlackClient`
+var _ SlackClient = (*retryingClient)(nil)
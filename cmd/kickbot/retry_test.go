@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/mock/gomock"
+)
+
+// testRetryConfig retries quickly and never opens the circuit breaker.
+var testRetryConfig = RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, BreakerThreshold: 100, BreakerCooldown: time.Minute}
+
+// TestRetryTransientErrors verifies that idempotent calls are retried on server errors, that posts are not and
+// that errors Slack answers with are never retried.
+func TestRetryTransientErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	client := newRetryingClient(mockSlackClient, testRetryConfig)
+	unavailable := slack.StatusCodeError{Code: 503, Status: "503 Service Unavailable"}
+
+	gomock.InOrder(
+		mockSlackClient.EXPECT().
+			UpdateMessage("C1", "ts", gomock.Any()).
+			Return("", "", "", unavailable).Times(2),
+		mockSlackClient.EXPECT().
+			UpdateMessage("C1", "ts", gomock.Any()).
+			Return("C1", "ts", "text", nil).Times(1),
+	)
+	if _, _, _, err := client.UpdateMessage("C1", "ts"); err != nil {
+		t.Errorf("Expected the update to succeed on the third attempt, got %v", err)
+	}
+
+	// the message might have been posted although the response was lost
+	mockSlackClient.EXPECT().
+		PostMessage("C1", gomock.Any()).
+		Return("", "", unavailable).Times(1)
+	if _, _, err := client.PostMessage("C1"); !errors.As(err, &slack.StatusCodeError{}) {
+		t.Errorf("Expected the server error, got %v", err)
+	}
+
+	mockSlackClient.EXPECT().
+		DeleteMessage("C1", "ts").
+		Return("", "", slack.SlackErrorResponse{Err: "message_not_found"}).Times(1)
+	if _, _, err := client.DeleteMessage("C1", "ts"); err == nil {
+		t.Error("Expected the error of Slack")
+	}
+}
+
+// TestRetryRateLimits verifies that rate limited calls wait as long as Slack asks for, unless that exceeds the
+// maximum delay or the deadline of their context.
+func TestRetryRateLimits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	config := testRetryConfig
+	config.MaxDelay = 50 * time.Millisecond
+	client := newRetryingClient(mockSlackClient, config)
+
+	gomock.InOrder(
+		mockSlackClient.EXPECT().
+			PostMessage("C1", gomock.Any()).
+			Return("", "", &slack.RateLimitedError{RetryAfter: 20 * time.Millisecond}).Times(1),
+		mockSlackClient.EXPECT().
+			PostMessage("C1", gomock.Any()).
+			Return("C1", "ts", nil).Times(1),
+	)
+	start := time.Now()
+	if _, _, err := client.PostMessage("C1"); err != nil {
+		t.Errorf("Expected the post to succeed after the rate limit, got %v", err)
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("Expected the retry to wait for the rate limit, waited %s", waited)
+	}
+
+	mockSlackClient.EXPECT().
+		UpdateMessage("C1", "ts", gomock.Any()).
+		Return("", "", "", &slack.RateLimitedError{RetryAfter: time.Hour}).Times(1)
+	start = time.Now()
+	if _, _, _, err := client.UpdateMessage("C1", "ts"); !errors.As(err, new(*slack.RateLimitedError)) {
+		t.Errorf("Expected the rate limit error, got %v", err)
+	}
+	if waited := time.Since(start); waited > 500*time.Millisecond {
+		t.Errorf("Expected the call to give up instead of waiting longer than the maximum delay, waited %s", waited)
+	}
+
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), "C1", "ts").
+		Return("", "", &slack.RateLimitedError{RetryAfter: 40 * time.Millisecond}).Times(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, _, err := client.DeleteMessageContext(ctx, "C1", "ts"); err == nil {
+		t.Error("Expected the rate limit error")
+	}
+	if waited := time.Since(start); waited >= 40*time.Millisecond {
+		t.Errorf("Expected the call to give up before its deadline, waited %s", waited)
+	}
+}
+
+// TestCircuitBreaker verifies that the circuit breaker rejects calls after consecutive failures and lets a trial
+// call through after the cooldown, whose success closes it again.
+func TestCircuitBreaker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	config := RetryConfig{MaxAttempts: 1, BreakerThreshold: 2, BreakerCooldown: 50 * time.Millisecond}
+	client := newRetryingClient(mockSlackClient, config)
+
+	mockSlackClient.EXPECT().
+		GetUserInfo("U1").
+		Return(nil, slack.StatusCodeError{Code: 502, Status: "502 Bad Gateway"}).Times(2)
+	for range 2 {
+		client.GetUserInfo("U1")
+	}
+	if _, err := client.GetUserInfo("U1"); !errors.Is(err, errCircuitOpen) {
+		t.Errorf("Expected the open circuit breaker to reject the call, got %v", err)
+	}
+
+	time.Sleep(config.BreakerCooldown)
+	mockSlackClient.EXPECT().
+		GetUserInfo("U1").
+		Return(&slack.User{ID: "U1"}, nil).Times(2)
+	for range 2 {
+		if _, err := client.GetUserInfo("U1"); err != nil {
+			t.Errorf("Expected the circuit breaker to close after the cooldown, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and calls functions later. The game managers use the wall clock, tests use a fake clock
+// that they advance themselves.
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) ClockTimer
+}
+
+// ClockTimer is a call armed with Clock.AfterFunc.
+type ClockTimer interface {
+	Stop() bool
+}
+
+// wallClock is the Clock of the time package.
+type wallClock struct{}
+
+func (wallClock) Now() time.Time { return time.Now() }
+
+func (wallClock) AfterFunc(d time.Duration, f func()) ClockTimer { return time.AfterFunc(d, f) }
+
+// jobID identifies a job of a scheduler, e.g. "timeout/3fa2c1" for the timeout of a game request.
+type jobID string
+
+func timeoutJob(id LobbyID) jobID    { return jobID("timeout/" + id) }
+func warningJob(id LobbyID) jobID    { return jobID("warning/" + id) }
+func announceJob(id LobbyID) jobID   { return jobID("announce/" + id) }
+func tableJob(name string) jobID     { return jobID("table/" + name) }
+func reminderJob(match string) jobID { return jobID("reminder/" + match) }
+
+// overdueLimit is how late a job may run before the scheduler counts as stuck, see check.
+const overdueLimit = time.Minute
+
+var errSchedulerStopped = errors.New("scheduler is stopped")
+
+// scheduler runs the timed jobs of a game manager, e.g. the timeouts of game requests, the announcements of
+// scheduled games, the reminders of their players and the release of busy tables. It keeps the jobs in a min-heap by
+// their time and arms a single timer of its clock for the earliest one, however many jobs wait. Jobs that are due
+// at the same time run concurrently, each on its own goroutine, so that a slow job doesn't hold up the others.
+//
+// Jobs are scheduled, rescheduled and cancelled by their ID. A job may still run shortly after it was cancelled or
+// rescheduled if it was already due, so jobs check that they are still needed.
+type scheduler struct {
+	clock   Clock
+	jobs    jobHeap
+	byID    map[jobID]*job
+	timer   ClockTimer // armed for the earliest job
+	timerAt time.Time
+	stopped bool
+	mu      sync.Mutex
+}
+
+type job struct {
+	id    jobID
+	at    time.Time
+	every time.Duration // interval of recurring jobs, zero for jobs that run once
+	run   func()
+	index int // in the heap
+}
+
+func newScheduler(clock Clock) *scheduler {
+	return &scheduler{clock: clock, byID: make(map[jobID]*job)}
+}
+
+// schedule schedules the job to run at the given time, replacing the job with the same ID. Jobs whose time passed run
+// right away.
+func (sched *scheduler) schedule(id jobID, at time.Time, run func()) {
+	sched.add(&job{id: id, at: at, run: run})
+}
+
+// every schedules the job to run every interval from now on, replacing the job with the same ID.
+func (sched *scheduler) every(id jobID, interval time.Duration, run func()) {
+	sched.add(&job{id: id, at: sched.clock.Now().Add(interval), every: interval, run: run})
+}
+
+func (sched *scheduler) add(next *job) {
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+
+	if sched.stopped {
+		return
+	}
+	if current, exists := sched.byID[next.id]; exists {
+		heap.Remove(&sched.jobs, current.index)
+	}
+	sched.byID[next.id] = next
+	heap.Push(&sched.jobs, next)
+	sched.arm()
+}
+
+// cancel removes the job with the ID and reports whether it was scheduled.
+func (sched *scheduler) cancel(id jobID) bool {
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+
+	current, exists := sched.byID[id]
+	if !exists {
+		return false
+	}
+	heap.Remove(&sched.jobs, current.index)
+	delete(sched.byID, id)
+	sched.arm()
+	return true
+}
+
+// when returns the time the job with the ID is scheduled for.
+func (sched *scheduler) when(id jobID) (time.Time, bool) {
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+
+	if current, exists := sched.byID[id]; exists {
+		return current.at, true
+	}
+	return time.Time{}, false
+}
+
+// stop drops all jobs. Jobs scheduled afterwards never run.
+func (sched *scheduler) stop() {
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+
+	sched.stopped = true
+	sched.jobs = nil
+	clear(sched.byID)
+	if sched.timer != nil {
+		sched.timer.Stop()
+		sched.timer = nil
+	}
+}
+
+// check fails if the scheduler was stopped or its earliest job should have run a while ago, which means that the
+// jobs are no longer run.
+func (sched *scheduler) check() error {
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+
+	if sched.stopped {
+		return errSchedulerStopped
+	}
+	if len(sched.jobs) > 0 {
+		if overdue := sched.clock.Now().Sub(sched.jobs[0].at); overdue > overdueLimit {
+			return fmt.Errorf("job %s is overdue by %v", sched.jobs[0].id, overdue)
+		}
+	}
+	return nil
+}
+
+// arm arms the timer for the earliest job. The caller must hold the lock of the scheduler.
+func (sched *scheduler) arm() {
+	if len(sched.jobs) == 0 {
+		if sched.timer != nil {
+			sched.timer.Stop()
+			sched.timer = nil
+		}
+		return
+	}
+	earliest := sched.jobs[0].at
+	if sched.timer != nil {
+		if sched.timerAt.Equal(earliest) {
+			return
+		}
+		sched.timer.Stop()
+	}
+	sched.timerAt = earliest
+	sched.timer = sched.clock.AfterFunc(earliest.Sub(sched.clock.Now()), sched.fire)
+}
+
+// fire runs the jobs that are due and waits for them. Recurring jobs are scheduled for their next run first.
+func (sched *scheduler) fire() {
+	sched.mu.Lock()
+	now := sched.clock.Now()
+	var due []*job
+	for len(sched.jobs) > 0 && !sched.jobs[0].at.After(now) {
+		next := sched.jobs[0]
+		due = append(due, next)
+		if next.every > 0 {
+			next.at = now.Add(next.every)
+			heap.Fix(&sched.jobs, 0)
+			continue
+		}
+		heap.Pop(&sched.jobs)
+		delete(sched.byID, next.id)
+	}
+	// the timer may have been armed for a later job in the meantime
+	if sched.timer != nil {
+		sched.timer.Stop()
+		sched.timer = nil
+	}
+	if !sched.stopped {
+		sched.arm()
+	}
+	sched.mu.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(len(due))
+	for _, next := range due {
+		go func(run func()) {
+			defer wg.Done()
+			run()
+		}(next.run)
+	}
+	wg.Wait()
+}
+
+// jobHeap orders jobs by their time, see container/heap.
+type jobHeap []*job
+
+func (jobs jobHeap) Len() int           { return len(jobs) }
+func (jobs jobHeap) Less(i, j int) bool { return jobs[i].at.Before(jobs[j].at) }
+
+func (jobs jobHeap) Swap(i, j int) {
+	jobs[i], jobs[j] = jobs[j], jobs[i]
+	jobs[i].index = i
+	jobs[j].index = j
+}
+
+func (jobs *jobHeap) Push(x any) {
+	next := x.(*job)
+	next.index = len(*jobs)
+	*jobs = append(*jobs, next)
+}
+
+func (jobs *jobHeap) Pop() any {
+	old := *jobs
+	last := old[len(old)-1]
+	old[len(old)-1] = nil
+	*jobs = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only moves when a test advances it. The calls that are due run synchronously
+// during Advance, so a test sees their effects once Advance returns.
+type fakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+	mu     sync.Mutex
+}
+
+type fakeTimer struct {
+	clock *fakeClock
+	at    time.Time
+	f     func()
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (clock *fakeClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	return clock.now
+}
+
+func (clock *fakeClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	timer := &fakeTimer{clock: clock, at: clock.now.Add(d), f: f}
+	clock.timers = append(clock.timers, timer)
+	return timer
+}
+
+func (timer *fakeTimer) Stop() bool {
+	timer.clock.mu.Lock()
+	defer timer.clock.mu.Unlock()
+
+	i := slices.Index(timer.clock.timers, timer)
+	if i < 0 {
+		return false
+	}
+	timer.clock.timers = slices.Delete(timer.clock.timers, i, i+1)
+	return true
+}
+
+// Advance moves the time forward by d and runs the calls that become due on the way, the earliest first.
+func (clock *fakeClock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	until := clock.now.Add(d)
+	for {
+		i := -1
+		for j, timer := range clock.timers {
+			if !timer.at.After(until) && (i < 0 || timer.at.Before(clock.timers[i].at)) {
+				i = j
+			}
+		}
+		if i < 0 {
+			break
+		}
+		timer := clock.timers[i]
+		clock.timers = slices.Delete(clock.timers, i, i+1)
+		if timer.at.After(clock.now) {
+			clock.now = timer.at
+		}
+		clock.mu.Unlock()
+		timer.f()
+		clock.mu.Lock()
+	}
+	clock.now = until
+	clock.mu.Unlock()
+}
+
+// TestSchedulerRunsJobsInOrder verifies that jobs run at their time in the order of their times, however they were
+// scheduled, and that rescheduled and cancelled jobs follow their latest schedule.
+func TestSchedulerRunsJobsInOrder(t *testing.T) {
+	clock := newFakeClock()
+	sched := newScheduler(clock)
+	start := clock.Now()
+
+	var ran []jobID
+	var mu sync.Mutex
+	record := func(id jobID) func() {
+		return func() {
+			mu.Lock()
+			defer mu.Unlock()
+			ran = append(ran, id)
+		}
+	}
+	sched.schedule("c", start.Add(3*time.Minute), record("c"))
+	sched.schedule("a", start.Add(time.Minute), record("a"))
+	sched.schedule("b", start.Add(2*time.Minute), record("b"))
+	sched.schedule("moved", start.Add(30*time.Second), record("moved"))
+	sched.schedule("cancelled", start.Add(90*time.Second), record("cancelled"))
+
+	sched.schedule("moved", start.Add(4*time.Minute), record("moved"))
+	if !sched.cancel("cancelled") {
+		t.Error("Expected the cancelled job to be scheduled")
+	}
+	if at, scheduled := sched.when("moved"); !scheduled || !at.Equal(start.Add(4*time.Minute)) {
+		t.Errorf("Expected the moved job to be scheduled for its new time, got %v", at)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if want := []jobID{"a", "b"}; !slices.Equal(ran, want) {
+		t.Errorf("Expected %v to run after 2 minutes, got %v", want, ran)
+	}
+	clock.Advance(2 * time.Minute)
+	if want := []jobID{"a", "b", "c", "moved"}; !slices.Equal(ran, want) {
+		t.Errorf("Expected %v to run after 4 minutes, got %v", want, ran)
+	}
+	if _, scheduled := sched.when("a"); scheduled {
+		t.Error("Expected a job to be dropped once it ran")
+	}
+}
+
+// TestSchedulerRunsRecurringJobs verifies that recurring jobs run every interval until they are cancelled.
+func TestSchedulerRunsRecurringJobs(t *testing.T) {
+	clock := newFakeClock()
+	sched := newScheduler(clock)
+
+	runs := 0
+	sched.every("prune", time.Hour, func() { runs++ })
+
+	clock.Advance(30 * time.Minute)
+	if runs != 0 {
+		t.Errorf("Expected the job not to run before its interval passed, got %d runs", runs)
+	}
+	for range 3 {
+		clock.Advance(time.Hour)
+	}
+	if runs != 3 {
+		t.Errorf("Expected the job to run every hour, got %d runs", runs)
+	}
+
+	sched.cancel("prune")
+	clock.Advance(time.Hour)
+	if runs != 3 {
+		t.Errorf("Expected the cancelled job not to run again, got %d runs", runs)
+	}
+}
+
+// TestSchedulerRunsDueJobsConcurrently verifies that a job that blocks doesn't hold up the jobs that are due at the
+// same time or later, so that a lobby waiting for Slack doesn't delay the timeouts of the others.
+func TestSchedulerRunsDueJobsConcurrently(t *testing.T) {
+	sched := newScheduler(wallClock{})
+	release := make(chan struct{})
+	defer close(release)
+
+	done := make(chan jobID, 2)
+	now := time.Now()
+	sched.schedule("blocked", now, func() { <-release })
+	sched.schedule("same time", now, func() { done <- "same time" })
+	sched.schedule("later", now.Add(10*time.Millisecond), func() { done <- "later" })
+
+	for range 2 {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Expected the jobs to run while another one is blocked")
+		}
+	}
+}
+
+// TestSchedulerCheck verifies that the check of the scheduler fails once it was stopped or its jobs stopped
+// running, and that stopped schedulers drop their jobs.
+func TestSchedulerCheck(t *testing.T) {
+	clock := newFakeClock()
+	sched := newScheduler(clock)
+
+	ran := false
+	sched.schedule("job", clock.Now().Add(time.Minute), func() { ran = true })
+	if err := sched.check(); err != nil {
+		t.Errorf("Expected the check to pass, got %v", err)
+	}
+
+	// the clock moves on without running the timer, as if the scheduler were stuck
+	clock.mu.Lock()
+	clock.now = clock.now.Add(time.Minute + overdueLimit + time.Second)
+	clock.mu.Unlock()
+	if err := sched.check(); err == nil {
+		t.Error("Expected the check to fail for an overdue job")
+	}
+
+	sched.stop()
+	if err := sched.check(); err != errSchedulerStopped {
+		t.Errorf("Expected the check to fail with %v, got %v", errSchedulerStopped, err)
+	}
+	sched.schedule("late", clock.Now(), func() { ran = true })
+	clock.Advance(time.Hour)
+	if ran {
+		t.Error("Expected the jobs of a stopped scheduler not to run")
+	}
+}
@@ -32,9 +32,21 @@ type SlackClient interface {
 	// Returns the channel and timestamp of the deleted message or an error.
 	DeleteMessage(channel, messageTimestamp string) (string, string, error)
 
+	// OpenView opens a modal view for the user who triggered the interaction identified by triggerID.
+	// Returns the opened view or an error.
+	OpenView(triggerID string, view slack.ModalViewRequest) (*slack.ViewResponse, error)
+
+	// GetUserInfo returns the profile of a user, including the locale the user picked in Slack.
+	// Returns the user or an error.
+	GetUserInfo(user string) (*slack.User, error)
+
 	// DeleteMessage removes a message from a Slack channel with a custom context.
 	// Returns the channel and timestamp of the deleted message or an error.
 	DeleteMessageContext(ctx context.Context, channel, messageTimestamp string) (string, string, error)
+
+	// AuthTestContext checks the token of the client with a custom context.
+	// Returns the team and user the token belongs to or an error if Slack rejects it.
+	AuthTestContext(ctx context.Context) (*slack.AuthTestResponse, error)
 }
 
 // compile-time assertion to ensure that `slack.Client` implements `SlackClient`
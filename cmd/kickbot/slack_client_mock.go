@@ -40,6 +40,21 @@ func (m *MockSlackClient) EXPECT() *MockSlackClientMockRecorder {
 	return m.recorder
 }
 
+// AuthTestContext mocks base method.
+func (m *MockSlackClient) AuthTestContext(ctx context.Context) (*slack.AuthTestResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthTestContext", ctx)
+	ret0, _ := ret[0].(*slack.AuthTestResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthTestContext indicates an expected call of AuthTestContext.
+func (mr *MockSlackClientMockRecorder) AuthTestContext(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthTestContext", reflect.TypeOf((*MockSlackClient)(nil).AuthTestContext), ctx)
+}
+
 // DeleteMessage mocks base method.
 func (m *MockSlackClient) DeleteMessage(channel, messageTimestamp string) (string, string, error) {
 	m.ctrl.T.Helper()
@@ -72,6 +87,36 @@ func (mr *MockSlackClientMockRecorder) DeleteMessageContext(ctx, channel, messag
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessageContext", reflect.TypeOf((*MockSlackClient)(nil).DeleteMessageContext), ctx, channel, messageTimestamp)
 }
 
+// GetUserInfo mocks base method.
+func (m *MockSlackClient) GetUserInfo(user string) (*slack.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserInfo", user)
+	ret0, _ := ret[0].(*slack.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserInfo indicates an expected call of GetUserInfo.
+func (mr *MockSlackClientMockRecorder) GetUserInfo(user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserInfo", reflect.TypeOf((*MockSlackClient)(nil).GetUserInfo), user)
+}
+
+// OpenView mocks base method.
+func (m *MockSlackClient) OpenView(triggerID string, view slack.ModalViewRequest) (*slack.ViewResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenView", triggerID, view)
+	ret0, _ := ret[0].(*slack.ViewResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenView indicates an expected call of OpenView.
+func (mr *MockSlackClientMockRecorder) OpenView(triggerID, view any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenView", reflect.TypeOf((*MockSlackClient)(nil).OpenView), triggerID, view)
+}
+
 // PostEphemeral mocks base method.
 func (m *MockSlackClient) PostEphemeral(channelID, userID string, options ...slack.MsgOption) (string, error) {
 	m.ctrl.T.Helper()
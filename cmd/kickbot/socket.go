@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// runSocketMode receives slash commands, interactions and events over a Socket Mode websocket instead of the public
+// HTTP endpoints, until the context is cancelled. The requests take the same paths through the game manager as
+// the ones of the HTTP handlers and are acknowledged once they were handled, like HTTP requests are answered. Slash
+// commands are acknowledged once they were queued, see queueCommand.
+// The client needs an app-level token, see slack.OptionAppLevelToken.
+func runSocketMode(ctx context.Context, ws *Workspaces, client *socketmode.Client) error {
+	go handleSocketEvents(ctx, ws, client)
+
+	err := client.RunContext(ctx)
+	if ctx.Err() != nil {
+		// the client fails to reconnect after it was stopped
+		return nil
+	}
+	return err
+}
+
+// handleSocketEvents handles the events of the Socket Mode client until the context is cancelled. Like the HTTP
+// server does with requests, every request is handled on its own goroutine.
+func handleSocketEvents(ctx context.Context, ws *Workspaces, client *socketmode.Client) {
+	dedup := newEventDeduplicator(eventDedupWindow)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case evt := <-client.Events:
+			go handleSocketEvent(ws, client, dedup, evt)
+		}
+	}
+}
+
+// handleSocketEvent dispatches a request received over Socket Mode and acknowledges it.
+func handleSocketEvent(ws *Workspaces, client *socketmode.Client, dedup *eventDeduplicator, evt socketmode.Event) {
+	switch evt.Type {
+	case socketmode.EventTypeConnecting:
+		slog.Info("Connecting to Slack with Socket Mode")
+	case socketmode.EventTypeConnected:
+		slog.Info("Connected to Slack with Socket Mode")
+	case socketmode.EventTypeConnectionError, socketmode.EventTypeInvalidAuth, socketmode.EventTypeIncomingError:
+		slog.Error("Socket Mode connection failed", "type", evt.Type, "error", evt.Data)
+	case socketmode.EventTypeSlashCommand:
+		if cmd, ok := evt.Data.(slack.SlashCommand); !ok {
+			slog.Warn("Invalid slash command payload", "data", evt.Data)
+		} else if gm, err := ws.Manager(Workspace{EnterpriseID: cmd.EnterpriseID, TeamID: cmd.TeamID}); err != nil {
+			slog.Warn("Received a command from another workspace", "error", err)
+		} else if !dedup.firstDelivery(commandID(cmd), time.Now()) && evt.Request.RetryAttempt > 0 {
+			slog.Info("Ignoring a retried command", "command", cmd.Command, "retry", evt.Request.RetryAttempt)
+		} else if err := queueCommand(gm, cmd); errors.Is(err, errCommandsBusy) {
+			slog.Warn("Turning away a command, the queue is full", "command", cmd.Command)
+			client.Ack(*evt.Request, commandBusyMsg(gm, cmd))
+			break
+		} else if err != nil {
+			slog.Warn("Recieved an invalid command", "command", cmd.Command)
+		}
+		client.Ack(*evt.Request)
+	case socketmode.EventTypeInteractive:
+		interactionCallback, ok := evt.Data.(slack.InteractionCallback)
+		if !ok {
+			slog.Warn("Invalid interaction payload", "data", evt.Data)
+			client.Ack(*evt.Request)
+			break
+		}
+		gm, err := ws.Manager(interactionWorkspace(interactionCallback))
+		if err != nil {
+			slog.Warn("Received an interaction from another workspace", "error", err)
+			client.Ack(*evt.Request)
+			break
+		}
+		response, err := dispatchInteraction(gm, interactionCallback)
+		if err != nil {
+			slog.Warn("Invalid interaction", "error", err)
+		}
+		// validation errors of modals are sent back with the acknowledgement
+		if response != nil {
+			client.Ack(*evt.Request, response)
+			break
+		}
+		client.Ack(*evt.Request)
+	case socketmode.EventTypeEventsAPI:
+		if event, ok := evt.Data.(slackevents.EventsAPIEvent); !ok {
+			slog.Warn("Invalid event payload", "data", evt.Data)
+		} else if err := dispatchEvent(ws, dedup, event, strconv.Itoa(evt.Request.RetryAttempt)); err != nil {
+			slog.Warn("Received an unsupported event", "error", err)
+		}
+		client.Ack(*evt.Request)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+	"go.uber.org/mock/gomock"
+)
+
+// socketModeStandIn stands in for the Socket Mode endpoints of Slack: apps.connections.open hands out the URL of
+// a websocket, which sends the requests of the test and collects the acknowledgements of the bot.
+type socketModeStandIn struct {
+	server   *httptest.Server
+	requests chan string
+	acks     chan map[string]any
+}
+
+func newSocketModeStandIn(t *testing.T) *socketModeStandIn {
+	standIn := &socketModeStandIn{requests: make(chan string), acks: make(chan map[string]any, 10)}
+	// the Socket Mode client claims to be api.slack.com
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xapp-test" {
+			t.Errorf("Expected the app-level token, got %q", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"ok":true,"url":"ws://%s/link"}`, r.Host)
+	})
+	mux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Failed to upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"hello","num_connections":1}`))
+
+		go func() {
+			for {
+				var ack map[string]any
+				if err := conn.ReadJSON(&ack); err != nil {
+					return
+				}
+				standIn.acks <- ack
+			}
+		}()
+		for {
+			select {
+			case request := <-standIn.requests:
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	standIn.server = httptest.NewServer(mux)
+	return standIn
+}
+
+// send sends a request envelope and waits for its acknowledgement.
+func (standIn *socketModeStandIn) send(t *testing.T, envelopeID, requestType string, payload any, retry int) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := fmt.Sprintf(`{"envelope_id":%q,"type":%q,"payload":%s,"accepts_response_payload":true,"retry_attempt":%d}`, envelopeID, requestType, data, retry)
+	select {
+	case standIn.requests <- request:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the bot to connect to the websocket")
+	}
+
+	select {
+	case ack := <-standIn.acks:
+		if ack["envelope_id"] != envelopeID {
+			t.Fatalf("Expected the acknowledgement of %s, got %v", envelopeID, ack)
+		}
+		return ack
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected %s to be acknowledged", envelopeID)
+		return nil
+	}
+}
+
+// TestSocketMode verifies that slash commands, interactions and events received over a Socket Mode websocket are
+// handled by the game manager and acknowledged.
+func TestSocketMode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	standIn := newSocketModeStandIn(t)
+	defer standIn.server.Close()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient)
+	defer gameMgr.Shutdown(context.TODO())
+
+	api := slack.New("xoxb-test", slack.OptionAppLevelToken("xapp-test"), slack.OptionAPIURL(standIn.server.URL+"/"))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- runSocketMode(ctx, SingleWorkspace(gameMgr), socketmode.New(api))
+	}()
+
+	// the slash command announces a game request
+	mockSlackClient.EXPECT().
+		PostMessage("C1", gomock.Any()).
+		Return("C1", "ts", nil).Times(1)
+	command := slack.SlashCommand{Command: CMD_START_ROUND, ChannelID: "C1", UserID: "U1", TriggerID: "trigger"}
+	standIn.send(t, "command", "slash_commands", command, 0)
+	// a retried delivery of the command is acknowledged without running it again
+	standIn.send(t, "command-retry", "slash_commands", command, 1)
+	// the command is acknowledged once it was queued
+	gameMgr.commands.flush(context.TODO())
+	id := lobbyIn(gameMgr, "C1")
+	if id == "" {
+		t.Fatal("Expected the slash command to create a game request")
+	}
+
+	// the click on the join button updates it
+	mockSlackClient.EXPECT().
+		UpdateMessage("C1", "ts", gomock.Any()).
+		Return("C1", "ts", "text", nil).Times(1)
+	callback := slack.InteractionCallback{Type: slack.InteractionTypeBlockActions}
+	callback.Channel.ID = "C1"
+	callback.User.ID = "U2"
+	callback.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: ACTION_JOIN_ROUND, Value: string(id)}}
+	standIn.send(t, "interaction", "interactive", callback, 0)
+	gameReq, _ := gameMgr.getGameRequest("C1", id)
+	inspectLobby(gameReq, func() {
+		if len(gameReq.players) != 2 {
+			t.Errorf("Expected the interaction to add a player, got %v", gameReq.players)
+		}
+	})
+
+	// the mention is answered once, although Slack retries it
+	mockSlackClient.EXPECT().
+		PostEphemeral("C1", "U3", gomock.Any()).
+		Return("timestamp", nil).Times(1)
+	mention := json.RawMessage(`{"type":"event_callback","team_id":"T1","event_id":"Ev1","event_time":1,` +
+		`"event":{"type":"app_mention","user":"U3","text":"<@B1>","ts":"1.1","channel":"C1","event_ts":"1.1"}}`)
+	standIn.send(t, "event", "events_api", mention, 0)
+	standIn.send(t, "event-retry", "events_api", mention, 1)
+	gameMgr.commands.flush(context.TODO())
+
+	// validation errors of modals are sent back with the acknowledgement
+	submission := slack.InteractionCallback{Type: slack.InteractionTypeViewSubmission}
+	submission.User.ID = "U1"
+	submission.View.CallbackID = VIEW_RECORD_RESULT
+	submission.View.PrivateMetadata = "unknown-match"
+	submission.View.State = &slack.ViewState{}
+	ack := standIn.send(t, "submission", "interactive", submission, 0)
+	if payload, _ := json.Marshal(ack["payload"]); !strings.Contains(string(payload), `"response_action":"errors"`) {
+		t.Errorf("Expected the validation errors in the acknowledgement, got %s", payload)
+	}
+
+	// shut down the game manager's announced game request
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), "C1", "ts").
+		Return("C1", "ts", nil).Times(1)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected Socket Mode to stop without error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected Socket Mode to stop")
+	}
+}
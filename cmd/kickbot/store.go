@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GameStore persists open game requests so that lobbies survive restarts of the bot, as well as the
+// played matches. Implementations must be safe for concurrent use.
+type GameStore interface {
+
+	// SaveGameRequest inserts or replaces the stored game request with the record's lobby ID.
+	SaveGameRequest(record GameRequestRecord) error
+
+	// DeleteGameRequest removes the stored game request with the given lobby ID.
+	// Deleting a game request that doesn't exist is not an error.
+	DeleteGameRequest(id LobbyID) error
+
+	// LoadGameRequests returns all stored game requests.
+	LoadGameRequests() ([]GameRequestRecord, error)
+
+	// SaveMatch inserts or replaces the stored match with the record's ID.
+	SaveMatch(match MatchRecord) error
+
+	// LoadMatches returns all stored matches ordered by their start time.
+	LoadMatches() ([]MatchRecord, error)
+
+	// SavePlayerPosition stores the preferred position of a player.
+	SavePlayerPosition(player string, position Position) error
+
+	// LoadPlayerPositions returns the preferred positions of all players who set one.
+	LoadPlayerPositions() (map[string]Position, error)
+
+	// Ping checks that the store can be written, for the readiness check of the bot.
+	Ping() error
+}
+
+// GameRequestRecord is the persisted snapshot of an open game request.
+type GameRequestRecord struct {
+	ID         LobbyID       `json:"id"`
+	Channel    SlackChannel  `json:"channel"`
+	GameType   GameType      `json:"game_type"`
+	Players    []string      `json:"players"`
+	Waitlist   []string      `json:"waitlist,omitempty"`
+	Quorum     int           `json:"quorum"`
+	MessageTs  string        `json:"message_ts"`
+	Timeout    time.Duration `json:"timeout"`
+	Deadline   time.Time     `json:"deadline"`
+	StartAt    time.Time     `json:"start_at"`
+	AnnounceAt time.Time     `json:"announce_at"`
+	Table      string        `json:"table,omitempty"`
+	QueuedAt   time.Time     `json:"queued_at,omitempty"`
+	CreatedAt  time.Time     `json:"created_at,omitempty"`
+	Team       string        `json:"team,omitempty"`       // set by the store, see FileStore.Workspace
+	Enterprise string        `json:"enterprise,omitempty"` // set by the store, see FileStore.Workspace
+}
+
+// FileStore is a GameStore that keeps the open game requests, the positions and the installations in a JSON file
+// and the match history in a JSON lines file next to it, see matchLogPath.
+// Every change of the game requests rewrites the JSON file atomically, which is plenty for the handful of lobbies a
+// workspace has open. Matches are appended to their file, so that the growing history isn't rewritten whenever a
+// player joins or leaves a lobby.
+// The files hold the game requests and matches of all workspaces the bot is installed in, each FileStore
+// only sees the ones of its workspace, see Workspace.
+type FileStore struct {
+	file      *storeFile
+	workspace Workspace // workspace of the game requests and matches, zero for a bot that serves a single one
+}
+
+// storeFile is the file shared by the stores of all workspaces.
+type storeFile struct {
+	path       string
+	state      fileStoreState
+	matchLog   string                 // path of the match history, see matchLogPath
+	matches    map[string]MatchRecord // by storeKey, the latest line of every match in the match log
+	matchLines int                    // lines in the match log, more than matches once results were recorded
+	mu         sync.Mutex
+}
+
+type fileStoreState struct {
+	GameRequests  map[string]GameRequestRecord `json:"game_requests"`     // by storeKey
+	Matches       map[string]MatchRecord       `json:"matches,omitempty"` // by storeKey, only in files written before the match log
+	Positions     map[string]Position          `json:"positions"`         // user IDs are unique across workspaces
+	Installations map[string]Installation      `json:"installations,omitempty"`
+}
+
+// matchLogPath returns the path of the match history of the store file at the given path, which replaces the
+// extension of the store file, e.g. kickbot.matches.jsonl for kickbot.json.
+func matchLogPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".matches.jsonl"
+}
+
+// NewFileStore opens the store at the given path. The file is created on the first write if it doesn't exist yet.
+func NewFileStore(path string) (*FileStore, error) {
+	file := &storeFile{
+		path: path,
+		state: fileStoreState{
+			GameRequests:  make(map[string]GameRequestRecord),
+			Positions:     make(map[string]Position),
+			Installations: make(map[string]Installation),
+		},
+		matchLog: matchLogPath(path),
+		matches:  make(map[string]MatchRecord),
+	}
+	store := &FileStore{file: file}
+	if err := file.loadMatches(); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store file %q: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &file.state); err != nil {
+		return nil, fmt.Errorf("failed to decode store file %q: %w", path, err)
+	}
+	if file.state.GameRequests == nil {
+		file.state.GameRequests = make(map[string]GameRequestRecord)
+	}
+	// files written before lobbies had IDs are keyed by channel, which is unique among their records
+	for key, record := range file.state.GameRequests {
+		if record.ID == "" {
+			record.ID = LobbyID(key)
+			file.state.GameRequests[key] = record
+		}
+	}
+	if file.state.Positions == nil {
+		file.state.Positions = make(map[string]Position)
+	}
+	if file.state.Installations == nil {
+		file.state.Installations = make(map[string]Installation)
+	}
+	if len(file.state.Matches) > 0 {
+		if err := file.migrateMatches(); err != nil {
+			return nil, fmt.Errorf("failed to move the matches of store file %q to %q: %w", path, file.matchLog, err)
+		}
+	}
+	return store, nil
+}
+
+// loadMatches reads the match log, later lines of a match replace the earlier ones. A last line that was cut off
+// by a crash is dropped. The log is compacted if it holds lines that were replaced or cut off.
+func (file *storeFile) loadMatches() error {
+	data, err := os.ReadFile(file.matchLog)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read match log %q: %w", file.matchLog, err)
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var match MatchRecord
+		if err := json.Unmarshal(line, &match); err != nil {
+			if i == len(lines)-1 {
+				slog.Warn("Dropping the incomplete last line of the match log", "path", file.matchLog, "error", err)
+				file.matchLines++
+				continue
+			}
+			return fmt.Errorf("failed to decode line %d of match log %q: %w", i+1, file.matchLog, err)
+		}
+		file.matches[matchKey(match)] = match
+		file.matchLines++
+	}
+	if file.matchLines > len(file.matches) {
+		return file.compactMatches()
+	}
+	return nil
+}
+
+// matchKey is the storeKey of a match read from the match log.
+func matchKey(match MatchRecord) string {
+	return (&FileStore{workspace: Workspace{TeamID: match.Team}}).storeKey(match.ID)
+}
+
+// migrateMatches moves the matches of a store file written before the match log into the log. The log is written
+// first, so that a crash in between leaves the matches in both files rather than in none.
+func (file *storeFile) migrateMatches() error {
+	for key, match := range file.state.Matches {
+		if _, exists := file.matches[key]; !exists {
+			file.matches[key] = match
+		}
+	}
+	if err := file.compactMatches(); err != nil {
+		return err
+	}
+	file.state.Matches = nil
+	return file.flush()
+}
+
+// Workspace returns the store of the game requests and matches of the workspace, backed by the same file.
+func (store *FileStore) Workspace(workspace Workspace) GameStore {
+	return &FileStore{file: store.file, workspace: workspace}
+}
+
+// storeKey is the key of a game request or match in the file. Lobby IDs are only unique within a workspace, so
+// the keys of workspaces are prefixed with the team ID. Records of a bot that serves a single workspace are keyed
+// by their ID alone, like they were before the bot served several workspaces.
+func (store *FileStore) storeKey(id string) string {
+	if store.workspace.TeamID == "" {
+		return id
+	}
+	return store.workspace.TeamID + "/" + id
+}
+
+func (store *FileStore) SaveGameRequest(record GameRequestRecord) error {
+	store.file.mu.Lock()
+	defer store.file.mu.Unlock()
+
+	record.Team, record.Enterprise = store.workspace.TeamID, store.workspace.EnterpriseID
+	store.file.state.GameRequests[store.storeKey(string(record.ID))] = record
+	return store.file.flush()
+}
+
+func (store *FileStore) DeleteGameRequest(id LobbyID) error {
+	store.file.mu.Lock()
+	defer store.file.mu.Unlock()
+
+	key := store.storeKey(string(id))
+	if _, exists := store.file.state.GameRequests[key]; !exists {
+		return nil
+	}
+	delete(store.file.state.GameRequests, key)
+	return store.file.flush()
+}
+
+func (store *FileStore) LoadGameRequests() ([]GameRequestRecord, error) {
+	store.file.mu.Lock()
+	defer store.file.mu.Unlock()
+
+	records := make([]GameRequestRecord, 0, len(store.file.state.GameRequests))
+	for _, record := range store.file.state.GameRequests {
+		if record.Team == store.workspace.TeamID {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (store *FileStore) SaveMatch(match MatchRecord) error {
+	store.file.mu.Lock()
+	defer store.file.mu.Unlock()
+
+	match.Team = store.workspace.TeamID
+	if err := store.file.appendMatch(match); err != nil {
+		return err
+	}
+	store.file.matches[store.storeKey(match.ID)] = match
+	return nil
+}
+
+func (store *FileStore) LoadMatches() ([]MatchRecord, error) {
+	store.file.mu.Lock()
+	defer store.file.mu.Unlock()
+
+	matches := make([]MatchRecord, 0, len(store.file.matches))
+	for _, match := range store.file.matches {
+		if match.Team == store.workspace.TeamID {
+			matches = append(matches, match)
+		}
+	}
+	slices.SortFunc(matches, func(a, b MatchRecord) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+	return matches, nil
+}
+
+func (store *FileStore) SavePlayerPosition(player string, position Position) error {
+	store.file.mu.Lock()
+	defer store.file.mu.Unlock()
+
+	if position == PositionAny {
+		delete(store.file.state.Positions, player)
+	} else {
+		store.file.state.Positions[player] = position
+	}
+	return store.file.flush()
+}
+
+func (store *FileStore) LoadPlayerPositions() (map[string]Position, error) {
+	store.file.mu.Lock()
+	defer store.file.mu.Unlock()
+
+	return maps.Clone(store.file.state.Positions), nil
+}
+
+// Ping creates and removes a temporary file next to the store file, which fails the same way the next write of
+// the store would, e.g. if its volume was unmounted or is read-only.
+func (store *FileStore) Ping() error {
+	tmp, err := os.CreateTemp(filepath.Dir(store.file.path), filepath.Base(store.file.path)+".ping-*")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+func (store *FileStore) SaveInstallation(installation Installation) error {
+	store.file.mu.Lock()
+	defer store.file.mu.Unlock()
+
+	store.file.state.Installations[installation.key()] = installation
+	return store.file.flush()
+}
+
+func (store *FileStore) LoadInstallations() ([]Installation, error) {
+	store.file.mu.Lock()
+	defer store.file.mu.Unlock()
+
+	installations := make([]Installation, 0, len(store.file.state.Installations))
+	for _, installation := range store.file.state.Installations {
+		installations = append(installations, installation)
+	}
+	return installations, nil
+}
+
+func (store *FileStore) OpenWorkspaces() ([]Workspace, error) {
+	store.file.mu.Lock()
+	defer store.file.mu.Unlock()
+
+	var workspaces []Workspace
+	for _, record := range store.file.state.GameRequests {
+		workspace := Workspace{EnterpriseID: record.Enterprise, TeamID: record.Team}
+		if workspace.TeamID != "" && !slices.Contains(workspaces, workspace) {
+			workspaces = append(workspaces, workspace)
+		}
+	}
+	return workspaces, nil
+}
+
+// flush writes the state to the store file. The caller must hold the lock of the file.
+func (file *storeFile) flush() error {
+	data, err := json.MarshalIndent(file.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(file.path, data)
+}
+
+// appendMatch appends a line with the match to the match log. The caller must hold the lock of the file.
+func (file *storeFile) appendMatch(match MatchRecord) error {
+	data, err := json.Marshal(match)
+	if err != nil {
+		return err
+	}
+	log, err := os.OpenFile(file.matchLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := log.Write(append(data, '\n')); err != nil {
+		log.Close()
+		return err
+	}
+	if err := log.Close(); err != nil {
+		return err
+	}
+	file.matchLines++
+	return nil
+}
+
+// compactMatches rewrites the match log with a single line per match, ordered by their start time. The caller must
+// hold the lock of the file.
+func (file *storeFile) compactMatches() error {
+	matches := make([]MatchRecord, 0, len(file.matches))
+	for _, match := range file.matches {
+		matches = append(matches, match)
+	}
+	slices.SortFunc(matches, func(a, b MatchRecord) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+	var data []byte
+	for _, match := range matches {
+		line, err := json.Marshal(match)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	if err := writeFileAtomic(file.matchLog, data); err != nil {
+		return err
+	}
+	file.matchLines = len(matches)
+	return nil
+}
+
+// writeFileAtomic writes the data to a temporary file and renames it over the file at the given path so that
+// a crash never leaves a half written file behind. The temporary file is only readable by its owner, which
+// keeps the bot tokens of the installations private.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// compile-time assertions to ensure that `FileStore` implements `GameStore` and `WorkspaceStore`
+var (
+	_ GameStore      = (*FileStore)(nil)
+	_ WorkspaceStore = (*FileStore)(nil)
+)
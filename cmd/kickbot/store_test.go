@@ -0,0 +1,248 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFileStoreRoundTrip verifies that saved game requests are loaded again by a new store instance on the same file
+// and that deleted game requests are gone.
+func TestFileStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kickbot.json")
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Minute * 30).Round(time.Second)
+	records := []GameRequestRecord{
+		{ID: "lobby-1", Channel: "channel-1", GameType: GameTypeTwoVsTwo, Players: []string{"p1", "p2"}, Quorum: 4, MessageTs: "ts-1", Deadline: deadline},
+		{ID: "lobby-2", Channel: "channel-2", GameType: GameTypeOneVsOne, Players: []string{"p3"}, Quorum: 2, MessageTs: "ts-2", Deadline: deadline},
+	}
+	for _, record := range records {
+		if err := store.SaveGameRequest(record); err != nil {
+			t.Fatalf("Failed to save game request: %v", err)
+		}
+	}
+	if err := store.DeleteGameRequest("lobby-2"); err != nil {
+		t.Fatalf("Failed to delete game request: %v", err)
+	}
+	if err := store.DeleteGameRequest("missing-lobby"); err != nil {
+		t.Errorf("Deleting a missing game request should not fail, got %v", err)
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	loaded, err := reopened.LoadGameRequests()
+	if err != nil {
+		t.Fatalf("Failed to load game requests: %v", err)
+	}
+	if len(loaded) != 1 {
+		t.Fatalf("Expected 1 game request, found %d", len(loaded))
+	}
+	got := loaded[0]
+	if got.ID != "lobby-1" || got.Channel != "channel-1" || got.GameType != GameTypeTwoVsTwo || got.Quorum != 4 || got.MessageTs != "ts-1" {
+		t.Errorf("Loaded game request %+v doesn't match the saved one", got)
+	}
+	if !slices.Equal(got.Players, []string{"p1", "p2"}) {
+		t.Errorf("Expected players [p1 p2], got %v", got.Players)
+	}
+	if !got.Deadline.Equal(deadline) {
+		t.Errorf("Expected deadline %v, got %v", deadline, got.Deadline)
+	}
+}
+
+func TestFileStoreCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kickbot.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileStore(path); err == nil {
+		t.Error("Expected an error when opening a corrupt store file")
+	}
+}
+
+func TestFileStoreMatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kickbot.json")
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	now := time.Now()
+	later := MatchRecord{ID: "later", Players: []string{"p1", "p2"}, StartedAt: now}
+	earlier := MatchRecord{ID: "earlier", Players: []string{"p3", "p4"}, StartedAt: now.Add(-time.Hour)}
+	store.SaveMatch(later)
+	store.SaveMatch(earlier)
+
+	// Saving a match again replaces the stored record
+	later.Score = [2]int{10, 5}
+	later.RecordedAt = now
+	store.SaveMatch(later)
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	matches, err := reopened.LoadMatches()
+	if err != nil {
+		t.Fatalf("Failed to load matches: %v", err)
+	}
+	if len(matches) != 2 || matches[0].ID != "earlier" || matches[1].ID != "later" {
+		t.Fatalf("Expected matches [earlier later] ordered by start, got %+v", matches)
+	}
+	if !matches[1].IsRecorded() || matches[1].Score != [2]int{10, 5} {
+		t.Errorf("Expected the updated match to be loaded, got %+v", matches[1])
+	}
+}
+
+// TestFileStoreMatchLog verifies that matches are appended to the match log instead of the store file, that the
+// log is compacted when it is opened and that a last line cut off by a crash is dropped.
+func TestFileStoreMatchLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kickbot.json")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	match := MatchRecord{ID: "match-1", Players: []string{"p1", "p2"}, StartedAt: time.Now()}
+	store.SaveMatch(match)
+	match.Score = [2]int{10, 5}
+	store.SaveMatch(match)
+	store.SaveGameRequest(GameRequestRecord{ID: "lobby-1", Channel: "channel-1", Players: []string{"p1"}})
+
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "match-1") {
+		t.Errorf("Expected the store file to hold no matches, got %s", data)
+	}
+	log, err := os.ReadFile(filepath.Join(filepath.Dir(path), "kickbot.matches.jsonl"))
+	if err != nil {
+		t.Fatalf("Failed to read the match log: %v", err)
+	}
+	if lines := strings.Count(string(log), "\n"); lines != 2 {
+		t.Errorf("Expected a line per save in the match log, got %d", lines)
+	}
+
+	// a crash while appending leaves an incomplete line behind
+	if err := os.WriteFile(matchLogPath(path), append(log, `{"id": "match-2", "pla`...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	matches, _ := reopened.LoadMatches()
+	if len(matches) != 1 || matches[0].Score != [2]int{10, 5} {
+		t.Fatalf("Expected the latest line of match-1, got %+v", matches)
+	}
+	if log, _ := os.ReadFile(matchLogPath(path)); strings.Count(string(log), "\n") != 1 || strings.Contains(string(log), "match-2") {
+		t.Errorf("Expected the match log to be compacted to a line per match, got %s", log)
+	}
+}
+
+// TestFileStoreLegacyMatches verifies that the matches of store files written before the match log are moved to
+// the match log.
+func TestFileStoreLegacyMatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kickbot.json")
+	legacy := `{"game_requests": {}, "matches": {"match-1": {"id": "match-1", "players": ["p1", "p2"], "score": [10, 5]}}}`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileStore(path); err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "match-1") {
+		t.Errorf("Expected the matches to be removed from the store file, got %s", data)
+	}
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	if matches, _ := reopened.LoadMatches(); len(matches) != 1 || matches[0].ID != "match-1" || matches[0].Score != [2]int{10, 5} {
+		t.Errorf("Expected the legacy match in the match log, got %+v", matches)
+	}
+}
+
+// TestFileStoreLegacyGameRequests verifies that game requests of store files written before lobbies had IDs
+// get the key they were stored under as lobby ID.
+func TestFileStoreLegacyGameRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kickbot.json")
+	legacy := `{"game_requests": {"channel-1": {"channel": "channel-1", "players": ["p1"], "quorum": 4, "message_ts": "ts-1"}}}`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	loaded, _ := store.LoadGameRequests()
+	if len(loaded) != 1 || loaded[0].ID != "channel-1" || loaded[0].Channel != "channel-1" {
+		t.Fatalf("Expected the legacy game request with lobby ID channel-1, got %+v", loaded)
+	}
+	if err := store.DeleteGameRequest(loaded[0].ID); err != nil {
+		t.Errorf("Failed to delete legacy game request: %v", err)
+	}
+}
+
+// TestFileStoreWorkspaces verifies that the stores of workspaces only see their own game requests and matches,
+// although lobby IDs repeat across workspaces, and that the installations survive a restart.
+func TestFileStoreWorkspaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kickbot.json")
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	alpha := store.Workspace(Workspace{TeamID: "T1"})
+	beta := store.Workspace(Workspace{EnterpriseID: "E1", TeamID: "T2"})
+	for _, workspace := range []GameStore{alpha, beta} {
+		if err := workspace.SaveGameRequest(GameRequestRecord{ID: "lobby-1", Channel: "channel-1", Players: []string{"p1"}}); err != nil {
+			t.Fatalf("Failed to save game request: %v", err)
+		}
+	}
+	if err := beta.SaveMatch(MatchRecord{ID: "match-1", Channel: "channel-1"}); err != nil {
+		t.Fatalf("Failed to save match: %v", err)
+	}
+	if err := alpha.DeleteGameRequest("lobby-1"); err != nil {
+		t.Fatalf("Failed to delete game request: %v", err)
+	}
+	installation := Installation{TeamID: "T1", BotToken: "xoxb-1", BotUserID: "B1"}
+	if err := store.SaveInstallation(installation); err != nil {
+		t.Fatalf("Failed to save installation: %v", err)
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	if loaded, _ := reopened.Workspace(Workspace{TeamID: "T1"}).LoadGameRequests(); len(loaded) != 0 {
+		t.Errorf("Expected the game request of T1 to be deleted, got %v", loaded)
+	}
+	if loaded, _ := reopened.Workspace(Workspace{TeamID: "T2"}).LoadGameRequests(); len(loaded) != 1 || loaded[0].ID != "lobby-1" {
+		t.Errorf("Expected the game request of T2 to be kept, got %v", loaded)
+	}
+	if matches, _ := reopened.Workspace(Workspace{TeamID: "T1"}).LoadMatches(); len(matches) != 0 {
+		t.Errorf("Expected no matches in T1, got %v", matches)
+	}
+	if loaded, _ := reopened.LoadGameRequests(); len(loaded) != 0 {
+		t.Errorf("Expected no game requests outside of the workspaces, got %v", loaded)
+	}
+	open, _ := reopened.OpenWorkspaces()
+	if !slices.Equal(open, []Workspace{{EnterpriseID: "E1", TeamID: "T2"}}) {
+		t.Errorf("Expected T2 to have open game requests, got %v", open)
+	}
+	installations, _ := reopened.LoadInstallations()
+	if len(installations) != 1 || installations[0] != installation {
+		t.Errorf("Expected the installation of T1, got %v", installations)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Table is a physical football table that game requests can target with --table.
+type Table struct {
+	Name     string `yaml:"name"`     // name in the --table flag, e.g. "dach"
+	Location string `yaml:"location"` // where to find the table, e.g. "4. OG"
+}
+
+// Label renders the table along with its location for messages.
+func (table Table) Label() string {
+	if table.Location == "" {
+		return table.Name
+	}
+	return fmt.Sprintf("%s (%s)", table.Name, table.Location)
+}
+
+// Table returns the configured table with the given name, ignoring case.
+func (settings *Settings) Table(name string) (Table, bool) {
+	for _, table := range settings.Tables {
+		if strings.EqualFold(table.Name, name) {
+			return table, true
+		}
+	}
+	return Table{}, false
+}
+
+// tableNames lists the names of the configured tables for messages.
+func (settings *Settings) tableNames() string {
+	names := make([]string, len(settings.Tables))
+	for i, table := range settings.Tables {
+		names[i] = "`" + table.Name + "`"
+	}
+	return strings.Join(names, ", ")
+}
+
+// tableLabel returns the label of the table with the given name. Tables that were removed from the configuration
+// while game requests still target them are shown by their name.
+func (gameMgr *GameManager) tableLabel(name string) string {
+	if table, exists := gameMgr.Settings().Table(name); exists {
+		return table.Label()
+	}
+	return name
+}
+
+// TableBusyUntil returns until when a match is played on the table, or the zero time if the table is free.
+func (gameMgr *GameManager) TableBusyUntil(name string) time.Time {
+	var until time.Time
+	gameMgr.do(func() {
+		until = gameMgr.tableBusyUntil(name, gameMgr.clock.Now())
+	})
+	return until
+}
+
+// tableBusyUntil returns until when a match is played on the table, or the zero time if the table is free.
+// A match keeps its table busy until its result is entered or until the configured TableBusyFor passed after
+// its start. It runs on the goroutine of the game manager.
+func (gameMgr *GameManager) tableBusyUntil(name string, now time.Time) time.Time {
+	busyFor := gameMgr.Settings().TableBusyFor
+	var until time.Time
+	for _, match := range gameMgr.matches {
+		if match.Table != name || match.IsRecorded() {
+			continue
+		}
+		if end := match.StartedAt.Add(busyFor); end.After(now) && end.After(until) {
+			until = end
+		}
+	}
+	return until
+}
+
+// occupyTable schedules the release of the table for the moment the matches on it no longer keep it busy.
+func (gameMgr *GameManager) occupyTable(name string) {
+	until := gameMgr.TableBusyUntil(name)
+	if until.IsZero() {
+		return
+	}
+	gameMgr.scheduler.schedule(tableJob(name), until, func() {
+		gameMgr.releaseTable(name)
+	})
+}
+
+// queueLength returns the number of game requests that wait for the table.
+func (gameMgr *GameManager) queueLength(name string) int {
+	queued := 0
+	for _, gameReq := range gameMgr.tableLobbies(name) {
+		if snapshot := gameReq.snapshot(); !snapshot.queuedAt.IsZero() && !snapshot.closed {
+			queued++
+		}
+	}
+	return queued
+}
+
+// tableLobbies returns the open game requests for the table. Their state has to be read with their commands,
+// e.g. with GameRequest.snapshot.
+func (gameMgr *GameManager) tableLobbies(name string) []*GameRequest {
+	var lobbies []*GameRequest
+	gameMgr.do(func() {
+		for _, gameReq := range gameMgr.gameRequests {
+			if gameReq.table == name {
+				lobbies = append(lobbies, gameReq)
+			}
+		}
+	})
+	return lobbies
+}
+
+// releaseTable announces the game request that waits longest for the table once the table is free, i.e. no match
+// is played on it and no other game request for it is looking for players. It is called whenever one of those ends.
+func (gameMgr *GameManager) releaseTable(name string) {
+	if name == "" || !gameMgr.TableBusyUntil(name).IsZero() {
+		return
+	}
+
+	var next *GameRequest
+	var nextQueuedAt time.Time
+	for _, gameReq := range gameMgr.tableLobbies(name) {
+		switch snapshot := gameReq.snapshot(); {
+		case snapshot.closed:
+		case snapshot.announced:
+			return
+		case !snapshot.queuedAt.IsZero() && (next == nil || snapshot.queuedAt.Before(nextQueuedAt)):
+			next, nextQueuedAt = gameReq, snapshot.queuedAt
+		}
+	}
+	if next == nil {
+		return
+	}
+
+	next.do(func() func() {
+		// the game request may have been cancelled or announced by a concurrent release in the meantime
+		if next.closed || next.queuedAt.IsZero() {
+			return nil
+		}
+		next.queuedAt = time.Time{}
+		creator := next.players[0]
+		if err := gameMgr.announceGame(next); err != nil {
+			slog.Error("Failed to announce queued game", "lobby", next.id, "table", name, "error", err)
+			gameMgr.deleteGameRequest(next, lobbyDiscarded)
+			return gameMgr.reply(next.channel, creator, msgAnnounceFailed)
+		}
+		return gameMgr.reply(next.channel, creator, msgTableFree, gameMgr.tableLabel(name))
+	})
+}
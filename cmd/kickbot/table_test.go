@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/mock/gomock"
+)
+
+// ephemeralRecorder collects the ephemeral messages of a mock Slack client by player.
+type ephemeralRecorder struct {
+	mu       sync.Mutex
+	messages map[string][]slack.MsgOption
+}
+
+func recordEphemerals(mockSlackClient *MockSlackClient) *ephemeralRecorder {
+	recorder := &ephemeralRecorder{messages: make(map[string][]slack.MsgOption)}
+	mockSlackClient.EXPECT().
+		PostEphemeral(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(channelID, userID string, options ...slack.MsgOption) (string, error) {
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+			recorder.messages[userID] = append(recorder.messages[userID], options[0])
+			return "timestamp", nil
+		}).AnyTimes()
+	return recorder
+}
+
+// last returns the text and blocks of the last ephemeral message of the player.
+func (recorder *ephemeralRecorder) last(t *testing.T, player string) (string, string) {
+	t.Helper()
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	messages := recorder.messages[player]
+	if len(messages) == 0 {
+		t.Fatalf("Expected an ephemeral message for %s", player)
+	}
+	_, values, err := slack.UnsafeApplyMsgOptions("token", "channel", "https://slack.com/api/", messages[len(messages)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values.Get("text"), values.Get("blocks")
+}
+
+func tableSettings(busyFor time.Duration) Settings {
+	settings := DefaultSettings()
+	settings.Tables = []Table{{Name: "dach", Location: "Dachterrasse"}}
+	settings.TableBusyFor = busyFor
+	return settings
+}
+
+// startDuel starts a 1 vs 1 game on the table and returns its match.
+func startDuel(t *testing.T, gameMgr *GameManager, channel SlackChannel, table string) MatchRecord {
+	t.Helper()
+	gameMgr.CreateGame(channel, "p1", GameOpts{timeout: 30 * time.Minute, gameType: GameTypeOneVsOne, table: table})
+	gameMgr.JoinGame(channel, lobbyIn(gameMgr, channel), "p2")
+
+	for _, match := range matchesOf(gameMgr) {
+		if match.Channel == channel {
+			return match
+		}
+	}
+	t.Fatal("Expected the game to start")
+	return MatchRecord{}
+}
+
+// TestTableBusyUntilResult verifies that a started game keeps its table busy until the result is entered, that
+// players are offered to queue for a busy table and that the queued game request is announced once it is free.
+func TestTableBusyUntilResult(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	gameMgr := NewGameManager(mockSlackClient, WithSettings(tableSettings(30*time.Minute)))
+	defer gameMgr.Shutdown(context.TODO())
+
+	ephemerals := recordEphemerals(mockSlackClient)
+	mockSlackClient.EXPECT().
+		UpdateMessage(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("channel", "ts", "text", nil).AnyTimes()
+	// the shutdown deletes the message of the announced game request
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), "second", "ts2").
+		Return("second", "ts2", nil).Times(1)
+	mockSlackClient.EXPECT().
+		PostMessage("first", gomock.Any()).
+		Return("first", "ts1", nil).Times(1)
+
+	match := startDuel(t, gameMgr, "first", "DACH")
+	if match.Table != "dach" {
+		t.Errorf("Expected the match on table dach, got %q", match.Table)
+	}
+	if gameMgr.TableBusyUntil("dach").IsZero() {
+		t.Fatal("Expected the table to be busy during the game")
+	}
+
+	gameMgr.CreateGame("second", "p3", GameOpts{timeout: 30 * time.Minute, gameType: GameTypeTwoVsTwo, table: "dach"})
+	if lobbyIn(gameMgr, "second") != "" {
+		t.Fatal("Expected no game request for a busy table")
+	}
+	if _, blocks := ephemerals.last(t, "p3"); !strings.Contains(blocks, "Dachterrasse") || !strings.Contains(blocks, ACTION_QUEUE_TABLE) {
+		t.Errorf("Expected the busy table to be offered for queueing, got %s", blocks)
+	}
+
+	gameMgr.CreateGame("second", "p3", GameOpts{timeout: 30 * time.Minute, gameType: GameTypeTwoVsTwo, table: "dach", queue: true})
+	if lobbyIn(gameMgr, "second") == "" {
+		t.Fatal("Expected a queued game request")
+	}
+	if text, _ := ephemerals.last(t, "p3"); !strings.Contains(text, "Nummer 1") {
+		t.Errorf("Expected the queue position, got %q", text)
+	}
+
+	gameMgr.CreateGame("third", "p4", GameOpts{timeout: 30 * time.Minute, gameType: GameTypeTwoVsTwo, table: "garage"})
+	if text, _ := ephemerals.last(t, "p4"); !strings.Contains(text, "`dach`") {
+		t.Errorf("Expected the available tables, got %q", text)
+	}
+
+	// the result frees the table for the queued game request
+	mockSlackClient.EXPECT().
+		PostMessage("second", gomock.Any()).
+		Return("second", "ts2", nil).Times(1)
+	if errs := gameMgr.RecordResult(match.ID, "p1", MatchResult{TeamOne: []string{"p1"}, Score: [2]int{10, 3}, Winner: 0}); errs != nil {
+		t.Fatalf("Expected result to be recorded, got %v", errs)
+	}
+	gameReq, exists := gameMgr.getGameRequest("second", lobbyIn(gameMgr, "second"))
+	if !exists {
+		t.Fatal("Expected the queued game request to be announced")
+	}
+	inspectLobby(gameReq, func() {
+		if !gameReq.announced() || gameReq.messageTs != "ts2" {
+			t.Errorf("Expected the queued game request to be announced, got %+v", gameReq.record())
+		}
+		if text, _ := ephemerals.last(t, "p3"); !strings.Contains(text, "ist frei") {
+			t.Errorf("Expected the creator to be told that the table is free, got %q", text)
+		}
+	})
+}
+
+// TestTableReleasedAfterBusyDuration verifies that a table without result is free once the configured duration
+// passed after the start of the game.
+func TestTableReleasedAfterBusyDuration(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	clock := newFakeClock()
+	gameMgr := NewGameManager(mockSlackClient, WithSettings(tableSettings(100*time.Millisecond)), WithClock(clock))
+	defer gameMgr.Shutdown(context.TODO())
+
+	recordEphemerals(mockSlackClient)
+	mockSlackClient.EXPECT().
+		UpdateMessage(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("channel", "ts", "text", nil).AnyTimes()
+	// the shutdown deletes the message of the announced game request
+	mockSlackClient.EXPECT().
+		DeleteMessageContext(gomock.Any(), "second", "ts2").
+		Return("second", "ts2", nil).Times(1)
+	mockSlackClient.EXPECT().
+		PostMessage("first", gomock.Any()).
+		Return("first", "ts1", nil).Times(1)
+	mockSlackClient.EXPECT().
+		PostMessage("second", gomock.Any()).
+		Return("second", "ts2", nil).Times(1)
+
+	startDuel(t, gameMgr, "first", "dach")
+	gameMgr.CreateGame("second", "p3", GameOpts{timeout: 30 * time.Minute, gameType: GameTypeTwoVsTwo, table: "dach", queue: true})
+
+	clock.Advance(100 * time.Millisecond)
+
+	if !gameMgr.TableBusyUntil("dach").IsZero() {
+		t.Error("Expected the table to be free after the busy duration")
+	}
+	gameReq, exists := gameMgr.getGameRequest("second", lobbyIn(gameMgr, "second"))
+	if !exists {
+		t.Fatal("Expected the queued game request to be announced")
+	}
+	inspectLobby(gameReq, func() {
+		if gameReq.messageTs != "ts2" {
+			t.Errorf("Expected the queued game request to be announced, got %+v", gameReq.record())
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Position is the preferred position of a player in a team of two.
+type Position string
+
+const (
+	PositionAny     Position = ""        // no preference
+	PositionDefense Position = "defense" // goalkeeper and defense rods
+	PositionAttack  Position = "attack"  // midfield and attack rods
+)
+
+// ParsePosition parses the position given to the /kicker-position command.
+func ParsePosition(value string) (Position, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "abwehr", "tor", "hinten", "defense":
+		return PositionDefense, true
+	case "sturm", "angriff", "vorne", "attack":
+		return PositionAttack, true
+	case "egal", "any", "":
+		return PositionAny, true
+	}
+	return PositionAny, false
+}
+
+// proposeTeams splits the players of a game into the teams of its format. If any of the players has a rating
+// the split with the smallest difference of the average team ratings is chosen, the same averages the ratings are
+// updated with, otherwise the split is random. Within teams of two the first player plays defense and the second
+// attack, respecting the players' preferences. The ratings are compared as of now, including their decay.
+func proposeTeams(gameType GameType, players []string, ratings *Ratings, positions map[string]Position, now time.Time) [2][]string {
+	splits := gameType.Format().splits()
+	rated := slices.ContainsFunc(players, func(player string) bool {
+		return ratings.HasRating(gameType, player)
+	})
+	if !rated {
+		split := splits[rand.IntN(len(splits))]
+		return lineup(players, split, positions)
+	}
+
+	best := make([][2][]int, 0, len(splits))
+	bestDiff := math.Inf(1)
+	for _, split := range splits {
+		var teamRatings [2]float64
+		for i, team := range split {
+			for _, idx := range team {
+				teamRatings[i] += ratings.Rating(gameType, players[idx], now).Rating
+			}
+			teamRatings[i] /= float64(len(team))
+		}
+		diff := math.Abs(teamRatings[0] - teamRatings[1])
+		switch {
+		case diff < bestDiff-1e-9:
+			best = append(best[:0], split)
+			bestDiff = diff
+		case diff < bestDiff+1e-9:
+			best = append(best, split)
+		}
+	}
+	return lineup(players, best[rand.IntN(len(best))], positions)
+}
+
+// reshuffleTeams proposes a random split of the players that differs from the current teams.
+func reshuffleTeams(gameType GameType, players []string, current [2][]string, positions map[string]Position) [2][]string {
+	splits := gameType.Format().splits()
+	candidates := make([][2][]int, 0, len(splits))
+	for _, split := range splits {
+		if !sameSplit(players, split, current) {
+			candidates = append(candidates, split)
+		}
+	}
+	return lineup(players, candidates[rand.IntN(len(candidates))], positions)
+}
+
+// sameSplit reports whether the split puts the same players together as one of the teams.
+func sameSplit(players []string, split [2][]int, teams [2][]string) bool {
+	for _, team := range teams {
+		if len(team) != len(split[0]) {
+			continue
+		}
+		if !slices.ContainsFunc(split[0], func(idx int) bool { return !slices.Contains(team, players[idx]) }) {
+			return true
+		}
+	}
+	return false
+}
+
+// lineup builds the teams of a split and orders each team of two as defense, attack.
+func lineup(players []string, split [2][]int, positions map[string]Position) [2][]string {
+	var teams [2][]string
+	for i, team := range split {
+		members := make([]string, len(team))
+		for j, idx := range team {
+			members[j] = players[idx]
+		}
+		if len(members) == 2 {
+			a, b := members[0], members[1]
+			keep := positionScore(positions[a], PositionDefense) + positionScore(positions[b], PositionAttack)
+			swap := positionScore(positions[b], PositionDefense) + positionScore(positions[a], PositionAttack)
+			if swap > keep || (swap == keep && rand.IntN(2) == 0) {
+				members[0], members[1] = b, a
+			}
+		}
+		teams[i] = members
+	}
+	return teams
+}
+
+// tableOrder returns the players of a game without teams in a random order, in which they line up around the table.
+func tableOrder(players []string) []string {
+	order := slices.Clone(players)
+	rand.Shuffle(len(order), func(i, j int) {
+		order[i], order[j] = order[j], order[i]
+	})
+	return order
+}
+
+// positionScore rates how well a position matches a player's preference.
+func positionScore(preferred, assigned Position) int {
+	switch preferred {
+	case PositionAny:
+		return 0
+	case assigned:
+		return 1
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func sortedPlayers(teams [2][]string) []string {
+	players := append(slices.Clone(teams[0]), teams[1]...)
+	slices.Sort(players)
+	return players
+}
+
+func TestProposeTeamsMinimisesRatingDifference(t *testing.T) {
+	now := time.Now()
+	ratings := NewRatings(DefaultRatingConfig)
+
+	// strong1 and strong2 win a lot, weak1 and weak2 lose a lot
+	for i := range 5 {
+		ratings.Apply(recordedMatch(GameTypeTwoVsTwo, []string{"strong1", "strong2"}, []string{"weak1", "weak2"}, 0, now.Add(time.Duration(i)*time.Minute)))
+	}
+
+	players := []string{"strong1", "strong2", "weak1", "weak2"}
+	for range 20 {
+		teams := proposeTeams(GameTypeTwoVsTwo, players, ratings, nil, time.Now())
+		for _, team := range teams {
+			if slices.Contains(team, "strong1") && slices.Contains(team, "strong2") {
+				t.Fatalf("Expected the strong players to be split up, got %v", teams)
+			}
+		}
+	}
+}
+
+func TestProposeTeamsTwoVsOne(t *testing.T) {
+	now := time.Now()
+	ratings := NewRatings(DefaultRatingConfig)
+	for i := range 5 {
+		ratings.Apply(recordedMatch(GameTypeTwoVsOne, []string{"weak1", "weak2"}, []string{"strong"}, 1, now.Add(time.Duration(i)*time.Minute)))
+	}
+
+	// alone, the strong player is as far above the average of the weak pair as above each weak player; teamed up
+	// with a weak player, their average is only half as far above the other one. Summed ratings would have left
+	// the strong player alone against a pair worth about twice their rating.
+	for range 20 {
+		teams := proposeTeams(GameTypeTwoVsOne, []string{"weak1", "strong", "weak2"}, ratings, nil, time.Now())
+		if len(teams[1]) != 1 || teams[1][0] == "strong" || !slices.Contains(teams[0], "strong") {
+			t.Fatalf("Expected the strong player to team up with a weak player, got %v", teams)
+		}
+	}
+}
+
+func TestProposeTeamsWithoutRatings(t *testing.T) {
+	ratings := NewRatings(DefaultRatingConfig)
+	players := []string{"p1", "p2", "p3", "p4"}
+
+	seen := make(map[string]bool)
+	for range 100 {
+		teams := proposeTeams(GameTypeTwoVsTwo, players, ratings, nil, time.Now())
+		if len(teams[0]) != 2 || len(teams[1]) != 2 || !slices.Equal(sortedPlayers(teams), players) {
+			t.Fatalf("Expected all four players in two teams of two, got %v", teams)
+		}
+		partner := teams[0][1]
+		if teams[0][0] != "p1" {
+			partner = teams[0][0]
+		}
+		if slices.Contains(teams[1], "p1") {
+			partner = teams[1][0]
+			if partner == "p1" {
+				partner = teams[1][1]
+			}
+		}
+		seen[partner] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("Expected random splits to pair p1 with every other player, got partners %v", seen)
+	}
+}
+
+func TestLineupRespectsPositions(t *testing.T) {
+	positions := map[string]Position{
+		"goalie":  PositionDefense,
+		"striker": PositionAttack,
+		"p3":      PositionAttack,
+	}
+
+	for range 20 {
+		teams := lineup([]string{"striker", "goalie", "p3", "p4"}, [2][]int{{0, 1}, {2, 3}}, positions)
+		if !slices.Equal(teams[0], []string{"goalie", "striker"}) {
+			t.Fatalf("Expected goalie in defense and striker in attack, got %v", teams[0])
+		}
+		if !slices.Equal(teams[1], []string{"p4", "p3"}) {
+			t.Fatalf("Expected p3 in attack, got %v", teams[1])
+		}
+	}
+}
+
+func TestReshuffleTeamsChangesSplit(t *testing.T) {
+	players := []string{"p1", "p2", "p3", "p4"}
+	current := [2][]string{{"p1", "p2"}, {"p3", "p4"}}
+
+	for range 20 {
+		teams := reshuffleTeams(GameTypeTwoVsTwo, players, current, nil)
+		if !slices.Equal(sortedPlayers(teams), players) {
+			t.Fatalf("Expected all four players in the teams, got %v", teams)
+		}
+		for _, team := range teams {
+			if slices.Contains(team, "p1") && slices.Contains(team, "p2") {
+				t.Fatalf("Expected a different split than the current one, got %v", teams)
+			}
+		}
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	tests := map[string]struct {
+		position Position
+		ok       bool
+	}{
+		"abwehr":  {PositionDefense, true},
+		" Sturm ": {PositionAttack, true},
+		"egal":    {PositionAny, true},
+		"":        {PositionAny, true},
+		"torwart": {PositionAny, false},
+	}
+	for input, expected := range tests {
+		position, ok := ParsePosition(input)
+		if position != expected.position || ok != expected.ok {
+			t.Errorf("ParsePosition(%q) = %q, %v, expected %q, %v", input, position, ok, expected.position, expected.ok)
+		}
+	}
+}
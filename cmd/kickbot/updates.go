@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// updateScheduler sends the updates of the bot's messages to Slack. The updates of a message are sent one at a time
+// and in the order they were submitted. An update submitted while the previous one of the message is in flight
+// belongs to a burst, e.g. of clicks on the join button. It waits for the debounce interval after the previous one
+// was sent and is coalesced with the updates submitted in the meantime, so that only the latest state of the message
+// is sent. Updates outside of bursts are sent right away. Critical updates, e.g. the start of a game, skip the
+// debounce interval and may use the reserved tokens of the rate limiter.
+//
+// Every submitter gets the result of the update that carried its state. An update that fails while a newer one is
+// waiting hands its submitters over to the newer one, which carries their state as well.
+type updateScheduler struct {
+	client   SlackClient // sends regular updates
+	critical SlackClient // sends critical updates
+	debounce time.Duration
+	queues   map[messageRef]*updateQueue
+	pending  int           // submitted updates that weren't sent yet
+	idle     chan struct{} // closed once no update is pending, see flush
+	mu       sync.Mutex
+}
+
+// messageRef identifies a message of the bot.
+type messageRef struct {
+	channel SlackChannel
+	ts      string
+}
+
+// messageUpdate is the latest state of a message that waits to be sent.
+type messageUpdate struct {
+	send     func(client SlackClient) error
+	critical bool
+	results  []chan error // of the submitters whose state the update carries
+}
+
+// updateQueue holds the update of a message that waits to be sent. It exists while its goroutine sends a burst.
+type updateQueue struct {
+	next *messageUpdate
+	wake chan struct{} // cuts the debounce interval short for critical updates
+}
+
+func newUpdateScheduler(client, critical SlackClient, debounce time.Duration) *updateScheduler {
+	idle := make(chan struct{})
+	close(idle)
+	return &updateScheduler{
+		client:   client,
+		critical: critical,
+		debounce: debounce,
+		queues:   make(map[messageRef]*updateQueue),
+		idle:     idle,
+	}
+}
+
+// update replaces the content of the message. The returned channel receives the result once the update, or a newer
+// one that replaced it, was sent.
+func (scheduler *updateScheduler) update(channel SlackChannel, ts string, msg slack.MsgOption, critical bool) <-chan error {
+	return scheduler.submit(messageRef{channel, ts}, critical, func(client SlackClient) error {
+		_, _, _, err := client.UpdateMessage(string(channel), ts, msg)
+		return err
+	})
+}
+
+// remove deletes the message. Updates of the message that wait to be sent are dropped.
+func (scheduler *updateScheduler) remove(channel SlackChannel, ts string) <-chan error {
+	return scheduler.submit(messageRef{channel, ts}, true, func(client SlackClient) error {
+		_, _, err := client.DeleteMessage(string(channel), ts)
+		return err
+	})
+}
+
+func (scheduler *updateScheduler) submit(ref messageRef, critical bool, send func(client SlackClient) error) <-chan error {
+	result := make(chan error, 1)
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	queue, running := scheduler.queues[ref]
+	if !running {
+		queue = &updateQueue{wake: make(chan struct{}, 1)}
+		scheduler.queues[ref] = queue
+		go scheduler.run(ref, queue)
+	}
+	if queue.next == nil {
+		queue.next = &messageUpdate{}
+		if scheduler.pending == 0 {
+			scheduler.idle = make(chan struct{})
+		}
+		scheduler.pending++
+	}
+	queue.next.send = send
+	queue.next.critical = queue.next.critical || critical
+	queue.next.results = append(queue.next.results, result)
+	if critical {
+		select {
+		case queue.wake <- struct{}{}:
+		default:
+		}
+	}
+	return result
+}
+
+// run sends the updates of a message until none was submitted while the last one was in flight.
+func (scheduler *updateScheduler) run(ref messageRef, queue *updateQueue) {
+	for {
+		scheduler.mu.Lock()
+		update := queue.next
+		queue.next = nil
+		if update == nil {
+			delete(scheduler.queues, ref)
+			scheduler.mu.Unlock()
+			return
+		}
+		scheduler.mu.Unlock()
+		select {
+		case <-queue.wake:
+		default:
+		}
+
+		client := scheduler.client
+		if update.critical {
+			client = scheduler.critical
+		}
+		err := update.send(client)
+		if err != nil {
+			slog.Error("Failed to update message", "channel", ref.channel, "ts", ref.ts, "error", err)
+		}
+
+		scheduler.mu.Lock()
+		results := update.results
+		burst := queue.next != nil
+		if err != nil && burst {
+			queue.next.results = append(queue.next.results, results...)
+			results = nil
+		}
+		if scheduler.pending--; scheduler.pending == 0 {
+			close(scheduler.idle)
+		}
+		scheduler.mu.Unlock()
+		for _, result := range results {
+			result <- err
+		}
+		if !burst {
+			continue
+		}
+
+		timer := time.NewTimer(scheduler.debounce)
+		select {
+		case <-timer.C:
+		case <-queue.wake:
+			timer.Stop()
+		}
+	}
+}
+
+// flush waits until the submitted updates were sent or the context is done.
+func (scheduler *updateScheduler) flush(ctx context.Context) {
+	scheduler.mu.Lock()
+	idle := scheduler.idle
+	scheduler.mu.Unlock()
+
+	select {
+	case <-idle:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/mock/gomock"
+)
+
+// TestUpdatesAreCoalesced verifies that the updates of a message submitted while an update is in flight are
+// coalesced, so that only the latest one is sent, and that all of their submitters get its result.
+func TestUpdatesAreCoalesced(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	scheduler := newUpdateScheduler(mockSlackClient, mockSlackClient, 10*time.Millisecond)
+
+	inFlight := make(chan struct{})
+	release := make(chan struct{})
+	var sent []string
+	mockSlackClient.EXPECT().
+		UpdateMessage("C1", "ts", gomock.Any()).
+		DoAndReturn(func(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+			text := msgText(t, options[0])
+			if text == "first" {
+				close(inFlight)
+				<-release
+			}
+			sent = append(sent, text)
+			return channelID, timestamp, "text", nil
+		}).Times(2)
+
+	first := scheduler.update("C1", "ts", slack.MsgOptionText("first", false), false)
+	<-inFlight
+	results := []<-chan error{
+		scheduler.update("C1", "ts", slack.MsgOptionText("second", false), false),
+		scheduler.update("C1", "ts", slack.MsgOptionText("third", false), false),
+	}
+	close(release)
+
+	for _, result := range append(results, first) {
+		if err := <-result; err != nil {
+			t.Errorf("Expected the update to succeed, got %v", err)
+		}
+	}
+	scheduler.flush(context.TODO())
+	if len(sent) != 2 || sent[1] != "third" {
+		t.Errorf("Expected the first and the latest update to be sent, got %v", sent)
+	}
+}
+
+// TestFailedUpdateIsReplacedByNewerOne verifies that the submitters of a failed update get the result of the newer
+// update that was waiting, since it carries their state as well.
+func TestFailedUpdateIsReplacedByNewerOne(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSlackClient := NewMockSlackClient(ctrl)
+	scheduler := newUpdateScheduler(mockSlackClient, mockSlackClient, time.Hour)
+
+	inFlight := make(chan struct{})
+	release := make(chan struct{})
+	gomock.InOrder(
+		mockSlackClient.EXPECT().
+			UpdateMessage("C1", "ts", gomock.Any()).
+			DoAndReturn(func(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+				close(inFlight)
+				<-release
+				return "", "", "", slack.SlackErrorResponse{Err: "cant_update_message"}
+			}).Times(1),
+		// the critical deletion skips the debounce interval
+		mockSlackClient.EXPECT().
+			DeleteMessage("C1", "ts").
+			Return("C1", "ts", nil).Times(1),
+	)
+
+	failed := scheduler.update("C1", "ts", slack.MsgOptionText("first", false), false)
+	<-inFlight
+	removed := scheduler.remove("C1", "ts")
+	close(release)
+
+	if err := <-failed; err != nil {
+		t.Errorf("Expected the result of the deletion, got %v", err)
+	}
+	if err := <-removed; err != nil {
+		t.Errorf("Expected the deletion to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Workspace identifies the Slack workspace a request comes from. Workspaces of an Enterprise Grid organization
+// also carry the ID of the organization, whose installation may serve all of its workspaces.
+type Workspace struct {
+	EnterpriseID string
+	TeamID       string
+}
+
+// Installation is what a workspace, or a whole Enterprise Grid organization, granted the bot when it installed
+// the app through OAuth.
+type Installation struct {
+	TeamID            string    `json:"team_id,omitempty"` // empty for installations in a whole organization
+	TeamName          string    `json:"team_name,omitempty"`
+	EnterpriseID      string    `json:"enterprise_id,omitempty"`
+	EnterpriseInstall bool      `json:"enterprise_install,omitempty"`
+	BotToken          string    `json:"bot_token"`
+	BotUserID         string    `json:"bot_user_id"`
+	InstalledAt       time.Time `json:"installed_at"`
+}
+
+// key identifies the installation among all installations: the team ID, or the enterprise ID for installations
+// in a whole organization.
+func (installation Installation) key() string {
+	if installation.EnterpriseInstall {
+		return installation.EnterpriseID
+	}
+	return installation.TeamID
+}
+
+// WorkspaceStore persists the installations of the app and the games of every workspace it is installed in.
+// Implementations must be safe for concurrent use.
+type WorkspaceStore interface {
+
+	// SaveInstallation inserts or replaces the stored installation of the workspace or organization.
+	SaveInstallation(installation Installation) error
+
+	// LoadInstallations returns all stored installations.
+	LoadInstallations() ([]Installation, error)
+
+	// Workspace returns the store of the game requests and matches of the workspace.
+	Workspace(workspace Workspace) GameStore
+
+	// OpenWorkspaces returns the workspaces that have stored game requests.
+	OpenWorkspaces() ([]Workspace, error)
+
+	// Ping checks that the store can be written, for the readiness check of the bot.
+	Ping() error
+}
+
+// errNotInstalled is returned for requests from workspaces the app isn't installed in.
+var errNotInstalled = errors.New("app is not installed in the workspace")
+
+// errWorkspacesStopped is returned for requests that arrive after the GameManagers were shut down.
+var errWorkspacesStopped = errors.New("the game managers are shut down")
+
+// Workspaces resolves the GameManager of the workspace an incoming request comes from. A bot started with a
+// single bot token serves all requests with one GameManager. A bot installed through OAuth runs a GameManager per
+// workspace with the workspace's bot token, so that the lobby state of every workspace is kept apart: game
+// requests are keyed by team and channel. The GameManagers are created on the first request of a workspace.
+type Workspaces struct {
+	single        *GameManager                // serves all requests of a bot with a single bot token
+	managers      map[string]*GameManager     // by team ID
+	clients       map[string]*workspaceClient // by team ID
+	pending       map[string]*pendingManager  // GameManagers that are being created, by team ID
+	installations map[string]Installation     // by Installation.key
+	store         WorkspaceStore              // nil to keep installations and games in memory
+	newClient     func(token string) SlackClient
+	opts          []GameManagerOption
+	settings      Settings
+	stopped       bool // set by Shutdown, no GameManagers are created afterwards
+	mu            sync.Mutex
+}
+
+// SingleWorkspace serves all requests with the GameManager of a bot with a single bot token.
+func SingleWorkspace(gameMgr *GameManager) *Workspaces {
+	return &Workspaces{single: gameMgr}
+}
+
+// NewWorkspaces returns the Workspaces of a bot installed through OAuth. The GameManagers of the workspaces are
+// created with the options, a client for the bot token of their installation and the settings of their workspace.
+func NewWorkspaces(store WorkspaceStore, newClient func(token string) SlackClient, settings Settings, opts ...GameManagerOption) *Workspaces {
+	return &Workspaces{
+		managers:      make(map[string]*GameManager),
+		clients:       make(map[string]*workspaceClient),
+		pending:       make(map[string]*pendingManager),
+		installations: make(map[string]Installation),
+		store:         store,
+		newClient:     newClient,
+		opts:          opts,
+		settings:      settings,
+	}
+}
+
+// Restore loads the stored installations and restores the games of the workspaces with open game requests, so
+// that their timeouts fire without waiting for the next request of the workspace.
+func (workspaces *Workspaces) Restore() error {
+	if workspaces.single != nil || workspaces.store == nil {
+		return nil
+	}
+	installations, err := workspaces.store.LoadInstallations()
+	if err != nil {
+		return fmt.Errorf("failed to load installations: %w", err)
+	}
+	workspaces.mu.Lock()
+	for _, installation := range installations {
+		workspaces.installations[installation.key()] = installation
+	}
+	workspaces.mu.Unlock()
+	slog.Info("Restored installations", "count", len(installations))
+
+	open, err := workspaces.store.OpenWorkspaces()
+	if err != nil {
+		return fmt.Errorf("failed to load workspaces: %w", err)
+	}
+	for _, workspace := range open {
+		if _, err := workspaces.Manager(workspace); err != nil {
+			slog.Error("Failed to restore the games of a workspace", "team", workspace.TeamID, "error", err)
+		}
+	}
+	return nil
+}
+
+// Manager returns the GameManager of the workspace. Workspaces of an organization the app was installed in as a
+// whole are served with the organization's bot token.
+func (workspaces *Workspaces) Manager(workspace Workspace) (*GameManager, error) {
+	if workspaces.single != nil {
+		return workspaces.single, nil
+	}
+
+	workspaces.mu.Lock()
+	if workspaces.stopped {
+		workspaces.mu.Unlock()
+		return nil, errWorkspacesStopped
+	}
+	if gameMgr, exists := workspaces.managers[workspace.TeamID]; exists {
+		workspaces.mu.Unlock()
+		return gameMgr, nil
+	}
+	if pending, exists := workspaces.pending[workspace.TeamID]; exists {
+		workspaces.mu.Unlock()
+		<-pending.done
+		return pending.gameMgr, pending.err
+	}
+	installation, exists := workspaces.installation(workspace)
+	if !exists || workspace.TeamID == "" {
+		workspaces.mu.Unlock()
+		return nil, fmt.Errorf("%w: team %q, enterprise %q", errNotInstalled, workspace.TeamID, workspace.EnterpriseID)
+	}
+
+	// the client is registered right away, so that a reinstallation while the games are restored replaces its token
+	client := &workspaceClient{workspace: workspace}
+	client.client.Store(&clientHolder{workspaces.newClient(installation.BotToken)})
+	workspaces.clients[workspace.TeamID] = client
+	pending := &pendingManager{done: make(chan struct{})}
+	workspaces.pending[workspace.TeamID] = pending
+	opts := append([]GameManagerOption{}, workspaces.opts...)
+	opts = append(opts, WithSettings(workspaces.settings.Workspace(workspace.TeamID)))
+	if workspaces.store != nil {
+		opts = append(opts, WithGameStore(workspaces.store.Workspace(workspace)))
+	}
+	workspaces.mu.Unlock()
+
+	// the games are restored without holding the lock, so that a slow store doesn't hold up the other workspaces;
+	// further requests of the workspace wait for the pending GameManager instead of creating another one
+	gameMgr := NewGameManager(client, opts...)
+	if err := gameMgr.RestoreGames(); err != nil {
+		gameMgr.Shutdown(context.Background())
+		pending.err = fmt.Errorf("failed to restore games of team %q: %w", workspace.TeamID, err)
+	} else {
+		pending.gameMgr = gameMgr
+	}
+
+	workspaces.mu.Lock()
+	delete(workspaces.pending, workspace.TeamID)
+	stopped := workspaces.stopped && pending.err == nil
+	if stopped {
+		// Shutdown didn't see the GameManager, it is shut down here instead of being published
+		pending.gameMgr, pending.err = nil, errWorkspacesStopped
+	}
+	if pending.err != nil {
+		delete(workspaces.clients, workspace.TeamID)
+	} else {
+		// the settings may have been reloaded while the games were restored
+		gameMgr.ApplySettings(workspaces.settings.Workspace(workspace.TeamID))
+		workspaces.managers[workspace.TeamID] = gameMgr
+	}
+	workspaces.mu.Unlock()
+	if stopped {
+		gameMgr.Shutdown(context.Background())
+	}
+	close(pending.done)
+	return pending.gameMgr, pending.err
+}
+
+// pendingManager is a GameManager that is being created, see Workspaces.Manager.
+type pendingManager struct {
+	done    chan struct{} // closed once the GameManager was created or failed to
+	gameMgr *GameManager
+	err     error
+}
+
+// installation returns the installation that serves the workspace. The caller must hold the lock.
+func (workspaces *Workspaces) installation(workspace Workspace) (Installation, bool) {
+	if installation, exists := workspaces.installations[workspace.TeamID]; exists && workspace.TeamID != "" {
+		return installation, true
+	}
+	if installation, exists := workspaces.installations[workspace.EnterpriseID]; exists && workspace.EnterpriseID != "" {
+		return installation, installation.EnterpriseInstall
+	}
+	return Installation{}, false
+}
+
+// Install stores the installation of the app in a workspace or organization. The GameManagers of the workspaces
+// it serves use its bot token from now on, which matters if the app is reinstalled after its token was revoked.
+func (workspaces *Workspaces) Install(installation Installation) error {
+	if workspaces.single != nil {
+		return errors.New("the bot runs with a single bot token")
+	}
+	if workspaces.store != nil {
+		if err := workspaces.store.SaveInstallation(installation); err != nil {
+			return fmt.Errorf("failed to save installation: %w", err)
+		}
+	}
+
+	workspaces.mu.Lock()
+	defer workspaces.mu.Unlock()
+
+	workspaces.installations[installation.key()] = installation
+	for _, client := range workspaces.clients {
+		if current, _ := workspaces.installation(client.workspace); current.key() == installation.key() {
+			client.client.Store(&clientHolder{workspaces.newClient(installation.BotToken)})
+		}
+	}
+	return nil
+}
+
+// Ready checks that the GameManagers of all workspaces are ready, see GameManager.Ready. A bot installed through
+// OAuth also needs its store to save new installations.
+func (workspaces *Workspaces) Ready(ctx context.Context) error {
+	if workspaces.single != nil {
+		return workspaces.single.Ready(ctx)
+	}
+	if workspaces.store != nil {
+		if err := workspaces.store.Ping(); err != nil {
+			return fmt.Errorf("store is not reachable: %w", err)
+		}
+	}
+
+	workspaces.mu.Lock()
+	managers := maps.Clone(workspaces.managers)
+	workspaces.mu.Unlock()
+
+	var errs []error
+	for team, gameMgr := range managers {
+		if err := gameMgr.Ready(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("team %s: %w", team, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ApplySettings replaces the settings of all GameManagers, see GameManager.ApplySettings. Every workspace gets the
+// settings of the bot with its own defaults and channels, see Settings.Workspace.
+func (workspaces *Workspaces) ApplySettings(settings Settings) {
+	if workspaces.single != nil {
+		workspaces.single.ApplySettings(settings)
+		return
+	}
+
+	workspaces.mu.Lock()
+	defer workspaces.mu.Unlock()
+
+	workspaces.settings = settings
+	for team, gameMgr := range workspaces.managers {
+		gameMgr.ApplySettings(settings.Workspace(team))
+	}
+}
+
+// Shutdown shuts down the GameManagers of all workspaces. GameManagers that are still being created are shut down
+// once their games are restored, Shutdown waits for them until the context is done.
+func (workspaces *Workspaces) Shutdown(ctx context.Context) {
+	if workspaces.single != nil {
+		workspaces.single.Shutdown(ctx)
+		return
+	}
+
+	// the lock is only held to take the GameManagers, so that requests arriving meanwhile fail right away
+	workspaces.mu.Lock()
+	workspaces.stopped = true
+	managers := make([]*GameManager, 0, len(workspaces.managers))
+	for _, gameMgr := range workspaces.managers {
+		managers = append(managers, gameMgr)
+	}
+	pending := make([]*pendingManager, 0, len(workspaces.pending))
+	for _, pendingMgr := range workspaces.pending {
+		pending = append(pending, pendingMgr)
+	}
+	workspaces.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, pendingMgr := range pending {
+		wg.Add(1)
+		go func(pendingMgr *pendingManager) {
+			defer wg.Done()
+			select {
+			case <-pendingMgr.done:
+			case <-ctx.Done():
+			}
+		}(pendingMgr)
+	}
+	for _, gameMgr := range managers {
+		wg.Add(1)
+		go func(gameMgr *GameManager) {
+			defer wg.Done()
+			gameMgr.Shutdown(ctx)
+		}(gameMgr)
+	}
+	wg.Wait()
+}
+
+// clientHolder wraps the SlackClient of a workspace, since an atomic.Pointer can't point to an interface.
+type clientHolder struct {
+	SlackClient
+}
+
+// workspaceClient is the SlackClient of a workspace. It sends every call with the bot token of the latest
+// installation, so that a reinstallation takes effect on the games in progress.
+type workspaceClient struct {
+	workspace Workspace
+	client    atomic.Pointer[clientHolder]
+}
+
+func (client *workspaceClient) current() SlackClient {
+	return client.client.Load().SlackClient
+}
+
+func (client *workspaceClient) PostEphemeral(channelID, userID string, options ...slack.MsgOption) (string, error) {
+	return client.current().PostEphemeral(channelID, userID, options...)
+}
+
+func (client *workspaceClient) PostMessage(channelID string, options ...slack.MsgOption) (string, string, error) {
+	return client.current().PostMessage(channelID, options...)
+}
+
+func (client *workspaceClient) ScheduleMessage(channelID, postAt string, options ...slack.MsgOption) (string, string, error) {
+	return client.current().ScheduleMessage(channelID, postAt, options...)
+}
+
+func (client *workspaceClient) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+	return client.current().UpdateMessage(channelID, timestamp, options...)
+}
+
+func (client *workspaceClient) DeleteMessage(channel, messageTimestamp string) (string, string, error) {
+	return client.current().DeleteMessage(channel, messageTimestamp)
+}
+
+func (client *workspaceClient) OpenView(triggerID string, view slack.ModalViewRequest) (*slack.ViewResponse, error) {
+	return client.current().OpenView(triggerID, view)
+}
+
+func (client *workspaceClient) GetUserInfo(user string) (*slack.User, error) {
+	return client.current().GetUserInfo(user)
+}
+
+func (client *workspaceClient) DeleteMessageContext(ctx context.Context, channel, messageTimestamp string) (string, string, error) {
+	return client.current().DeleteMessageContext(ctx, channel, messageTimestamp)
+}
+
+func (client *workspaceClient) AuthTestContext(ctx context.Context) (*slack.AuthTestResponse, error) {
+	return client.current().AuthTestContext(ctx)
+}
+
+// compile-time assertion to ensure that `workspaceClient` implements `SlackClient`
+var _ SlackClient = (*workspaceClient)(nil)
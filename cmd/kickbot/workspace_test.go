@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/mock/gomock"
+)
+
+// newTestWorkspaces returns Workspaces without store whose clients are mocks by bot token.
+func newTestWorkspaces(clients map[string]*MockSlackClient) *Workspaces {
+	return NewWorkspaces(nil, func(token string) SlackClient { return clients[token] }, DefaultSettings())
+}
+
+// TestWorkspacesResolveInstallations verifies that every workspace gets its own game manager with the bot token of
+// its installation, that workspaces of an organization use the installation of the organization and that
+// workspaces without installation are rejected.
+func TestWorkspacesResolveInstallations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	clients := map[string]*MockSlackClient{"xoxb-1": NewMockSlackClient(ctrl), "xoxb-grid": NewMockSlackClient(ctrl)}
+	workspaces := newTestWorkspaces(clients)
+	defer workspaces.Shutdown(context.TODO())
+
+	if _, err := workspaces.Manager(Workspace{TeamID: "T1"}); !errors.Is(err, errNotInstalled) {
+		t.Fatalf("Expected T1 to be rejected before the installation, got %v", err)
+	}
+	if err := workspaces.Install(Installation{TeamID: "T1", BotToken: "xoxb-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := workspaces.Install(Installation{EnterpriseID: "E1", EnterpriseInstall: true, BotToken: "xoxb-grid"}); err != nil {
+		t.Fatal(err)
+	}
+
+	alpha, err := workspaces.Manager(Workspace{TeamID: "T1"})
+	if err != nil {
+		t.Fatalf("Expected T1 to be installed, got %v", err)
+	}
+	if again, _ := workspaces.Manager(Workspace{TeamID: "T1"}); again != alpha {
+		t.Error("Expected the game manager of T1 to be reused")
+	}
+	grid, err := workspaces.Manager(Workspace{EnterpriseID: "E1", TeamID: "T2"})
+	if err != nil {
+		t.Fatalf("Expected T2 to be served by the installation of E1, got %v", err)
+	}
+	if grid == alpha {
+		t.Error("Expected the workspaces to have their own game managers")
+	}
+	if _, err := workspaces.Manager(Workspace{EnterpriseID: "E2", TeamID: "T3"}); !errors.Is(err, errNotInstalled) {
+		t.Errorf("Expected T3 of another organization to be rejected, got %v", err)
+	}
+
+	// the lobbies of the workspaces are kept apart
+	clients["xoxb-1"].EXPECT().
+		PostMessage("C1", gomock.Any()).
+		Return("C1", "ts-1", nil).Times(1)
+	clients["xoxb-1"].EXPECT().
+		DeleteMessageContext(gomock.Any(), "C1", "ts-1").
+		Return("C1", "ts-1", nil).Times(1)
+	alpha.CreateGame("C1", "U1", GameOpts{timeout: 30 * time.Minute, gameType: GameTypeTwoVsTwo})
+	if lobbyIn(alpha, "C1") == "" {
+		t.Error("Expected a game request in C1 of T1")
+	}
+	if lobbyIn(grid, "C1") != "" {
+		t.Error("Expected no game request in C1 of T2")
+	}
+}
+
+// TestWorkspacesReinstall verifies that a reinstallation replaces the bot token of the running game manager.
+func TestWorkspacesReinstall(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	clients := map[string]*MockSlackClient{"xoxb-old": NewMockSlackClient(ctrl), "xoxb-new": NewMockSlackClient(ctrl)}
+	workspaces := newTestWorkspaces(clients)
+	defer workspaces.Shutdown(context.TODO())
+
+	if err := workspaces.Install(Installation{TeamID: "T1", BotToken: "xoxb-old"}); err != nil {
+		t.Fatal(err)
+	}
+	gameMgr, err := workspaces.Manager(Workspace{TeamID: "T1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := workspaces.Install(Installation{TeamID: "T1", BotToken: "xoxb-new"}); err != nil {
+		t.Fatal(err)
+	}
+
+	clients["xoxb-new"].EXPECT().
+		PostEphemeral("C1", "U1", gomock.Any()).
+		Return("timestamp", nil).Times(1)
+	gameMgr.CancelGame("C1", "U1", "")
+}
+
+// TestCommandFromUninstalledWorkspace verifies that commands of workspaces without installation are rejected.
+func TestCommandFromUninstalledWorkspace(t *testing.T) {
+	workspaces := newTestWorkspaces(nil)
+
+	formData := url.Values{"command": {CMD_START_ROUND}, "team_id": {"T1"}, "channel_id": {"C1"}, "user_id": {"U1"}}
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/commands", strings.NewReader(formData.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handleSlackCommand(workspaces)(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, rr.Code)
+	}
+}
+
+// TestInteractionWorkspace verifies that interactions are routed to the workspace that owns the modal or the
+// message, not to the workspace of the user, who may be a guest from another workspace in a shared channel.
+func TestInteractionWorkspace(t *testing.T) {
+	tests := []struct {
+		name     string
+		callback slack.InteractionCallback
+		expected string
+	}{
+		{
+			name: "button in message of another team",
+			callback: slack.InteractionCallback{
+				Team:    slack.Team{ID: "T2"},
+				Message: slack.Message{Msg: slack.Msg{Team: "T1"}},
+			},
+			expected: "T1",
+		},
+		{
+			name: "modal of another team",
+			callback: slack.InteractionCallback{
+				Team: slack.Team{ID: "T2"},
+				View: slack.View{TeamID: "T1"},
+			},
+			expected: "T1",
+		},
+		{
+			name:     "fallback to the team of the user",
+			callback: slack.InteractionCallback{Team: slack.Team{ID: "T2"}},
+			expected: "T2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if workspace := interactionWorkspace(tt.callback); workspace.TeamID != tt.expected {
+				t.Errorf("Expected team %q, got %q", tt.expected, workspace.TeamID)
+			}
+		})
+	}
+}
+
+// blockingStore is a WorkspaceStore whose matches of a team only load once they are released.
+type blockingStore struct {
+	WorkspaceStore
+	team    string
+	release chan struct{}
+}
+
+func (store *blockingStore) Workspace(workspace Workspace) GameStore {
+	gameStore := store.WorkspaceStore.Workspace(workspace)
+	if workspace.TeamID != store.team {
+		return gameStore
+	}
+	return blockingGameStore{GameStore: gameStore, release: store.release}
+}
+
+type blockingGameStore struct {
+	GameStore
+	release chan struct{}
+}
+
+func (store blockingGameStore) LoadMatches() ([]MatchRecord, error) {
+	<-store.release
+	return store.GameStore.LoadMatches()
+}
+
+// TestWorkspacesRestoreConcurrently verifies that a workspace whose games are restored slowly doesn't hold up the
+// other workspaces, and that the requests arriving meanwhile share a single game manager.
+func TestWorkspacesRestoreConcurrently(t *testing.T) {
+	fileStore, err := NewFileStore(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &blockingStore{WorkspaceStore: fileStore, team: "T1", release: make(chan struct{})}
+	workspaces := NewWorkspaces(store, func(string) SlackClient { return nil }, DefaultSettings())
+	defer workspaces.Shutdown(context.TODO())
+	for _, team := range []string{"T1", "T2"} {
+		if err := workspaces.Install(Installation{TeamID: team, BotToken: "xoxb-" + team}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	slow := make(chan *GameManager, 2)
+	for range 2 {
+		go func() {
+			gameMgr, err := workspaces.Manager(Workspace{TeamID: "T1"})
+			if err != nil {
+				t.Errorf("Expected T1 to be restored, got %v", err)
+			}
+			slow <- gameMgr
+		}()
+	}
+
+	fast := make(chan error, 1)
+	go func() {
+		_, err := workspaces.Manager(Workspace{TeamID: "T2"})
+		fast <- err
+	}()
+	select {
+	case err := <-fast:
+		if err != nil {
+			t.Errorf("Expected T2 to be restored, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected T2 to be served while the games of T1 are restored")
+	}
+
+	close(store.release)
+	if first, second := <-slow, <-slow; first == nil || first != second {
+		t.Error("Expected the requests of T1 to share one game manager")
+	}
+}
+
+// TestWorkspaceSettings verifies that every workspace gets the settings of the bot with its own defaults and
+// channels, also once the settings are reloaded.
+func TestWorkspaceSettings(t *testing.T) {
+	settings := DefaultSettings()
+	english := settings.Defaults
+	english.Language = LanguageEnglish
+	settings.Workspaces = map[string]WorkspaceSettings{"T1": {Defaults: english}}
+	workspaces := NewWorkspaces(nil, func(string) SlackClient { return nil }, settings)
+	defer workspaces.Shutdown(context.TODO())
+	for _, team := range []string{"T1", "T2"} {
+		if err := workspaces.Install(Installation{TeamID: team, BotToken: "xoxb-" + team}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	alpha, _ := workspaces.Manager(Workspace{TeamID: "T1"})
+	beta, _ := workspaces.Manager(Workspace{TeamID: "T2"})
+	if lang := alpha.channelLanguage("C1"); lang != LanguageEnglish {
+		t.Errorf("Expected T1 to use its own language, got %s", lang)
+	}
+	if lang := beta.channelLanguage("C1"); lang != DefaultLanguage {
+		t.Errorf("Expected T2 to use the language of the bot, got %s", lang)
+	}
+
+	reloaded := DefaultSettings()
+	reloaded.Defaults.Timeout = time.Hour
+	reloaded.Workspaces = map[string]WorkspaceSettings{"T2": {Defaults: english}}
+	workspaces.ApplySettings(reloaded)
+	if lang := alpha.channelLanguage("C1"); lang != DefaultLanguage {
+		t.Errorf("Expected T1 to use the language of the bot after the reload, got %s", lang)
+	}
+	if channel := beta.Settings().Channel("C1"); channel.Language != LanguageEnglish || channel.Timeout != english.Timeout {
+		t.Errorf("Expected T2 to use its own defaults after the reload, got %+v", channel)
+	}
+}
+
+// TestWorkspacesShutdownWhileRestoring verifies that Shutdown doesn't hold the lock while the game managers shut
+// down, waits for the game managers that are still being created and rejects the requests arriving meanwhile.
+func TestWorkspacesShutdownWhileRestoring(t *testing.T) {
+	fileStore, err := NewFileStore(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &blockingStore{WorkspaceStore: fileStore, team: "T1", release: make(chan struct{})}
+	workspaces := NewWorkspaces(store, func(string) SlackClient { return nil }, DefaultSettings())
+	for _, team := range []string{"T1", "T2"} {
+		if err := workspaces.Install(Installation{TeamID: team, BotToken: "xoxb-" + team}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	restored := make(chan error, 1)
+	go func() {
+		_, err := workspaces.Manager(Workspace{TeamID: "T1"})
+		restored <- err
+	}()
+	for pending := 0; pending == 0; {
+		workspaces.mu.Lock()
+		pending = len(workspaces.pending)
+		workspaces.mu.Unlock()
+	}
+
+	shutdown := make(chan struct{})
+	go func() {
+		workspaces.Shutdown(context.TODO())
+		close(shutdown)
+	}()
+	for stopped := false; !stopped; {
+		workspaces.mu.Lock()
+		stopped = workspaces.stopped
+		workspaces.mu.Unlock()
+	}
+	if _, err := workspaces.Manager(Workspace{TeamID: "T2"}); !errors.Is(err, errWorkspacesStopped) {
+		t.Errorf("Expected requests during the shutdown to be rejected, got %v", err)
+	}
+	select {
+	case <-shutdown:
+		t.Fatal("Expected the shutdown to wait for the games of T1 to be restored")
+	default:
+	}
+
+	close(store.release)
+	<-shutdown
+	if err := <-restored; !errors.Is(err, errWorkspacesStopped) {
+		t.Errorf("Expected the game manager of T1 to be shut down instead of published, got %v", err)
+	}
+	if len(workspaces.managers) != 0 {
+		t.Errorf("Expected no game managers after the shutdown, got %d", len(workspaces.managers))
+	}
+}